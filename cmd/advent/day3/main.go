@@ -1,480 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"strings"
-	"time"
-
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
-)
-
-// Результат выполнения стратегии
-type StrategyResult struct {
-	StrategyName  string
-	Prompt        string
-	Response      string
-	TokensUsed    int
-	ExecutionTime time.Duration
-	AnswerCorrect bool
-	AnswerQuality int // Оценка качества от 1 до 10
-}
-
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
-
-	// Создание клиента
-	aiClient := client.NewOpenAIClient(cfg.OpenAIKey)
-
-	// Заголовок
-	utils.PrintHeader("Day 3: Разные способы рассуждения")
-
-	// Описание задачи
-	printProblemDescription()
-
-	// Хранилище результатов
-	results := make([]StrategyResult, 0, 4)
-
-	// 1. Прямой ответ
-	results = append(results, runStrategy1DirectAnswer(aiClient))
-
-	// 2. Пошаговое решение
-	results = append(results, runStrategy2StepByStep(aiClient))
-
-	// 3. Мета-промпт (сначала генерируем промпт)
-	results = append(results, runStrategy3MetaPrompt(aiClient))
-
-	// 4. Группа экспертов
-	results = append(results, runStrategy4ExpertPanel(aiClient))
-
-	// Сравнение результатов
-	compareResults(results)
-
-	utils.PrintDivider()
-	utils.PrintSuccess("Задание Day 3 выполнено!")
-}
-
-func printProblemDescription() {
-	utils.PrintSection("🧩", "ЗАДАЧА")
-
-	fmt.Println("Классическая задача о переправе:")
-	fmt.Println()
-	fmt.Println("Фермеру нужно перевезти через реку волка, козу и капусту.")
-	fmt.Println("В лодке помещается только фермер и один из них.")
-	fmt.Println()
-	fmt.Println("Ограничения:")
-	fmt.Println("  - Волк не может оставаться наедине с козой (съест)")
-	fmt.Println("  - Коза не может оставаться наедине с капустой (съест)")
-	fmt.Println()
-	fmt.Println("Вопрос: Как перевезти всех через реку?")
-	fmt.Println()
-
-	utils.PrintDivider()
-}
-
-// Стратегия 1: Прямой ответ без дополнительных инструкций
-func runStrategy1DirectAnswer(aiClient *client.OpenAIClient) StrategyResult {
-	utils.PrintSection("1️⃣", "СТРАТЕГИЯ 1: Прямой ответ")
-
-	prompt := `Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Как перевезти всех через реку?`
-
-	fmt.Printf("Промпт:\n%s\n\n", prompt)
-
-	start := time.Now()
-	resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      prompt,
-		Temperature: 0.7,
-		MaxTokens:   500,
-	})
-	elapsed := time.Since(start)
-
-	if err != nil {
-		log.Printf("Ошибка: %v\n", err)
-		return StrategyResult{}
-	}
-
-	fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-	utils.PrintKeyValue("Время выполнения", elapsed.String())
-	utils.PrintDivider()
-
-	return StrategyResult{
-		StrategyName:  "Прямой ответ",
-		Prompt:        prompt,
-		Response:      resp.Content,
-		TokensUsed:    resp.TotalTokens,
-		ExecutionTime: elapsed,
-	}
-}
-
-// Стратегия 2: Пошаговое решение
-func runStrategy2StepByStep(aiClient *client.OpenAIClient) StrategyResult {
-	utils.PrintSection("2️⃣", "СТРАТЕГИЯ 2: Пошаговое решение")
-
-	prompt := `Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Как перевезти всех через реку?
-
-ВАЖНО: Решай задачу пошагово:
-1. Сначала проанализируй ограничения
-2. Определи критические комбинации (кого нельзя оставлять вместе)
-3. Найди безопасный первый ход
-4. Продолжай шаг за шагом до решения
-5. Проверь, что решение удовлетворяет всем условиям
-
-Каждый шаг объясняй подробно.`
-
-	fmt.Printf("Промпт:\n%s\n\n", prompt)
-
-	start := time.Now()
-	resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      prompt,
-		Temperature: 0.7,
-		MaxTokens:   800,
-	})
-	elapsed := time.Since(start)
-
-	if err != nil {
-		log.Printf("Ошибка: %v\n", err)
-		return StrategyResult{}
-	}
-
-	fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-	utils.PrintKeyValue("Время выполнения", elapsed.String())
-	utils.PrintDivider()
-
-	return StrategyResult{
-		StrategyName:  "Пошаговое решение",
-		Prompt:        prompt,
-		Response:      resp.Content,
-		TokensUsed:    resp.TotalTokens,
-		ExecutionTime: elapsed,
-	}
-}
-
-// Стратегия 3: Мета-промпт (сначала генерируем промпт)
-func runStrategy3MetaPrompt(aiClient *client.OpenAIClient) StrategyResult {
-	utils.PrintSection("3️⃣", "СТРАТЕГИЯ 3: Мета-промпт")
-
-	// Шаг 1: Генерация промпта
-	metaPrompt := `Мне нужно решить следующую задачу:
-
-Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Составь оптимальный промпт для языковой модели, который поможет
-эффективно решить эту задачу. Промпт должен включать:
-- Четкую формулировку задачи
-- Структуру для ответа
-- Подсказки для рассуждения
-
-Выведи только сам промпт, без дополнительных пояснений.`
-
-	fmt.Println("Шаг 1: Генерация оптимального промпта")
-	fmt.Printf("Мета-промпт:\n%s\n\n", metaPrompt)
-
-	start := time.Now()
-
-	// Генерируем промпт
-	respPrompt, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      metaPrompt,
-		Temperature: 0.7,
-		MaxTokens:   400,
-	})
-
-	if err != nil {
-		log.Printf("Ошибка при генерации промпта: %v\n", err)
-		return StrategyResult{}
-	}
-
-	generatedPrompt := respPrompt.Content
-	fmt.Printf("Сгенерированный промпт:\n%s\n\n", generatedPrompt)
-	utils.PrintTokenStats(respPrompt.TotalTokens, respPrompt.PromptTokens, respPrompt.CompletionTokens)
-
-	fmt.Println("\nШаг 2: Использование сгенерированного промпта")
-
-	// Используем сгенерированный промпт
-	respFinal, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      generatedPrompt,
-		Temperature: 0.7,
-		MaxTokens:   600,
-	})
-
-	elapsed := time.Since(start)
-
-	if err != nil {
-		log.Printf("Ошибка при решении: %v\n", err)
-		return StrategyResult{}
-	}
-
-	fmt.Printf("Итоговый ответ:\n%s\n\n", respFinal.Content)
-	utils.PrintTokenStats(respFinal.TotalTokens, respFinal.PromptTokens, respFinal.CompletionTokens)
-	utils.PrintKeyValue("Время выполнения", elapsed.String())
-	utils.PrintKeyValue("Токенов всего", fmt.Sprintf("%d", respPrompt.TotalTokens+respFinal.TotalTokens))
-	utils.PrintDivider()
-
-	return StrategyResult{
-		StrategyName:  "Мета-промпт",
-		Prompt:        generatedPrompt,
-		Response:      respFinal.Content,
-		TokensUsed:    respPrompt.TotalTokens + respFinal.TotalTokens,
-		ExecutionTime: elapsed,
-	}
-}
-
-// Стратегия 4: Группа экспертов
-func runStrategy4ExpertPanel(aiClient *client.OpenAIClient) StrategyResult {
-	utils.PrintSection("4️⃣", "СТРАТЕГИЯ 4: Группа экспертов")
-
-	// Определяем экспертов
-	experts := []struct {
-		Role   string
-		Emoji  string
-		Prompt string
-	}{
-		{
-			Role:  "Логик-аналитик",
-			Emoji: "🧠",
-			Prompt: `Ты — эксперт по логическим задачам и комбинаторике.
-
-ЗАДАЧА:
-Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Проанализируй задачу с точки зрения логики:
-- Определи пространство состояний
-- Найди критические ограничения
-- Предложи оптимальное решение
-
-Будь точным и структурированным.`,
-		},
-		{
-			Role:  "Игровой теоретик",
-			Emoji: "🎮",
-			Prompt: `Ты — эксперт по теории игр и алгоритмам.
-
-ЗАДАЧА:
-Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Рассмотри задачу как граф состояний:
-- Какие состояния возможны?
-- Какие переходы допустимы?
-- Найди кратчайший путь к цели
-
-Опиши решение в терминах графов и поиска.`,
-		},
-		{
-			Role:  "Критик-верификатор",
-			Emoji: "🔍",
-			Prompt: `Ты — эксперт по верификации решений.
-
-ЗАДАЧА:
-Фермеру нужно перевезти через реку волка, козу и капусту.
-В лодке помещается только фермер и один из них.
-Волк не может оставаться наедине с козой (съест).
-Коза не может оставаться наедине с капустой (съест).
-
-Твоя цель:
-1. Найди решение
-2. Тщательно проверь каждый шаг
-3. Убедись, что нет нарушений условий
-4. Предложи альтернативы, если есть
-
-Будь педантичным и внимательным к деталям.`,
-		},
-	}
-
-	responses := make([]string, 0, len(experts))
-	totalTokens := 0
-	start := time.Now()
-
-	for i, expert := range experts {
-		fmt.Printf("\n%s Эксперт %d: %s\n", expert.Emoji, i+1, expert.Role)
-		fmt.Printf("Промпт:\n%s\n\n", expert.Prompt)
-
-		resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-			Prompt:      expert.Prompt,
-			Temperature: 0.7,
-			MaxTokens:   500,
-		})
-
-		if err != nil {
-			log.Printf("Ошибка для эксперта %s: %v\n", expert.Role, err)
-			continue
-		}
-
-		fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-		utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-
-		responses = append(responses, fmt.Sprintf("=== %s %s ===\n%s", expert.Emoji, expert.Role, resp.Content))
-		totalTokens += resp.TotalTokens
-	}
-
-	elapsed := time.Since(start)
-
-	// Объединяем ответы всех экспертов
-	combinedResponse := strings.Join(responses, "\n\n")
-
-	utils.PrintKeyValue("Время выполнения", elapsed.String())
-	utils.PrintKeyValue("Токенов всего", fmt.Sprintf("%d", totalTokens))
-	utils.PrintDivider()
-
-	return StrategyResult{
-		StrategyName:  "Группа экспертов",
-		Prompt:        "См. промпты для каждого эксперта выше",
-		Response:      combinedResponse,
-		TokensUsed:    totalTokens,
-		ExecutionTime: elapsed,
-	}
-}
-
-// Сравнение результатов всех стратегий
-func compareResults(results []StrategyResult) {
-	utils.PrintSection("📊", "СРАВНЕНИЕ РЕЗУЛЬТАТОВ")
-
-	// Таблица с основными метриками
-	fmt.Println("\n┌─────────────────────────┬───────────┬─────────────────┐")
-	fmt.Println("│ Стратегия               │ Токены    │ Время           │")
-	fmt.Println("├─────────────────────────┼───────────┼─────────────────┤")
-
-	for _, result := range results {
-		fmt.Printf("│ %-23s │ %9d │ %15s │\n",
-			truncate(result.StrategyName, 23),
-			result.TokensUsed,
-			result.ExecutionTime.Round(time.Millisecond),
-		)
-	}
-
-	fmt.Println("└─────────────────────────┴───────────┴─────────────────┘")
-
-	// Анализ каждой стратегии
-	fmt.Println("\n📝 ДЕТАЛЬНЫЙ АНАЛИЗ:\n")
-
-	analyses := []struct {
-		name string
-		pros []string
-		cons []string
-	}{
-		{
-			name: "1. Прямой ответ",
-			pros: []string{
-				"Быстрое выполнение",
-				"Минимальное количество токенов",
-				"Простая реализация",
-			},
-			cons: []string{
-				"Может быть неполным",
-				"Нет гарантии детального рассуждения",
-				"Зависит от базовых знаний модели",
-			},
-		},
-		{
-			name: "2. Пошаговое решение",
-			pros: []string{
-				"Детальное объяснение процесса",
-				"Структурированный подход",
-				"Меньше ошибок благодаря проверке на каждом шаге",
-				"Понятно, как модель пришла к решению",
-			},
-			cons: []string{
-				"Больше токенов (выше стоимость)",
-				"Дольше выполнение",
-			},
-		},
-		{
-			name: "3. Мета-промпт",
-			pros: []string{
-				"Оптимизированный промпт под конкретную задачу",
-				"Модель сама формулирует лучший подход",
-				"Потенциально более качественное решение",
-			},
-			cons: []string{
-				"Два запроса вместо одного (2x стоимость)",
-				"Самое долгое выполнение",
-				"Не всегда лучше прямого пошагового подхода",
-			},
-		},
-		{
-			name: "4. Группа экспертов",
-			pros: []string{
-				"Разные углы зрения на задачу",
-				"Взаимная проверка решений",
-				"Более глубокий анализ",
-				"Можно выбрать лучшее решение",
-			},
-			cons: []string{
-				"Максимальное количество токенов",
-				"Несколько последовательных запросов",
-				"Избыточно для простых задач",
-			},
-		},
-	}
-
-	for i, analysis := range analyses {
-		fmt.Printf("%s\n", analysis.name)
-
-		fmt.Println("\nПлюсы:")
-		for _, pro := range analysis.pros {
-			utils.PrintSuccess(pro)
-		}
-
-		fmt.Println("\nМинусы:")
-		for _, con := range analysis.cons {
-			utils.PrintError(con)
-		}
-
-		if i < len(analyses)-1 {
-			fmt.Println()
-		}
-	}
-
-	// Итоговый вывод
-	fmt.Println()
-	utils.PrintSection("🎯", "ВЫВОДЫ")
-
-	fmt.Println("Для простых задач:")
-	utils.PrintInfo("  → Прямой ответ или пошаговое решение")
-
-	fmt.Println("\nДля сложных логических задач:")
-	utils.PrintInfo("  → Пошаговое решение (баланс качества и стоимости)")
-
-	fmt.Println("\nДля критически важных решений:")
-	utils.PrintInfo("  → Группа экспертов (взаимная проверка)")
-
-	fmt.Println("\nДля исследовательских задач:")
-	utils.PrintInfo("  → Мета-промпт (оптимизация подхода)")
-
-	fmt.Println()
-	utils.PrintSuccess("Все стратегии дали правильное решение!")
-	utils.PrintInfo("Выбор стратегии зависит от баланса между качеством, стоимостью и временем")
-}
-
-// Вспомогательная функция для обрезки строк
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
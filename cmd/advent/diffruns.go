@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// diffRunsFlags флаги команды diff-runs
+var diffRunsFlags struct {
+	store            string
+	scoreDrop        float64
+	change           float64
+	failOnRegression bool
+}
+
+func bindDiffRunsFlags(fs *flag.FlagSet) {
+	defaults := experiment.DefaultDiffOptions
+	fs.StringVar(&diffRunsFlags.store, "store", experiment.DefaultStoreDir, "каталог хранилища запусков")
+	fs.Float64Var(&diffRunsFlags.scoreDrop, "score-drop", defaults.ScoreDrop, "падение доли успешных или значения проверки, считающееся регрессией (0-1)")
+	fs.Float64Var(&diffRunsFlags.change, "change", defaults.ChangeRatio, "относительное изменение стоимости и времени, которое выделяется (0.2 - 20%)")
+	fs.BoolVar(&diffRunsFlags.failOnRegression, "fail-on-regression", false, "завершиться с ошибкой, если есть регрессии качества")
+}
+
+// runDiffRuns сравнивает два запуска эксперимента; без аргументов - список запусков,
+// с одним - сравнение с предыдущим запуском того же эксперимента
+func runDiffRuns(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) > 2 {
+		return cli.Usagef("использование: diff-runs [флаги] [<старый запуск>] [<новый запуск>]")
+	}
+	if diffRunsFlags.scoreDrop < 0 || diffRunsFlags.scoreDrop > 1 {
+		return cli.Usagef("-score-drop должно быть от 0 до 1, получено %g", diffRunsFlags.scoreDrop)
+	}
+	if diffRunsFlags.change < 0 {
+		return cli.Usagef("-change не может быть отрицательным")
+	}
+
+	store := experiment.NewStore(diffRunsFlags.store)
+	if len(env.Args) == 0 {
+		runs, err := store.Runs()
+		if err != nil {
+			return err
+		}
+		printRuns(store, runs)
+		return env.Emit(runs)
+	}
+
+	newRun, err := store.Find(env.Args[len(env.Args)-1])
+	if err != nil {
+		return err
+	}
+	var oldRun experiment.Run
+	if len(env.Args) == 2 {
+		oldRun, err = store.Find(env.Args[0])
+	} else {
+		oldRun, err = store.Previous(newRun)
+	}
+	if err != nil {
+		return err
+	}
+
+	oldResults, err := store.Results(oldRun)
+	if err != nil {
+		return err
+	}
+	newResults, err := store.Results(newRun)
+	if err != nil {
+		return err
+	}
+
+	opts := experiment.DefaultDiffOptions
+	opts.ScoreDrop = diffRunsFlags.scoreDrop
+	opts.ChangeRatio = diffRunsFlags.change
+	diff := experiment.Compare(oldRun, newRun, oldResults, newResults, opts)
+	printDiff(diff)
+
+	if err := env.Emit(diff); err != nil {
+		return err
+	}
+	if diffRunsFlags.failOnRegression && diff.Regressions > 0 {
+		return fmt.Errorf("регрессии качества в %d ячейках", diff.Regressions)
+	}
+	return nil
+}
+
+// printRuns выводит журнал запусков
+func printRuns(store *experiment.Store, runs []experiment.Run) {
+	utils.PrintHeader("Запуски экспериментов: " + store.Dir())
+	if len(runs) == 0 {
+		utils.PrintInfo("Запусков нет: выполните advent experiment run <spec.yaml>")
+		return
+	}
+
+	fmt.Printf("%-45s %-12s %-9s %8s %8s %12s\n", "запуск", "спецификация", "коммит", "ответов", "успешно", "стоимость")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, r := range runs {
+		line := fmt.Sprintf("%-45s %-12s %-9s %8d %8d %12s",
+			truncate(r.ID, 45), r.SpecHash, shortCommit(r.GitCommit, r.GitDirty), r.Completed, r.Passed, fmt.Sprintf("$%.6f", r.Cost))
+		if r.Error != "" {
+			line += "  остановлен: " + r.Error
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// printDiff выводит сравнение запусков: регрессии качества красным,
+// изменения стоимости и времени выше порога - желтым
+func printDiff(d experiment.Diff) {
+	utils.PrintHeader("Сравнение запусков: " + d.New.Experiment)
+	utils.PrintKeyValue("Было", runLabel(d.Old))
+	utils.PrintKeyValue("Стало", runLabel(d.New))
+	if !d.SameSpec {
+		utils.PrintWarning(fmt.Sprintf("Спецификации различаются (%s → %s): сравниваются совпадающие ячейки",
+			orDash(d.Old.SpecHash), orDash(d.New.SpecHash)))
+	}
+	fmt.Println()
+
+	for _, c := range d.Cells {
+		label := cellLabel(c.Cell)
+		switch {
+		case c.Old == nil:
+			utils.PrintInfo("+ " + label + " (новая ячейка)")
+			continue
+		case c.New == nil:
+			utils.PrintWarning(label + " (нет в новом запуске)")
+			continue
+		}
+
+		line := fmt.Sprintf("%s  успешно %d/%d → %d/%d", label, c.Old.Passed, c.Old.Runs, c.New.Passed, c.New.Runs)
+		switch {
+		case len(c.Regressions) > 0:
+			utils.PrintError(line + "  регрессия: " + strings.Join(c.Regressions, "; "))
+		case c.New.Passed*c.Old.Runs > c.Old.Passed*c.New.Runs:
+			utils.PrintSuccess(line)
+		default:
+			fmt.Println("  " + line)
+		}
+
+		var scores []string
+		for _, s := range c.Scores {
+			switch {
+			case s.Old == nil:
+				scores = append(scores, fmt.Sprintf("%s=%.2f (новая)", s.Evaluator, *s.New))
+			case s.New == nil:
+				scores = append(scores, fmt.Sprintf("%s=%.2f (удалена)", s.Evaluator, *s.Old))
+			case s.Delta() != 0:
+				scores = append(scores, fmt.Sprintf("%s %.2f→%.2f", s.Evaluator, *s.Old, *s.New))
+			}
+		}
+		if len(scores) > 0 {
+			fmt.Printf("    проверки: %s\n", strings.Join(scores, " "))
+		}
+
+		changes := fmt.Sprintf("    стоимость $%.6f → $%.6f (%s) · время %.0fms → %.0fms (%s)",
+			c.Old.TotalCost/float64(c.Old.Runs), c.New.TotalCost/float64(c.New.Runs), percent(c.CostChange),
+			c.Old.AvgLatencyMs, c.New.AvgLatencyMs, percent(c.Latency))
+		if c.CostFlag || c.LatencyFlag {
+			utils.PrintColored(utils.ColorYellow, changes)
+		} else {
+			fmt.Println(changes)
+		}
+	}
+
+	fmt.Println()
+	utils.PrintKeyValue("Стоимость", fmt.Sprintf("$%.6f → $%.6f (%s)", d.Old.Cost, d.New.Cost, percent(d.CostChange)))
+	if d.Regressions > 0 {
+		utils.PrintError(fmt.Sprintf("Регрессии качества: %d из %d ячеек", d.Regressions, len(d.Cells)))
+	} else {
+		utils.PrintSuccess("Регрессий качества нет")
+	}
+}
+
+// runLabel краткое описание запуска: идентификатор, время, коммит
+func runLabel(r experiment.Run) string {
+	parts := []string{r.ID}
+	if !r.Started.IsZero() {
+		parts = append(parts, r.Started.Format("2006-01-02 15:04"))
+	}
+	if r.GitCommit != "" {
+		parts = append(parts, "коммит "+shortCommit(r.GitCommit, r.GitDirty))
+	}
+	if r.Error != "" {
+		parts = append(parts, "остановлен")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// shortCommit короткий хэш коммита, "+" - с незакоммиченными изменениями
+func shortCommit(commit string, dirty bool) string {
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	if dirty {
+		commit += "+"
+	}
+	return orDash(commit)
+}
+
+func percent(ratio float64) string {
+	return fmt.Sprintf("%+.0f%%", ratio*100)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// experimentArgs подкоманды experiment
+var experimentArgs = []string{"run", "plan"}
+
+// experimentFlags флаги команды experiment
+var experimentFlags struct {
+	out         string
+	store       string
+	repetitions int
+}
+
+func bindExperimentFlags(fs *flag.FlagSet) {
+	fs.StringVar(&experimentFlags.out, "out", "", "файл результатов JSONL (по умолчанию <store>/<имя>-<время>.jsonl)")
+	fs.StringVar(&experimentFlags.store, "store", experiment.DefaultStoreDir, "каталог хранилища запусков (журнал runs.jsonl)")
+	fs.IntVar(&experimentFlags.repetitions, "repetitions", 0, "число повторов каждой ячейки (0 - из спецификации)")
+}
+
+// experimentOutput результат команды для -output json
+type experimentOutput struct {
+	Experiment string               `json:"experiment"`
+	Run        string               `json:"run,omitempty"` // Идентификатор запуска в хранилище
+	File       string               `json:"file,omitempty"`
+	Cells      []experiment.Cell    `json:"cells,omitempty"`
+	Results    []experiment.Result  `json:"results,omitempty"`
+	Summary    []experiment.Summary `json:"summary,omitempty"`
+}
+
+// runExperiment выполняет эксперимент по спецификации (run) или показывает его сетку (plan)
+func runExperiment(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) != 2 {
+		return cli.Usagef("использование: experiment [флаги] %s <spec.yaml>", strings.Join(experimentArgs, "|"))
+	}
+	command, path := env.Args[0], env.Args[1]
+	if command != "run" && command != "plan" {
+		return cli.Usagef("неизвестная подкоманда experiment %q (допустимо: %s)", command, strings.Join(experimentArgs, ", "))
+	}
+	if experimentFlags.repetitions < 0 {
+		return cli.Usagef("-repetitions не может быть отрицательным")
+	}
+
+	cfg := env.Config
+	spec, err := experiment.LoadSpec(path, cfg.Prompts())
+	if err != nil {
+		return err
+	}
+	// Хэш спецификации - до -repetitions: запуски с разным числом повторов сравнимы
+	run := experiment.NewRun(spec, time.Now())
+	if experimentFlags.repetitions > 0 {
+		spec.Repetitions = experimentFlags.repetitions
+	}
+
+	cells := spec.Expand(experiment.Defaults{
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+	})
+
+	if command == "plan" {
+		printPlan(spec, cells)
+		return env.Emit(experimentOutput{Experiment: spec.Name, Cells: cells})
+	}
+
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiClient.SetMeter(meter)
+
+	runner := experiment.NewRunner(aiClient)
+	runner.SetJudgeModel(cfg.Judge.Model)
+	runner.SetPrompts(cfg.Prompts())
+	summarizer, err := cfg.NewSummarizer(openai.NewClientWithConfig(cfg.ClientConfig()), meter)
+	if err != nil {
+		return err
+	}
+	runner.SetSummarizer(summarizer)
+
+	store := experiment.NewStore(experimentFlags.store)
+	run.GitCommit, run.GitDirty = experiment.GitRevision(ctx)
+	out := experimentFlags.out
+	if out == "" {
+		out = store.ResultPath(run.ID)
+	}
+	run.Results = out
+	writer, err := experiment.CreateResultWriter(out)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	utils.PrintHeader("Эксперимент: " + spec.Name)
+	if spec.Description != "" {
+		fmt.Printf("%s\n\n", strings.TrimSpace(spec.Description))
+	}
+	utils.PrintKeyValue("Ячеек", fmt.Sprintf("%d", len(cells)))
+	utils.PrintKeyValue("Запуск", run.ID)
+	utils.PrintKeyValue("Результаты", out)
+	fmt.Println()
+
+	var writeErr error
+	runner.SetProgress(func(r experiment.Result) {
+		if err := writer.Write(r); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		printProgress(r, len(cells))
+	})
+
+	results, runErr := runner.Run(ctx, spec, cells)
+	if writeErr != nil {
+		return writeErr
+	}
+
+	// Запись о запуске сохраняется и для прерванного эксперимента
+	run.Finish(len(cells), results, runErr)
+	if err := store.Save(run); err != nil {
+		return err
+	}
+
+	summary := run.Summary
+	printSummary(summary)
+	if runErr != nil {
+		utils.PrintWarning(fmt.Sprintf("Эксперимент остановлен после %d из %d ячеек", len(results), len(cells)))
+		return runErr
+	}
+
+	utils.PrintSuccess(fmt.Sprintf("Результаты сохранены: %s (запуск %s)", out, run.ID))
+	return env.Emit(experimentOutput{Experiment: spec.Name, Run: run.ID, File: out, Results: results, Summary: summary})
+}
+
+// printPlan выводит ячейки эксперимента без обращения к API
+func printPlan(spec *experiment.Spec, cells []experiment.Cell) {
+	utils.PrintHeader("План эксперимента: " + spec.Name)
+	for _, c := range cells {
+		fmt.Printf("%4d  %s\n", c.Index+1, cellLabel(c))
+	}
+	fmt.Println()
+	utils.PrintKeyValue("Ячеек", fmt.Sprintf("%d", len(cells)))
+}
+
+// printProgress выводит строку результата ячейки
+func printProgress(r experiment.Result, total int) {
+	prefix := fmt.Sprintf("[%d/%d] %s", r.Index+1, total, cellLabel(r.Cell))
+	if r.Error != "" {
+		utils.PrintError(fmt.Sprintf("%s: %s", prefix, r.Error))
+		return
+	}
+
+	line := fmt.Sprintf("%s  %d tok  %dms  $%.6f", prefix, r.TotalTokens, r.LatencyMs, r.Cost)
+	for _, s := range r.Scores {
+		mark := "✓"
+		if !s.Pass {
+			mark = "✗"
+		}
+		line += fmt.Sprintf("  %s%s", mark, s.Evaluator)
+	}
+	if r.Passed() {
+		utils.PrintSuccess(line)
+	} else {
+		utils.PrintWarning(line)
+	}
+}
+
+// printSummary выводит сводку по ячейкам без учета повторов
+func printSummary(summary []experiment.Summary) {
+	if len(summary) == 0 {
+		return
+	}
+
+	fmt.Println()
+	utils.PrintSection("📊", "СВОДКА")
+	fmt.Printf("%-60s %6s %8s %8s %10s %10s  %s\n", "ячейка", "повт.", "успешно", "ср. tok", "ср. время", "стоимость", "проверки")
+	fmt.Println(strings.Repeat("-", 120))
+	for _, s := range summary {
+		var scores []string
+		for _, score := range s.Scores {
+			scores = append(scores, fmt.Sprintf("%s=%.2f", score.Evaluator, score.Value))
+		}
+		fmt.Printf("%-60s %6d %8d %8.0f %8.0fms %10s  %s\n",
+			truncate(cellLabel(s.Cell), 60), s.Runs, s.Passed, s.AvgTotalTokens, s.AvgLatencyMs,
+			fmt.Sprintf("$%.6f", s.TotalCost), strings.Join(scores, " "))
+	}
+	fmt.Println()
+}
+
+// cellLabel краткое описание параметров ячейки
+func cellLabel(c experiment.Cell) string {
+	parts := []string{c.Prompt}
+	if c.System != "" {
+		parts = append(parts, "system="+c.System)
+	}
+	if c.History != "" {
+		parts = append(parts, "history="+c.History)
+	}
+	parts = append(parts, c.Model, fmt.Sprintf("t=%.1f", c.Temperature))
+	if c.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max=%d", c.MaxTokens))
+	}
+	if c.Repetition > 1 {
+		parts = append(parts, fmt.Sprintf("#%d", c.Repetition))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// truncate обрезает строку до n символов
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
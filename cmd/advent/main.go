@@ -0,0 +1,148 @@
+// advent - единая точка входа для заданий AI Advent Challenge.
+// Каждое задание - подкоманда; сценарии дней доступны и по имени дня (advent day6).
+package main
+
+import (
+	"os"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day1"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day2"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day3"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day4"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day5"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day6"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day7"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day8"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/days/day9"
+)
+
+var app = &cli.App{
+	Name:    "advent",
+	Summary: "задания AI Advent Challenge",
+	Commands: []*cli.Command{
+		{
+			Name:    "ask",
+			Preset:  "day1",
+			Summary: "один запрос к модели (аргументы - текст вопроса)",
+			JSON:    true,
+			Run:     day1.Run,
+		},
+		{
+			Name:    "format",
+			Preset:  "day2",
+			Summary: "контроль формата ответа: без ограничений, с ограничениями, JSON",
+			Run:     day2.Run,
+		},
+		{
+			Name:    "reasoning",
+			Preset:  "day3",
+			Summary: "способы рассуждения: прямой ответ, пошагово, мета-промпт, эксперты",
+			JSON:    true,
+			Flags:   day3.BindFlags,
+			Run:     day3.Run,
+		},
+		{
+			Name:    "temperature",
+			Preset:  "day4",
+			Summary: "сравнение ответов при разной температуре",
+			JSON:    true,
+			Flags:   day4.BindFlags,
+			Run:     day4.Run,
+		},
+		{
+			Name:    "compare-models",
+			Preset:  "day5",
+			Summary: "сравнение моделей по качеству, времени и стоимости (аргументы - модели)",
+			JSON:    true,
+			Flags:   day5.BindFlags,
+			Run:     day5.Run,
+		},
+		{
+			Name:        "chat",
+			Preset:      "day6",
+			Summary:     "интерактивный диалог с агентом",
+			Interactive: true,
+			Run:         day6.Run,
+		},
+		{
+			Name:        "memory",
+			Preset:      "day7",
+			Summary:     "диалог с сохранением истории и памятью фактов между запусками",
+			Interactive: true,
+			Run:         day7.Run,
+		},
+		{
+			Name:    "tokens",
+			Preset:  "day8",
+			Summary: "учет токенов, рост стоимости и переполнение контекста",
+			Args:    day8.Scenarios,
+			Run:     day8.Run,
+		},
+		{
+			Name:    "compress",
+			Preset:  "day9",
+			Summary: "сжатие истории диалога через summary",
+			Run:     day9.Run,
+		},
+		{
+			Name:    "experiment",
+			Summary: "эксперимент по YAML-спецификации: сетка промптов, моделей и параметров",
+			Args:    experimentArgs,
+			JSON:    true,
+			Flags:   bindExperimentFlags,
+			Run:     runExperiment,
+		},
+		{
+			Name:    "optimize",
+			Summary: "оптимизация системного промпта на примерах спецификации эксперимента",
+			JSON:    true,
+			Flags:   bindOptimizeFlags,
+			Run:     runOptimize,
+		},
+		{
+			Name:     "diff-runs",
+			Summary:  "сравнение двух запусков эксперимента: регрессии качества, стоимость, время",
+			NoConfig: true,
+			JSON:     true,
+			Flags:    bindDiffRunsFlags,
+			Run:      runDiffRuns,
+		},
+		{
+			Name:     "report",
+			Summary:  "отчет по запуску эксперимента в markdown и HTML",
+			NoConfig: true,
+			JSON:     true,
+			Flags:    bindReportFlags,
+			Run:      runReport,
+		},
+		{
+			Name:     "prompts",
+			Summary:  "библиотека шаблонов промптов: список и текст по ссылке name@version",
+			Args:     promptsArgs,
+			NoConfig: true,
+			JSON:     true,
+			Flags:    bindPromptsFlags,
+			Run:      runPrompts,
+		},
+		{
+			Name:     "usage",
+			Summary:  "отчет по журналу использования API",
+			NoConfig: true,
+			JSON:     true,
+			Flags:    bindUsageFlags,
+			Run:      runUsage,
+		},
+		{
+			Name:     "secrets",
+			Summary:  "управление ключом API: связка ключей ОС, зашифрованный файл",
+			Args:     secretsArgs,
+			NoConfig: true,
+			Run:      runSecrets,
+		},
+	},
+}
+
+func main() {
+	os.Exit(app.Run(os.Args[1:]))
+}
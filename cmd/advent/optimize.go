@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/optimize"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// optimizeFlags флаги команды optimize
+var optimizeFlags = struct {
+	optimize.Options
+	repetitions int
+	out         string
+}{Options: optimize.DefaultOptions()}
+
+func bindOptimizeFlags(fs *flag.FlagSet) {
+	opts := &optimizeFlags.Options
+	fs.IntVar(&opts.Candidates, "candidates", opts.Candidates, "вариантов промпта на итерацию")
+	fs.IntVar(&opts.Iterations, "iterations", opts.Iterations, "максимум итераций")
+	fs.IntVar(&opts.Patience, "patience", opts.Patience, "остановиться после N итераций без улучшения")
+	fs.Float64Var(&opts.MinImprovement, "min-gain", opts.MinImprovement, "прирост оценки, который считается улучшением")
+	fs.Float64Var(&opts.MaxCost, "max-cost", opts.MaxCost, "лимит расходов на оптимизацию в долларах (0 - только бюджет из конфигурации)")
+	fs.StringVar(&opts.Model, "meta-model", "", "модель мета-промпта (пусто - модель диалога)")
+	fs.StringVar(&opts.MetaPrompt, "meta-prompt", optimize.DefaultMetaPrompt, "шаблон мета-промпта name@version")
+	fs.IntVar(&optimizeFlags.repetitions, "repetitions", 0, "число повторов каждого примера (0 - из спецификации)")
+	fs.StringVar(&optimizeFlags.out, "out", "", "файл для лучшего промпта")
+}
+
+// runOptimize улучшает системный промпт спецификации эксперимента на ее примерах
+func runOptimize(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) != 1 {
+		return cli.Usagef("использование: optimize [флаги] <spec.yaml>")
+	}
+	opts := optimizeFlags.Options
+	if opts.Candidates < 1 || opts.Iterations < 1 || opts.Patience < 1 {
+		return cli.Usagef("-candidates, -iterations и -patience должны быть положительными")
+	}
+	if optimizeFlags.repetitions < 0 {
+		return cli.Usagef("-repetitions не может быть отрицательным")
+	}
+
+	cfg := env.Config
+	spec, err := experiment.LoadSpec(env.Args[0], cfg.Prompts())
+	if err != nil {
+		return err
+	}
+	if _, err := optimize.Seed(spec); err != nil {
+		return fmt.Errorf("%s: %w", env.Args[0], err)
+	}
+	if optimizeFlags.repetitions > 0 {
+		spec.Repetitions = optimizeFlags.repetitions
+	}
+	if opts.Model == "" {
+		opts.Model = cfg.Model
+	}
+	defaults := experiment.Defaults{
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+	}
+
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiClient.SetMeter(meter)
+
+	runner := experiment.NewRunner(aiClient)
+	runner.SetJudgeModel(cfg.Judge.Model)
+	runner.SetPrompts(cfg.Prompts())
+	summarizer, err := cfg.NewSummarizer(openai.NewClientWithConfig(cfg.ClientConfig()), meter)
+	if err != nil {
+		return err
+	}
+	runner.SetSummarizer(summarizer)
+
+	utils.PrintHeader("Оптимизация промпта: " + spec.Name)
+	if spec.Description != "" {
+		fmt.Printf("%s\n\n", strings.TrimSpace(spec.Description))
+	}
+	utils.PrintKeyValue("Ответов на вариант", fmt.Sprintf("%d", len(spec.Expand(defaults))))
+	utils.PrintKeyValue("Вариантов на итерацию", fmt.Sprintf("%d", opts.Candidates))
+	utils.PrintKeyValue("Итераций", fmt.Sprintf("до %d, без улучшения - до %d", opts.Iterations, opts.Patience))
+	fmt.Println()
+
+	optimizer := optimize.New(aiClient, runner, cfg.Prompts(), opts)
+	optimizer.SetProgress(printCandidate)
+
+	result, err := optimizer.Optimize(ctx, spec, defaults)
+	if result == nil {
+		return err
+	}
+
+	printOptimization(result)
+	if optimizeFlags.out != "" {
+		if err := os.WriteFile(optimizeFlags.out, []byte(result.Best.Prompt+"\n"), 0644); err != nil {
+			return fmt.Errorf("ошибка записи промпта: %w", err)
+		}
+		utils.PrintSuccess("Лучший промпт сохранен: " + optimizeFlags.out)
+	}
+	if err != nil {
+		return err
+	}
+	return env.Emit(result)
+}
+
+// printCandidate выводит строку оценки варианта
+func printCandidate(c optimize.Candidate) {
+	label := "исходный промпт"
+	if c.Iteration > 0 {
+		label = fmt.Sprintf("итерация %d", c.Iteration)
+	}
+	line := fmt.Sprintf("[%s] оценка %.2f, пройдено %s, %d tok, $%.6f  %s",
+		label, c.Score, passRate(c.PassRate), c.Tokens, c.Cost, truncate(strings.Join(strings.Fields(c.Prompt), " "), 60))
+	switch {
+	case c.Failed != "":
+		utils.PrintWarning(fmt.Sprintf("[%s] вариант пропущен: %s", label, c.Failed))
+	case c.Duplicate:
+		line += " (повтор)"
+		utils.PrintInfo(line)
+	case c.Errors > 0:
+		utils.PrintWarning(fmt.Sprintf("%s (ошибок: %d)", line, c.Errors))
+	default:
+		fmt.Println(line)
+	}
+}
+
+// printOptimization выводит историю оценок и лучший промпт
+func printOptimization(r *optimize.Result) {
+	fmt.Println()
+	utils.PrintSection("📈", "ИСТОРИЯ ОЦЕНОК")
+	fmt.Printf("%-9s %-30s %8s\n", "итерация", "варианты", "лучшая")
+	fmt.Printf("%-9d %-30s %8.2f\n", 0, fmt.Sprintf("%.2f", r.Seed.Score), r.Seed.Score)
+	for _, it := range r.History {
+		scores := make([]string, 0, len(it.Candidates))
+		for _, c := range it.Candidates {
+			if c.Failed != "" {
+				scores = append(scores, "-")
+				continue
+			}
+			scores = append(scores, fmt.Sprintf("%.2f", c.Score))
+		}
+		mark := ""
+		if it.Improved {
+			mark = " ↑"
+		}
+		fmt.Printf("%-9d %-30s %8.2f%s\n", it.Number, strings.Join(scores, " "), it.BestScore, mark)
+	}
+
+	fmt.Println()
+	utils.PrintKeyValue("Остановка", stopLabel(r.Stop))
+	utils.PrintKeyValue("Оценка", fmt.Sprintf("%.2f → %.2f (%+.2f)", r.Seed.Score, r.Best.Score, r.Improvement()))
+	utils.PrintKeyValue("Пройдено", fmt.Sprintf("%s → %s", passRate(r.Seed.PassRate), passRate(r.Best.PassRate)))
+	utils.PrintKeyValue("Токенов", fmt.Sprintf("%d", r.Tokens))
+	utils.PrintKeyValue("Стоимость", fmt.Sprintf("$%.6f", r.Cost))
+
+	utils.PrintSection("🏆", "ЛУЧШИЙ ПРОМПТ")
+	fmt.Println(r.Best.Prompt)
+	fmt.Println()
+	if r.Best.Iteration == 0 {
+		utils.PrintInfo("Ни один вариант не превзошел исходный промпт")
+	}
+}
+
+func passRate(ratio float64) string {
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
+
+func stopLabel(stop string) string {
+	switch stop {
+	case optimize.StopIterations:
+		return "выполнены все итерации"
+	case optimize.StopPlateau:
+		return "нет улучшений"
+	case optimize.StopPerfect:
+		return "все проверки пройдены"
+	case optimize.StopBudget:
+		return "исчерпан бюджет"
+	case optimize.StopCanceled:
+		return "прервано"
+	}
+	return stop
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// promptsArgs подкоманды prompts
+var promptsArgs = []string{"list", "show"}
+
+// promptsFlags флаги команды prompts
+var promptsFlags struct {
+	dir  string
+	vars varFlag
+}
+
+func bindPromptsFlags(fs *flag.FlagSet) {
+	fs.StringVar(&promptsFlags.dir, "dir", os.Getenv("PROMPTS_DIR"), "каталог своих шаблонов в дополнение к встроенным (PROMPTS_DIR)")
+	fs.Var(&promptsFlags.vars, "var", "переменная шаблона name=value для show (можно повторять)")
+}
+
+// varFlag повторяемый флаг name=value
+type varFlag map[string]any
+
+func (v *varFlag) String() string {
+	return fmt.Sprint(map[string]any(*v))
+}
+
+func (v *varFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("ожидается name=value, получено %q", value)
+	}
+	if *v == nil {
+		*v = make(varFlag)
+	}
+	(*v)[name] = val
+	return nil
+}
+
+// promptInfo шаблон для -output json
+type promptInfo struct {
+	Ref         string            `json:"ref"`
+	Description string            `json:"description,omitempty"`
+	Partial     bool              `json:"partial,omitempty"`
+	Vars        map[string]string `json:"vars,omitempty"`
+	File        string            `json:"file,omitempty"`
+}
+
+// promptOutput отрисованный шаблон для -output json
+type promptOutput struct {
+	prompts.Rendered
+	Ref  string `json:"ref"`
+	Text string `json:"text"`
+}
+
+// runPrompts показывает библиотеку шаблонов промптов
+func runPrompts(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) == 0 {
+		return cli.Usagef("укажите подкоманду: %s", strings.Join(promptsArgs, ", "))
+	}
+
+	lib := prompts.Default()
+	if promptsFlags.dir != "" {
+		lib = lib.Clone()
+		if err := lib.LoadDir(promptsFlags.dir); err != nil {
+			return err
+		}
+	}
+
+	switch command := env.Args[0]; command {
+	case "list":
+		return listPrompts(env, lib)
+	case "show":
+		if len(env.Args) != 2 {
+			return cli.Usagef("использование: prompts [-var name=value] show <name@version>")
+		}
+		rendered, err := lib.Render(env.Args[1], promptsFlags.vars)
+		if err != nil {
+			return err
+		}
+		if !env.JSON() {
+			utils.PrintKeyValue("Шаблон", rendered.String())
+			fmt.Println()
+			fmt.Println(rendered.Text)
+		}
+		return env.Emit(promptOutput{Rendered: rendered, Ref: rendered.Ref(), Text: rendered.Text})
+	default:
+		return cli.Usagef("неизвестная подкоманда prompts %q (допустимо: %s)", command, strings.Join(promptsArgs, ", "))
+	}
+}
+
+func listPrompts(env *cli.Env, lib *prompts.Library) error {
+	var infos []promptInfo
+	for _, t := range lib.Templates() {
+		infos = append(infos, promptInfo{
+			Ref:         t.Ref(),
+			Description: t.Description,
+			Partial:     t.Partial,
+			Vars:        t.Vars,
+			File:        t.File,
+		})
+	}
+	if env.JSON() {
+		return env.Emit(infos)
+	}
+	utils.PrintHeader("Шаблоны промптов")
+	for _, info := range infos {
+		line := fmt.Sprintf("%-28s %s", info.Ref, info.Description)
+		if info.Partial {
+			line += " (фрагмент)"
+		}
+		fmt.Println(line)
+		if len(info.Vars) > 0 {
+			names := make([]string, 0, len(info.Vars))
+			for name := range info.Vars {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Printf("%-28s переменные: %s\n", "", strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
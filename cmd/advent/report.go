@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/report"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// reportFlags флаги команды report
+var reportFlags struct {
+	store  string
+	format string
+	out    string
+}
+
+func bindReportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&reportFlags.store, "store", experiment.DefaultStoreDir, "каталог хранилища запусков")
+	fs.StringVar(&reportFlags.format, "format", strings.Join(report.Formats, ","), "форматы отчета через запятую: md, html")
+	fs.StringVar(&reportFlags.out, "out", "", "путь к отчету без расширения (по умолчанию <store>/<запуск>)")
+}
+
+// reportOutput результат команды для -output json
+type reportOutput struct {
+	Run   string   `json:"run"`
+	Files []string `json:"files"`
+}
+
+// runReport строит отчет по сохраненному запуску эксперимента
+func runReport(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) != 1 {
+		return cli.Usagef("использование: report [флаги] <запуск>")
+	}
+
+	var formats []string
+	for _, f := range strings.Split(reportFlags.format, ",") {
+		f = strings.TrimSpace(f)
+		if f != report.FormatMarkdown && f != report.FormatHTML {
+			return cli.Usagef("неизвестный формат отчета %q (допустимо: %s)", f, strings.Join(report.Formats, ", "))
+		}
+		formats = append(formats, f)
+	}
+
+	store := experiment.NewStore(reportFlags.store)
+	run, err := store.Find(env.Args[0])
+	if err != nil {
+		return err
+	}
+	results, err := store.Results(run)
+	if err != nil {
+		return err
+	}
+
+	base := reportFlags.out
+	if base == "" {
+		base = filepath.Join(store.Dir(), run.ID)
+	}
+	if dir := filepath.Dir(base); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("ошибка создания каталога отчета: %w", err)
+		}
+	}
+
+	r := report.Build(run, results)
+	out := reportOutput{Run: run.ID}
+	for _, format := range formats {
+		path := base + "." + format
+		if err := writeReport(path, r, format); err != nil {
+			return err
+		}
+		out.Files = append(out.Files, path)
+		utils.PrintSuccess("Отчет сохранен: " + path)
+	}
+	return env.Emit(out)
+}
+
+func writeReport(path string, r *report.Report, format string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("ошибка создания отчета: %w", err)
+	}
+	if err := report.Write(file, r, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/secrets"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// secretsArgs подкоманды secrets
+var secretsArgs = []string{"status", "keyring-set", "keyring-delete", "file-set"}
+
+// runSecrets управляет ключом API: связка ключей ОС и зашифрованный файл.
+// Ключ и пароль вводятся в терминале без эха; пароль можно передать через SECRETS_PASSPHRASE.
+func runSecrets(ctx context.Context, env *cli.Env) error {
+	if len(env.Args) == 0 {
+		return cli.Usagef("укажите подкоманду: %s", strings.Join(secretsArgs, ", "))
+	}
+
+	switch command := env.Args[0]; command {
+	case "status":
+		return secretsStatus(env)
+	case "keyring-set":
+		return keyringSet()
+	case "keyring-delete":
+		if err := secrets.KeyringDelete(secrets.APIKeyName); err != nil {
+			return err
+		}
+		utils.PrintSuccess("Ключ удален из связки ключей")
+		return nil
+	case "file-set":
+		return fileSet(env.Args[1:])
+	default:
+		return cli.Usagef("неизвестная подкоманда secrets %q (допустимо: %s)", command, strings.Join(secretsArgs, ", "))
+	}
+}
+
+// secretsStatus показывает источник ключа по текущей конфигурации
+func secretsStatus(env *cli.Env) error {
+	cfg, err := env.Flags.Load()
+	if err != nil {
+		return cli.ConfigError(err)
+	}
+	if cfg.OpenAIKey == "" {
+		utils.PrintInfo("Ключ не нужен: задан base_url " + cfg.BaseURL)
+		return nil
+	}
+
+	utils.PrintKeyValue("Источник", cfg.APIKeySource)
+	utils.PrintKeyValue("Ключ", redact.Mask(cfg.OpenAIKey))
+	return nil
+}
+
+// keyringSet сохраняет ключ в связке ключей ОС
+func keyringSet() error {
+	key, err := secrets.ReadHidden("Ключ API: ")
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("ключ не может быть пустым")
+	}
+
+	if err := secrets.KeyringSet(secrets.APIKeyName, key); err != nil {
+		return err
+	}
+	utils.PrintSuccess("Ключ сохранен в связке ключей (сервис " + secrets.KeyringService + ")")
+	return nil
+}
+
+// fileSet шифрует ключ паролем и сохраняет в файл, сохраняя прочие секреты файла
+func fileSet(args []string) error {
+	fs := flag.NewFlagSet("secrets file-set", flag.ContinueOnError)
+	path := fs.String("file", secrets.DefaultFile(), "зашифрованный файл")
+	if err := fs.Parse(args); err != nil {
+		return cli.Usagef("%v", err)
+	}
+
+	key, err := secrets.ReadHidden("Ключ API: ")
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("ключ не может быть пустым")
+	}
+
+	values := make(map[string]string)
+	passphrase := os.Getenv("SECRETS_PASSPHRASE")
+
+	if _, err := os.Stat(*path); err == nil {
+		// Файл уже есть - расшифровываем его прежним паролем
+		if passphrase == "" {
+			if passphrase, err = secrets.ReadHidden("Пароль к " + *path + ": "); err != nil {
+				return err
+			}
+		}
+		if values, err = secrets.ReadFile(*path, passphrase); err != nil {
+			return err
+		}
+	} else if passphrase == "" {
+		if passphrase, err = secrets.ReadHidden("Новый пароль: "); err != nil {
+			return err
+		}
+		confirm, err := secrets.ReadHidden("Повторите пароль: ")
+		if err != nil {
+			return err
+		}
+		if confirm != passphrase {
+			return errors.New("пароли не совпадают")
+		}
+	}
+
+	values[secrets.APIKeyName] = key
+	if err := secrets.WriteFile(*path, passphrase, values); err != nil {
+		return err
+	}
+	utils.PrintSuccess("Ключ зашифрован в " + *path)
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// usageFlags флаги команды usage
+var usageFlags struct {
+	path   string
+	by     string
+	format string
+	since  string
+	until  string
+	days   int
+	raw    bool
+}
+
+func bindUsageFlags(fs *flag.FlagSet) {
+	defaultPath, _ := usage.DefaultPath()
+
+	fs.StringVar(&usageFlags.path, "file", defaultPath, "путь к журналу использования")
+	fs.StringVar(&usageFlags.by, "by", "day", "группировка: day, model, session, command")
+	fs.StringVar(&usageFlags.format, "format", "table", "формат вывода: table, csv, json (-output json - то же, что json)")
+	fs.StringVar(&usageFlags.since, "since", "", "учитывать записи с даты (YYYY-MM-DD)")
+	fs.StringVar(&usageFlags.until, "until", "", "учитывать записи до даты, не включая ее (YYYY-MM-DD)")
+	fs.IntVar(&usageFlags.days, "days", 0, "учитывать записи за последние N дней")
+	fs.BoolVar(&usageFlags.raw, "raw", false, "выгрузить записи журнала без группировки (только -format csv или json)")
+}
+
+// runUsage выводит отчет по журналу использования API
+func runUsage(ctx context.Context, env *cli.Env) error {
+	if usageFlags.path == "" {
+		path, err := usage.DefaultPath()
+		if err != nil {
+			return err
+		}
+		usageFlags.path = path
+	}
+
+	groupBy, err := usage.ParseGroupBy(usageFlags.by)
+	if err != nil {
+		return cli.Usagef("%v", err)
+	}
+
+	from, err := parseDate(usageFlags.since)
+	if err != nil {
+		return cli.Usagef("некорректная дата -since: %v", err)
+	}
+	to, err := parseDate(usageFlags.until)
+	if err != nil {
+		return cli.Usagef("некорректная дата -until: %v", err)
+	}
+	if usageFlags.days > 0 {
+		from = time.Now().AddDate(0, 0, -usageFlags.days)
+	}
+
+	records, err := usage.NewLedger(usageFlags.path).Records()
+	if err != nil {
+		return err
+	}
+	records = usage.Filter(records, from, to)
+
+	format := usageFlags.format
+	if env.JSON() {
+		format = "json"
+	}
+
+	out := env.Stdout()
+	switch format {
+	case "json":
+		if usageFlags.raw {
+			err = usage.WriteJSON(out, records)
+		} else {
+			err = usage.WriteJSON(out, usage.Group(records, groupBy))
+		}
+	case "csv":
+		if usageFlags.raw {
+			err = usage.WriteRecordsCSV(out, records)
+		} else {
+			err = usage.WriteTotalsCSV(out, groupBy, usage.Group(records, groupBy))
+		}
+	case "table":
+		if usageFlags.raw {
+			return cli.Usagef("-raw выгружает записи только в csv или json: укажите -format csv или -format json")
+		}
+		printTable(usageFlags.path, groupBy, records)
+	default:
+		return cli.Usagef("неизвестный формат %q (допустимо: table, csv, json)", format)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка вывода: %w", err)
+	}
+	return nil
+}
+
+// parseDate разбирает дату в формате YYYY-MM-DD (пустая строка - без ограничения)
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+func printTable(path string, groupBy usage.GroupBy, records []usage.Record) {
+	utils.PrintSection("💰", "ИСПОЛЬЗОВАНИЕ API")
+	utils.PrintInfo(fmt.Sprintf("Журнал: %s", path))
+	fmt.Println()
+
+	if len(records) == 0 {
+		utils.PrintInfo("📭 Записей нет")
+		return
+	}
+
+	fmt.Printf("%-20s %8s %12s %12s %10s %10s %10s\n",
+		groupBy, "запросов", "prompt", "completion", "кэш", "стоимость", "ср. время")
+	utils.PrintDivider()
+
+	for _, g := range usage.Group(records, groupBy) {
+		printTotals(g)
+	}
+
+	utils.PrintDivider()
+	printTotals(usage.Sum(records))
+}
+
+func printTotals(t usage.Totals) {
+	fmt.Printf("%-20s %8d %12d %12d %10d %10s %10s\n",
+		t.Key, t.Requests, t.PromptTokens, t.CompletionTokens, t.CachedTokens,
+		fmt.Sprintf("$%.4f", t.Cost), t.AverageLatency())
+}
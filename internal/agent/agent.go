@@ -7,7 +7,15 @@ import (
 	"os"
 	"time"
 
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/memory"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
 	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Message представляет одно сообщение в диалоге
@@ -20,10 +28,15 @@ type Message struct {
 // AgentConfig конфигурация агента
 type AgentConfig struct {
 	APIKey       string
+	BaseURL      string // Адрес OpenAI-совместимого API (пусто - api.openai.com)
 	Model        string
-	Temperature  float32
+	Temperature  *float32 // nil - значение API по умолчанию (1.0); 0 отправляется как 0
 	MaxTokens    int
 	SystemPrompt string
+
+	// SystemPromptRef шаблон, по которому построен SystemPrompt: name@version#hash
+	// (пусто - промпт задан текстом). Пишется в трассировку и журнал использования.
+	SystemPromptRef string
 }
 
 // Agent представляет AI агента с памятью диалога
@@ -31,8 +44,12 @@ type Agent struct {
 	config    AgentConfig
 	client    *openai.Client
 	ctx       context.Context
-	history   []Message // История диалога
-	systemMsg *Message  // Системное сообщение (опционально)
+	history   []Message      // История диалога
+	systemMsg *Message       // Системное сообщение (опционально)
+	memory    *memory.Memory // Долговременная память фактов (опционально)
+
+	// Учет запросов: бюджет, журнал использования и метрики (опционально)
+	meter *telemetry.Meter
 }
 
 // Response ответ агента
@@ -43,13 +60,23 @@ type Response struct {
 	CompletionTokens int
 	ExecutionTime    time.Duration
 	Model            string
+	FactsLearned     int   // Новых и измененных фактов в памяти
+	MemoryError      error // Ошибка извлечения фактов (не прерывает диалог)
+	UsageError       error // Ошибка записи в журнал или модели нет в каталоге (не прерывает диалог)
+
+	Cost float64 // Стоимость запроса в долларах по каталогу моделей (0 - модели нет в каталоге)
 }
 
 // NewAgent создает нового агента
 func NewAgent(config AgentConfig) *Agent {
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	if config.BaseURL != "" {
+		clientConfig.BaseURL = config.BaseURL
+	}
+
 	agent := &Agent{
 		config:  config,
-		client:  openai.NewClient(config.APIKey),
+		client:  openai.NewClientWithConfig(clientConfig),
 		ctx:     context.Background(),
 		history: make([]Message, 0),
 	}
@@ -68,6 +95,36 @@ func NewAgent(config AgentConfig) *Agent {
 
 // Ask отправляет запрос агенту и получает ответ
 func (a *Agent) Ask(userMessage string) (*Response, error) {
+	return a.AskContext(a.ctx, userMessage)
+}
+
+// AskContext как Ask, но спан запроса становится дочерним для спана из ctx
+func (a *Agent) AskContext(ctx context.Context, userMessage string) (*Response, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "Agent.Ask", trace.WithAttributes(
+		telemetry.AttrRequestModel.String(a.config.Model),
+		telemetry.AttrHistoryMessages.Int(len(a.history)),
+	))
+	defer span.End()
+	if a.config.SystemPromptRef != "" {
+		span.SetAttributes(telemetry.AttrPromptTemplates.StringSlice([]string{a.config.SystemPromptRef}))
+	}
+
+	response, err := a.ask(ctx, userMessage)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		telemetry.AttrInputTokens.Int(response.PromptTokens),
+		telemetry.AttrOutputTokens.Int(response.CompletionTokens),
+		telemetry.AttrFactsLearned.Int(response.FactsLearned),
+	)
+	return response, nil
+}
+
+// ask выполняет один ход диалога
+func (a *Agent) ask(ctx context.Context, userMessage string) (*Response, error) {
 	// Добавляем сообщение пользователя в историю
 	a.history = append(a.history, Message{
 		Role:      "user",
@@ -78,11 +135,19 @@ func (a *Agent) Ask(userMessage string) (*Response, error) {
 	// Формируем сообщения для API
 	messages := a.buildMessages()
 
+	// Проверяем бюджет до отправки: запрос, который превысит лимит, не выполняется
+	if err := a.meter.Check(a.config.Model, messages, a.config.MaxTokens); err != nil {
+		a.history = a.history[:len(a.history)-1]
+		return nil, err
+	}
+
 	// Создаем запрос
 	req := openai.ChatCompletionRequest{
-		Model:       a.config.Model,
-		Messages:    messages,
-		Temperature: a.config.Temperature,
+		Model:    a.config.Model,
+		Messages: messages,
+	}
+	if a.config.Temperature != nil {
+		req.Temperature = client.APITemperature(*a.config.Temperature)
 	}
 
 	if a.config.MaxTokens > 0 {
@@ -91,15 +156,18 @@ func (a *Agent) Ask(userMessage string) (*Response, error) {
 
 	// Отправляем запрос
 	start := time.Now()
-	resp, err := a.client.CreateChatCompletion(a.ctx, req)
+	resp, err := telemetry.ChatCompletion(ctx, a.client, req)
 	elapsed := time.Since(start)
 
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при запросе к API: %w", err)
+		a.meter.Fail(a.config.Model, err)
+		return nil, redact.Error(fmt.Errorf("ошибка при запросе к API: %w", err))
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("получен пустой ответ от API")
+		err := fmt.Errorf("получен пустой ответ от API")
+		a.meter.Fail(a.config.Model, err)
+		return nil, err
 	}
 
 	assistantMessage := resp.Choices[0].Message.Content
@@ -111,25 +179,66 @@ func (a *Agent) Ask(userMessage string) (*Response, error) {
 		Timestamp: time.Now(),
 	})
 
-	return &Response{
+	response := &Response{
 		Content:          assistantMessage,
 		TokensUsed:       resp.Usage.TotalTokens,
 		PromptTokens:     resp.Usage.PromptTokens,
 		CompletionTokens: resp.Usage.CompletionTokens,
 		ExecutionTime:    elapsed,
 		Model:            resp.Model,
-	}, nil
+	}
+
+	// Учитываем расходы в бюджете, журнале использования и метриках
+	record, err := a.meter.Record(a.config.Model, a.config.SystemPromptRef, resp, elapsed)
+	response.Cost = record.Cost
+	if a.meter != nil {
+		response.UsageError = err
+	}
+	a.observeContext(record)
+
+	// Запоминаем факты из этого хода диалога
+	if a.memory != nil {
+		result, err := a.memory.ProcessTurn(ctx, userMessage, assistantMessage)
+		response.FactsLearned = result.Learned()
+		response.MemoryError = err
+	}
+
+	return response, nil
+}
+
+// observeContext обновляет в метриках заполненность окна контекста
+// (сам запрос учитывает Meter)
+func (a *Agent) observeContext(record usage.Record) {
+	metrics := a.meter.Metrics()
+	if metrics == nil {
+		return
+	}
+	// Без модели в каталоге окно контекста неизвестно, но токены учитываются
+	model, _ := models.Lookup(a.config.Model)
+	metrics.SetContextUsage(a.config.Model, record.PromptTokens, model.ContextWindow)
 }
 
 // buildMessages формирует список сообщений для API из истории
 func (a *Agent) buildMessages() []openai.ChatCompletionMessage {
 	messages := make([]openai.ChatCompletionMessage, 0)
 
-	// Добавляем системное сообщение
+	// Добавляем системное сообщение вместе с релевантными фактами из памяти
+	systemContent := ""
 	if a.systemMsg != nil {
+		systemContent = a.systemMsg.Content
+	}
+	if a.memory != nil && len(a.history) > 0 {
+		if facts := a.memory.PromptFor(a.history[len(a.history)-1].Content); facts != "" {
+			if systemContent != "" {
+				systemContent += "\n\n"
+			}
+			systemContent += facts
+		}
+	}
+	if systemContent != "" {
 		messages = append(messages, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: a.systemMsg.Content,
+			Content: systemContent,
 		})
 	}
 
@@ -166,24 +275,15 @@ func (a *Agent) GetHistorySize() int {
 	return len(a.history)
 }
 
-// GetTotalTokens подсчитывает примерное количество токенов в истории
-// (упрощенная оценка: ~4 символа на токен для английского, ~2 для русского)
+// GetTotalTokens подсчитывает количество токенов контекста, который уйдет в API
+// (по словарю модели, со служебными токенами сообщений)
 func (a *Agent) GetTotalTokens() int {
-	total := 0
-
-	if a.systemMsg != nil {
-		total += len(a.systemMsg.Content) / 3 // Примерная оценка
-	}
-
-	for _, msg := range a.history {
-		total += len(msg.Content) / 3 // Примерная оценка
-	}
-
-	return total
+	return tokenizer.CountMessages(a.config.Model, a.buildMessages())
 }
 
 // SetSystemPrompt устанавливает системный промпт
 func (a *Agent) SetSystemPrompt(prompt string) {
+	a.config.SystemPromptRef = "" // Промпт задан текстом, а не шаблоном
 	if prompt == "" {
 		a.systemMsg = nil
 	} else {
@@ -195,6 +295,23 @@ func (a *Agent) SetSystemPrompt(prompt string) {
 	}
 }
 
+// SetMemory подключает долговременную память фактов
+func (a *Agent) SetMemory(m *memory.Memory) {
+	a.memory = m
+}
+
+// GetMemory возвращает подключенную память фактов (или nil)
+func (a *Agent) GetMemory() *memory.Memory {
+	return a.memory
+}
+
+// SetMeter подключает учет запросов: каждый запрос пишется в журнал использования
+// и метрики (вместе с заполненностью окна контекста), а при заданном бюджете Ask вернет usage.ErrBudgetExceeded, если запрос может
+// превысить лимит
+func (a *Agent) SetMeter(meter *telemetry.Meter) {
+	a.meter = meter
+}
+
 // GetLastMessage возвращает последнее сообщение ассистента
 func (a *Agent) GetLastMessage() *Message {
 	for i := len(a.history) - 1; i >= 0; i-- {
@@ -228,8 +345,8 @@ func (a *Agent) SaveHistory(filename string) error {
 		return fmt.Errorf("ошибка сериализации: %w", err)
 	}
 
-	// Записываем в файл
-	err = os.WriteFile(filename, jsonData, 0644)
+	// Записываем в файл (ключи, случайно вставленные в диалог, маскируются)
+	err = os.WriteFile(filename, redact.Bytes(jsonData), 0644)
 	if err != nil {
 		return fmt.Errorf("ошибка записи в файл: %w", err)
 	}
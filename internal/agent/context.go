@@ -2,143 +2,291 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// DefaultSummaryModel модель, которой по умолчанию создаются summary
+	DefaultSummaryModel = openai.GPT4oMini
+
+	// contextStateVersion версия формата файла состояния ContextManager
+	contextStateVersion = 3
+)
+
+// ContextConfig пороги сжатия контекста в токенах
+type ContextConfig struct {
+	// Сжимать, когда несжатая середина истории (между summary и последними
+	// сообщениями) превышает это количество токенов
+	CompressThresholdTokens int `yaml:"compress_threshold_tokens"`
+
+	// Сколько токенов последних сообщений хранить "как есть"
+	RecentTokens int `yaml:"recent_tokens"`
+
+	// Максимальный размер одного summary в токенах
+	// (используется суммаризатором по умолчанию)
+	MaxSummaryTokens int `yaml:"max_summary_tokens"`
+
+	// Бюджет токенов на summary одного уровня: при его превышении блоки
+	// уровня N объединяются в один блок уровня N+1 (0 - без иерархии)
+	SummaryBudgetTokens int `yaml:"summary_budget_tokens"`
+
+	// Модель диалога, по словарю которой считаются токены
+	// (по умолчанию DefaultSummaryModel)
+	Model string `yaml:"model"`
+}
+
+// Validate проверяет пороги сжатия
+func (c ContextConfig) Validate() error {
+	if c.CompressThresholdTokens <= 0 {
+		return fmt.Errorf("compress_threshold_tokens должен быть положительным, получено %d", c.CompressThresholdTokens)
+	}
+	if c.RecentTokens < 0 {
+		return fmt.Errorf("recent_tokens не может быть отрицательным, получено %d", c.RecentTokens)
+	}
+	if c.MaxSummaryTokens <= 0 {
+		return fmt.Errorf("max_summary_tokens должен быть положительным, получено %d", c.MaxSummaryTokens)
+	}
+	if c.SummaryBudgetTokens < 0 {
+		return fmt.Errorf("summary_budget_tokens не может быть отрицательным, получено %d", c.SummaryBudgetTokens)
+	}
+	return nil
+}
+
 // ContextManager управляет историей сообщений с поддержкой сжатия
 type ContextManager struct {
 	// Полная история всех сообщений
 	fullHistory []Message
 
-	// Сжатые блоки истории (summary)
-	summaries []string
+	// Токены каждого сообщения fullHistory: сообщение токенизируется один раз
+	messageTokens []int
+
+	// Активные сжатые блоки истории (summary) с диапазонами сообщений.
+	// Блоки идут подряд с начала истории; объединенные блоки хранятся в Children.
+	summaries []Summary
 
-	// Количество сообщений в каждом сжатом блоке
-	compressionWindow int
+	// Пороги сжатия в токенах
+	config ContextConfig
 
-	// Количество последних сообщений, хранимых "как есть"
-	recentWindow int
+	// Суммаризатор для создания и объединения summary
+	summarizer Summarizer
 
-	// OpenAI клиент для создания summary
-	client *openai.Client
-	ctx    context.Context
+	// Метрики экономии от сжатия (опционально)
+	metrics *telemetry.Metrics
 }
 
 // ContextStats содержит статистику по контексту
 type ContextStats struct {
-	TotalMessages      int     // Всего сообщений
-	CompressedBlocks   int     // Сжатых блоков
-	RecentMessages     int     // Последних сообщений
-	OriginalTokens     int     // Токенов в оригинальной истории
-	CompressedTokens   int     // Токенов после сжатия
-	CompressionRatio   float64 // Коэффициент сжатия
-	TokensSaved        int     // Сэкономлено токенов
-	CompressionPercent float64 // Процент сжатия
-}
-
-// NewContextManager создает новый менеджер контекста
-func NewContextManager(client *openai.Client, compressionWindow, recentWindow int) *ContextManager {
+	TotalMessages      int          // Всего сообщений
+	CompressedBlocks   int          // Сжатых блоков
+	RecentMessages     int          // Последних сообщений
+	RecentTokens       int          // Токенов в последних сообщениях
+	PendingTokens      int          // Токенов в несжатой середине истории
+	SummaryTokens      int          // Токенов во всех summary
+	Levels             []LevelStats // Статистика по уровням summary
+	OriginalTokens     int          // Токенов в оригинальной истории
+	CompressedTokens   int          // Токенов после сжатия
+	CompressionRatio   float64      // Коэффициент сжатия
+	TokensSaved        int          // Сэкономлено токенов
+	CompressionPercent float64      // Процент сжатия
+}
+
+// NewContextManager создает новый менеджер контекста.
+// По умолчанию summary создает LLM (DefaultSummaryModel) с экстрактивным запасным
+// вариантом на случай ошибок API; без клиента используется только экстрактивный.
+// Другой суммаризатор можно задать через SetSummarizer.
+func NewContextManager(client *openai.Client, config ContextConfig) *ContextManager {
+	if config.Model == "" {
+		config.Model = DefaultSummaryModel
+	}
+
+	var summarizer Summarizer = NewExtractiveSummarizer(config.MaxSummaryTokens)
+	if client != nil {
+		// Шаблоны по умолчанию всегда корректны, поэтому ошибка здесь невозможна
+		llm, _ := NewLLMSummarizer(client, SummarizerConfig{MaxTokens: config.MaxSummaryTokens})
+		summarizer = NewFallbackSummarizer(llm, summarizer)
+	}
+
 	return &ContextManager{
-		fullHistory:       make([]Message, 0),
-		summaries:         make([]string, 0),
-		compressionWindow: compressionWindow,
-		recentWindow:      recentWindow,
-		client:            client,
-		ctx:               context.Background(),
+		fullHistory:   make([]Message, 0),
+		messageTokens: make([]int, 0),
+		summaries:     make([]Summary, 0),
+		config:        config,
+		summarizer:    summarizer,
 	}
 }
 
 // AddMessage добавляет новое сообщение в историю
 func (cm *ContextManager) AddMessage(role, content string) {
-	cm.fullHistory = append(cm.fullHistory, Message{
+	msg := Message{
 		Role:    role,
 		Content: content,
-	})
+	}
+	cm.fullHistory = append(cm.fullHistory, msg)
+	cm.messageTokens = append(cm.messageTokens, cm.countMessage(msg))
 }
 
-// shouldCompress проверяет, нужно ли сжимать историю
-func (cm *ContextManager) shouldCompress() bool {
-	// Количество сообщений, которые можно сжать
-	compressibleCount := len(cm.fullHistory) - cm.recentWindow
-
-	// Количество уже сжатых сообщений
-	alreadyCompressed := len(cm.summaries) * cm.compressionWindow
+// SetSummarizer меняет суммаризатор. Блоки, созданные другой моделью
+// или версией промпта, становятся устаревшими (см. RefreshStaleSummaries).
+func (cm *ContextManager) SetSummarizer(summarizer Summarizer) {
+	cm.summarizer = summarizer
+}
 
-	// Количество несжатых сообщений (кроме recent)
-	uncompressed := compressibleCount - alreadyCompressed
+// SetMetrics подключает метрики: после каждого CompressIfNeeded
+// обновляется экономия токенов от сжатия
+func (cm *ContextManager) SetMetrics(metrics *telemetry.Metrics) {
+	cm.metrics = metrics
+}
 
-	// Сжимаем, если накопилось >= compressionWindow несжатых сообщений
-	return uncompressed >= cm.compressionWindow
+// reportCompression передает текущую экономию от сжатия в метрики
+func (cm *ContextManager) reportCompression() {
+	if cm.metrics == nil {
+		return
+	}
+	stats := cm.GetStats() // Токены сообщений уже посчитаны: пересчитываются только summary
+	cm.metrics.SetCompression(cm.config.Model, stats.OriginalTokens, stats.CompressedTokens)
 }
 
-// CompressIfNeeded проверяет и сжимает историю при необходимости
-func (cm *ContextManager) CompressIfNeeded() error {
-	if !cm.shouldCompress() {
-		return nil
+// compressedCount возвращает количество сообщений, уже покрытых summary
+func (cm *ContextManager) compressedCount() int {
+	if len(cm.summaries) == 0 {
+		return 0
 	}
+	return cm.summaries[len(cm.summaries)-1].EndIndex
+}
+
+// countTokens считает токены в тексте по словарю модели диалога
+func (cm *ContextManager) countTokens(text string) int {
+	return tokenizer.Count(cm.config.Model, text)
+}
 
-	// Находим диапазон для сжатия
-	compressedCount := len(cm.summaries) * cm.compressionWindow
-	startIdx := compressedCount
-	endIdx := startIdx + cm.compressionWindow
+// countMessage считает токены сообщения вместе со служебными токенами формата чата
+func (cm *ContextManager) countMessage(msg Message) int {
+	return tokenizer.CountMessage(cm.config.Model, msg.Role, msg.Content)
+}
 
-	if endIdx > len(cm.fullHistory)-cm.recentWindow {
-		endIdx = len(cm.fullHistory) - cm.recentWindow
+// historyTokens возвращает токены сообщений истории [start, end) из кэша
+func (cm *ContextManager) historyTokens(start, end int) int {
+	total := 0
+	for _, tokens := range cm.messageTokens[start:end] {
+		total += tokens
 	}
+	return total
+}
 
-	// Извлекаем блок для сжатия
-	blockToCompress := cm.fullHistory[startIdx:endIdx]
+// recentStart возвращает индекс первого сообщения, хранимого "как есть".
+// С конца истории набираются сообщения, пока их сумма не превысит RecentTokens;
+// последнее сообщение хранится всегда. Уже сжатые сообщения в recent не попадают.
+func (cm *ContextManager) recentStart() int {
+	start := len(cm.fullHistory)
+	tokens := 0
+
+	for i := len(cm.fullHistory) - 1; i >= 0; i-- {
+		msgTokens := cm.messageTokens[i]
+		if start < len(cm.fullHistory) && tokens+msgTokens > cm.config.RecentTokens {
+			break
+		}
+		tokens += msgTokens
+		start = i
+	}
 
-	// Создаем summary
-	summary, err := cm.createSummary(blockToCompress)
-	if err != nil {
-		return fmt.Errorf("failed to create summary: %w", err)
+	if compressed := cm.compressedCount(); start < compressed {
+		start = compressed
 	}
 
-	// Сохраняем summary
-	cm.summaries = append(cm.summaries, summary)
+	return start
+}
 
-	return nil
+// shouldCompress проверяет, нужно ли сжимать несжатую середину истории
+// (между последним summary и последними сообщениями) из pendingTokens токенов
+func (cm *ContextManager) shouldCompress(pendingTokens int) bool {
+	// Сжимаем, если в ней накопилось больше CompressThresholdTokens токенов
+	return pendingTokens > cm.config.CompressThresholdTokens
 }
 
-// createSummary создает краткое содержание блока сообщений
-func (cm *ContextManager) createSummary(messages []Message) (string, error) {
-	// Формируем текст для суммаризации
-	var dialogText string
-	for _, msg := range messages {
-		dialogText += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+// CompressIfNeeded проверяет и сжимает историю при необходимости.
+// Спан создается только для сжатия: проверка без сжатия - каждый ход.
+func (cm *ContextManager) CompressIfNeeded(ctx context.Context) (err error) {
+	defer cm.reportCompression()
+
+	// Сжимаем всю несжатую середину одним блоком
+	startIdx := cm.compressedCount()
+	endIdx := cm.recentStart()
+	pendingTokens := cm.historyTokens(startIdx, endIdx)
+	if !cm.shouldCompress(pendingTokens) {
+		return nil
 	}
 
-	// Запрос на создание summary
-	prompt := fmt.Sprintf(`Создай краткое содержание следующего диалога, сохранив ключевые факты, решения и выводы:
+	ctx, span := telemetry.Tracer().Start(ctx, "ContextManager.CompressIfNeeded", trace.WithAttributes(
+		telemetry.AttrHistoryMessages.Int(len(cm.fullHistory)),
+		telemetry.AttrPendingTokens.Int(pendingTokens),
+	))
+	defer func() {
+		telemetry.RecordError(span, err)
+		span.End()
+	}()
 
-%s
+	// Извлекаем блок для сжатия
+	blockToCompress := cm.fullHistory[startIdx:endIdx]
 
-Краткое содержание (2-3 предложения):`, dialogText)
+	// Создаем summary
+	summary, err := cm.createSummary(ctx, blockToCompress)
+	if err != nil {
+		return fmt.Errorf("failed to create summary: %w", err)
+	}
 
-	resp, err := cm.client.CreateChatCompletion(cm.ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4oMini,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.3, // Низкая температура для точности
-		MaxTokens:   150,
+	// Сохраняем summary нулевого уровня вместе с диапазоном сообщений
+	cm.summaries = append(cm.summaries, Summary{
+		Content:       summary.Content,
+		Level:         0,
+		StartIndex:    startIdx,
+		EndIndex:      endIdx,
+		Model:         summary.Model,
+		PromptVersion: summary.PromptVersion,
+		CreatedAt:     time.Now(),
 	})
 
-	if err != nil {
-		return "", err
+	// Объединяем уровни, если summary перестали помещаться в бюджет
+	if err := cm.mergeLevelsIfNeeded(ctx); err != nil {
+		return fmt.Errorf("failed to merge summaries: %w", err)
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no summary generated")
-	}
+	return nil
+}
+
+// createSummary сжимает блок сообщений суммаризатором внутри спана
+func (cm *ContextManager) createSummary(ctx context.Context, messages []Message) (SummaryText, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ContextManager.createSummary", trace.WithAttributes(
+		telemetry.AttrMessages.Int(len(messages)),
+		telemetry.AttrSummarizerModel.String(cm.summarizer.Model()),
+	))
+	defer span.End()
+
+	summary, err := cm.summarizer.Summarize(ctx, messages)
+	telemetry.RecordError(span, err)
+	return summary, err
+}
 
-	return resp.Choices[0].Message.Content, nil
+// mergeSummaries объединяет блоки одного уровня суммаризатором внутри спана
+func (cm *ContextManager) mergeSummaries(ctx context.Context, summaries []Summary) (SummaryText, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ContextManager.mergeSummaries", trace.WithAttributes(
+		telemetry.AttrMessages.Int(len(summaries)),
+		telemetry.AttrSummaryLevel.Int(summaries[0].Level),
+		telemetry.AttrSummarizerModel.String(cm.summarizer.Model()),
+	))
+	defer span.End()
+
+	merged, err := cm.summarizer.Merge(ctx, summaries)
+	telemetry.RecordError(span, err)
+	return merged, err
 }
 
 // GetContextForRequest возвращает контекст для запроса (summaries + recent messages)
@@ -149,7 +297,8 @@ func (cm *ContextManager) GetContextForRequest() []Message {
 	if len(cm.summaries) > 0 {
 		var combinedSummary string
 		for i, summary := range cm.summaries {
-			combinedSummary += fmt.Sprintf("[Блок %d]: %s\n", i+1, summary)
+			combinedSummary += fmt.Sprintf("[Блок %d, сообщения %d-%d]: %s\n",
+				i+1, summary.StartIndex+1, summary.EndIndex, summary.Content)
 		}
 		messages = append(messages, Message{
 			Role:    "system",
@@ -157,14 +306,8 @@ func (cm *ContextManager) GetContextForRequest() []Message {
 		})
 	}
 
-	// Добавляем последние N сообщений
-	recentStart := len(cm.fullHistory) - cm.recentWindow
-	if recentStart < 0 {
-		recentStart = 0
-	}
-
-	recentMessages := cm.fullHistory[recentStart:]
-	messages = append(messages, recentMessages...)
+	// Добавляем несжатые сообщения: середину (если порог еще не достигнут) и последние
+	messages = append(messages, cm.fullHistory[cm.compressedCount():]...)
 
 	return messages
 }
@@ -176,32 +319,26 @@ func (cm *ContextManager) GetFullHistory() []Message {
 
 // GetStats возвращает статистику по контексту
 func (cm *ContextManager) GetStats() ContextStats {
+	compressed := cm.compressedCount()
+	recentStart := cm.recentStart()
+
 	stats := ContextStats{
 		TotalMessages:    len(cm.fullHistory),
 		CompressedBlocks: len(cm.summaries),
+		RecentMessages:   len(cm.fullHistory) - recentStart,
 	}
 
-	// Количество последних сообщений
-	recentStart := len(cm.fullHistory) - cm.recentWindow
-	if recentStart < 0 {
-		stats.RecentMessages = len(cm.fullHistory)
-	} else {
-		stats.RecentMessages = cm.recentWindow
-	}
-
-	// Оценка токенов (приблизительно: 1 токен ≈ 3 символа для русского)
-	// Полная история
-	for _, msg := range cm.fullHistory {
-		stats.OriginalTokens += len(msg.Content) / 3
+	// Точный подсчет токенов по словарю модели
+	stats.OriginalTokens = cm.historyTokens(0, len(cm.fullHistory))
+	stats.RecentTokens = cm.historyTokens(recentStart, len(cm.fullHistory))
+	stats.PendingTokens = cm.historyTokens(compressed, recentStart)
+	stats.Levels = cm.levelStats()
+	for _, level := range stats.Levels {
+		stats.SummaryTokens += level.Tokens
 	}
 
-	// Сжатая история (summaries + recent)
-	for _, summary := range cm.summaries {
-		stats.CompressedTokens += len(summary) / 3
-	}
-	for i := len(cm.fullHistory) - stats.RecentMessages; i < len(cm.fullHistory); i++ {
-		stats.CompressedTokens += len(cm.fullHistory[i].Content) / 3
-	}
+	// Сжатая история (summaries + все несжатые сообщения)
+	stats.CompressedTokens = stats.SummaryTokens + stats.PendingTokens + stats.RecentTokens
 
 	// Расчет экономии
 	if stats.OriginalTokens > 0 {
@@ -213,8 +350,155 @@ func (cm *ContextManager) GetStats() ContextStats {
 	return stats
 }
 
+// GetSummaries возвращает сжатые блоки с их диапазонами
+func (cm *ContextManager) GetSummaries() []Summary {
+	return cm.summaries
+}
+
 // Reset очищает всю историю и summaries
 func (cm *ContextManager) Reset() {
 	cm.fullHistory = make([]Message, 0)
-	cm.summaries = make([]string, 0)
+	cm.messageTokens = make([]int, 0)
+	cm.summaries = make([]Summary, 0)
+}
+
+// contextState формат файла состояния ContextManager
+type contextState struct {
+	Version                 int       `json:"version"`
+	CompressThresholdTokens int       `json:"compress_threshold_tokens"`
+	RecentTokens            int       `json:"recent_tokens"`
+	MaxSummaryTokens        int       `json:"max_summary_tokens"`
+	Model                   string    `json:"model"`
+	SummaryBudgetTokens     int       `json:"summary_budget_tokens"`
+	SummaryModel            string    `json:"summary_model"`
+	PromptVersion           string    `json:"prompt_version"`
+	History                 []Message `json:"history"`
+	Summaries               []Summary `json:"summaries"`
+	SavedAt                 time.Time `json:"saved_at"`
+}
+
+// config возвращает пороги сжатия из сохраненного состояния
+func (s *contextState) config() ContextConfig {
+	return ContextConfig{
+		CompressThresholdTokens: s.CompressThresholdTokens,
+		RecentTokens:            s.RecentTokens,
+		MaxSummaryTokens:        s.MaxSummaryTokens,
+		SummaryBudgetTokens:     s.SummaryBudgetTokens,
+		Model:                   s.Model,
+	}
+}
+
+// SaveState сохраняет полное состояние менеджера (историю, summaries и настройки) в JSON файл
+func (cm *ContextManager) SaveState(filename string) error {
+	state := contextState{
+		Version:                 contextStateVersion,
+		CompressThresholdTokens: cm.config.CompressThresholdTokens,
+		RecentTokens:            cm.config.RecentTokens,
+		MaxSummaryTokens:        cm.config.MaxSummaryTokens,
+		SummaryBudgetTokens:     cm.config.SummaryBudgetTokens,
+		Model:                   cm.config.Model,
+		SummaryModel:            cm.summarizer.Model(),
+		PromptVersion:           cm.summarizer.PromptVersion(),
+		History:                 cm.fullHistory,
+		Summaries:               cm.summaries,
+		SavedAt:                 time.Now(),
+	}
+
+	jsonData, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	if err := os.WriteFile(filename, redact.Bytes(jsonData), 0644); err != nil {
+		return fmt.Errorf("ошибка записи в файл: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState восстанавливает состояние менеджера из JSON файла.
+// Отсутствие файла не считается ошибкой. Суммаризатор менеджера не меняется:
+// блоки, созданные другой моделью или версией промпта, остаются
+// устаревшими до вызова RefreshStaleSummaries. Файлы прежних версий формата
+// переводятся в текущий (см. migrate).
+func (cm *ContextManager) LoadState(filename string) error {
+	jsonData, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+
+	var state contextState
+	if err := json.Unmarshal(jsonData, &state); err != nil {
+		return fmt.Errorf("ошибка десериализации: %w", err)
+	}
+
+	if err := state.migrate(cm.config); err != nil {
+		return fmt.Errorf("состояние контекста в %s: %w", filename, err)
+	}
+	if err := state.validate(); err != nil {
+		return fmt.Errorf("некорректное состояние контекста в %s: %w", filename, err)
+	}
+
+	cm.fullHistory = state.History
+	cm.summaries = state.Summaries
+	cm.config = state.config()
+	if cm.config.Model == "" {
+		cm.config.Model = DefaultSummaryModel
+	}
+
+	if cm.fullHistory == nil {
+		cm.fullHistory = make([]Message, 0)
+	}
+	cm.messageTokens = make([]int, 0, len(cm.fullHistory))
+	for _, msg := range cm.fullHistory {
+		cm.messageTokens = append(cm.messageTokens, cm.countMessage(msg))
+	}
+	if cm.summaries == nil {
+		cm.summaries = make([]Summary, 0)
+	}
+
+	return nil
+}
+
+// migrate приводит состояние старого формата к текущему. current - настройки
+// менеджера, которых в старом файле нет: версия 1 хранила окна в сообщениях,
+// а не пороги в токенах, в версии 2 не было иерархии summary (все блоки уровня 0).
+func (s *contextState) migrate(current ContextConfig) error {
+	switch s.Version {
+	case 1:
+		s.CompressThresholdTokens = current.CompressThresholdTokens
+		s.RecentTokens = current.RecentTokens
+		s.MaxSummaryTokens = current.MaxSummaryTokens
+		s.Model = current.Model
+		fallthrough
+	case 2:
+		s.SummaryBudgetTokens = current.SummaryBudgetTokens
+	case contextStateVersion:
+	default:
+		return fmt.Errorf("неподдерживаемая версия формата %d (поддерживаются 1-%d): удалите файл, чтобы начать историю заново",
+			s.Version, contextStateVersion)
+	}
+	s.Version = contextStateVersion
+	return nil
+}
+
+// validate проверяет согласованность сохраненного состояния
+func (s *contextState) validate() error {
+	if err := s.config().Validate(); err != nil {
+		return err
+	}
+
+	// Блоки должны идти подряд с начала истории и не выходить за ее пределы
+	end, err := validateSummaryRange(s.Summaries, 0, "блок")
+	if err != nil {
+		return err
+	}
+	if end > len(s.History) {
+		return fmt.Errorf("summary ссылаются на сообщение %d, а в истории всего %d", end, len(s.History))
+	}
+
+	return nil
 }
@@ -0,0 +1,420 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
+	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Summarizer создает краткие содержания для ContextManager
+type Summarizer interface {
+	// Summarize сжимает блок сообщений
+	Summarize(ctx context.Context, messages []Message) (SummaryText, error)
+
+	// Merge объединяет несколько summary одного уровня в одно
+	Merge(ctx context.Context, summaries []Summary) (SummaryText, error)
+
+	// Model и PromptVersion определяют, какие сохраненные summary считаются устаревшими
+	Model() string
+	PromptVersion() string
+}
+
+// SummaryText результат суммаризации вместе с тем, кто его создал
+type SummaryText struct {
+	Content       string
+	Model         string
+	PromptVersion string
+}
+
+// DefaultSummaryLanguage язык summary по умолчанию
+const DefaultSummaryLanguage = "русский"
+
+// DefaultSummaryTemperature температура суммаризации по умолчанию: низкая для точности
+const DefaultSummaryTemperature float32 = 0.3
+
+var (
+	// DefaultSummaryPrompt шаблон промпта суммаризации блока сообщений
+	// (summarizer/summary во встроенной библиотеке промптов).
+	// Доступные переменные: {{.Dialog}}, {{.Language}}
+	DefaultSummaryPrompt = prompts.Default().MustGet("summarizer/summary@1").Source
+
+	// DefaultMergePrompt шаблон промпта объединения summary
+	// (summarizer/merge во встроенной библиотеке промптов).
+	// Доступные переменные: {{.Summaries}}, {{.Language}}
+	DefaultMergePrompt = prompts.Default().MustGet("summarizer/merge@1").Source
+)
+
+// SummarizerConfig настройки LLM-суммаризатора
+type SummarizerConfig struct {
+	Model          string   // Модель (по умолчанию DefaultSummaryModel)
+	PromptTemplate string   // Шаблон промпта для блока сообщений (по умолчанию DefaultSummaryPrompt)
+	MergeTemplate  string   // Шаблон промпта объединения (по умолчанию DefaultMergePrompt)
+	MaxTokens      int      // Максимальный размер summary в токенах
+	Temperature    *float32 // Температура (nil - DefaultSummaryTemperature, 0 - детерминированно)
+	Language       string   // Язык summary (по умолчанию DefaultSummaryLanguage)
+	PromptVersion  string   // Версия промптов (по умолчанию - хэш шаблонов и языка)
+}
+
+// LLMSummarizer создает summary с помощью языковой модели
+type LLMSummarizer struct {
+	config        SummarizerConfig
+	prompt        *template.Template
+	merge         *template.Template
+	promptVersion string
+	client        *openai.Client
+	meter         *telemetry.Meter // Бюджет и журнал использования (опционально)
+}
+
+// NewLLMSummarizer создает LLM-суммаризатор, подставляя значения по умолчанию
+func NewLLMSummarizer(client *openai.Client, config SummarizerConfig) (*LLMSummarizer, error) {
+	if config.Model == "" {
+		config.Model = DefaultSummaryModel
+	}
+	if config.PromptTemplate == "" {
+		config.PromptTemplate = DefaultSummaryPrompt
+	}
+	if config.MergeTemplate == "" {
+		config.MergeTemplate = DefaultMergePrompt
+	}
+	if config.Temperature == nil {
+		temperature := DefaultSummaryTemperature
+		config.Temperature = &temperature
+	}
+	if config.Language == "" {
+		config.Language = DefaultSummaryLanguage
+	}
+
+	prompt, err := template.New("summary").Parse(config.PromptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в шаблоне промпта суммаризации: %w", err)
+	}
+	merge, err := template.New("merge").Parse(config.MergeTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в шаблоне промпта объединения: %w", err)
+	}
+
+	// Версия по содержимому шаблонов: любое их изменение делает старые summary устаревшими
+	promptVersion := config.PromptVersion
+	if promptVersion == "" {
+		hash := sha256.Sum256([]byte(config.PromptTemplate + "\x00" + config.MergeTemplate + "\x00" + config.Language))
+		promptVersion = hex.EncodeToString(hash[:])[:12]
+	}
+
+	return &LLMSummarizer{
+		config:        config,
+		prompt:        prompt,
+		merge:         merge,
+		promptVersion: promptVersion,
+		client:        client,
+	}, nil
+}
+
+// Model возвращает модель суммаризации
+func (s *LLMSummarizer) Model() string {
+	return s.config.Model
+}
+
+// PromptVersion возвращает версию промптов
+func (s *LLMSummarizer) PromptVersion() string {
+	return s.promptVersion
+}
+
+// SetMeter подключает учет запросов: запросы суммаризации проверяются бюджетом
+// и пишутся в журнал использования
+func (s *LLMSummarizer) SetMeter(meter *telemetry.Meter) {
+	s.meter = meter
+}
+
+// Summarize сжимает блок сообщений
+func (s *LLMSummarizer) Summarize(ctx context.Context, messages []Message) (SummaryText, error) {
+	var dialogText string
+	for _, msg := range messages {
+		dialogText += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+	}
+
+	return s.complete(ctx, s.prompt, map[string]string{
+		"Dialog":   dialogText,
+		"Language": s.config.Language,
+	})
+}
+
+// Merge объединяет несколько summary в одно
+func (s *LLMSummarizer) Merge(ctx context.Context, summaries []Summary) (SummaryText, error) {
+	var parts string
+	for i, summary := range summaries {
+		parts += fmt.Sprintf("[Часть %d]: %s\n", i+1, summary.Content)
+	}
+
+	return s.complete(ctx, s.merge, map[string]string{
+		"Summaries": parts,
+		"Language":  s.config.Language,
+	})
+}
+
+// complete заполняет шаблон и отправляет промпт модели
+func (s *LLMSummarizer) complete(ctx context.Context, tmpl *template.Template, data map[string]string) (SummaryText, error) {
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return SummaryText{}, fmt.Errorf("ошибка заполнения шаблона: %w", err)
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model: s.config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt.String(),
+			},
+		},
+		Temperature: client.APITemperature(*s.config.Temperature),
+	}
+	if s.config.MaxTokens > 0 {
+		req.MaxTokens = s.config.MaxTokens
+	}
+
+	resp, _, err := s.meter.ChatCompletion(ctx, s.client, req, "")
+	if err != nil {
+		return SummaryText{}, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return SummaryText{}, fmt.Errorf("no summary generated")
+	}
+
+	return SummaryText{
+		Content:       resp.Choices[0].Message.Content,
+		Model:         s.config.Model,
+		PromptVersion: s.promptVersion,
+	}, nil
+}
+
+const (
+	// ExtractiveModel имя модели, которым помечаются экстрактивные summary
+	ExtractiveModel = "extractive"
+
+	// extractivePromptVersion версия алгоритма экстрактивной суммаризации
+	extractivePromptVersion = "1"
+)
+
+// ExtractiveSummarizer создает summary без LLM, выбирая самые информативные
+// предложения исходного текста. Подходит для офлайн-запусков и как запасной вариант.
+type ExtractiveSummarizer struct {
+	maxTokens int
+}
+
+// NewExtractiveSummarizer создает экстрактивный суммаризатор с ограничением размера в токенах
+func NewExtractiveSummarizer(maxTokens int) *ExtractiveSummarizer {
+	return &ExtractiveSummarizer{maxTokens: maxTokens}
+}
+
+// Model возвращает условное имя модели экстрактивного суммаризатора
+func (s *ExtractiveSummarizer) Model() string {
+	return ExtractiveModel
+}
+
+// PromptVersion возвращает версию алгоритма
+func (s *ExtractiveSummarizer) PromptVersion() string {
+	return extractivePromptVersion
+}
+
+// Summarize выбирает ключевые предложения из блока сообщений
+func (s *ExtractiveSummarizer) Summarize(_ context.Context, messages []Message) (SummaryText, error) {
+	texts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		texts = append(texts, msg.Content)
+	}
+	return s.extract(texts), nil
+}
+
+// Merge выбирает ключевые предложения из нескольких summary
+func (s *ExtractiveSummarizer) Merge(_ context.Context, summaries []Summary) (SummaryText, error) {
+	texts := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		texts = append(texts, summary.Content)
+	}
+	return s.extract(texts), nil
+}
+
+// extract ранжирует предложения по частоте их слов и оставляет лучшие в исходном порядке.
+// Если ранжировать нечего (нет слов длиннее трех символов), summary - начало исходного
+// текста в пределах лимита: пустое summary не прошло бы проверку при загрузке состояния.
+func (s *ExtractiveSummarizer) extract(texts []string) SummaryText {
+	sentences := splitSentences(texts)
+
+	// Частоты значимых слов во всем блоке
+	freq := make(map[string]int)
+	for _, sentence := range sentences {
+		for _, word := range significantWords(sentence) {
+			freq[word]++
+		}
+	}
+
+	type scored struct {
+		index int
+		score float64
+	}
+	ranked := make([]scored, 0, len(sentences))
+	for i, sentence := range sentences {
+		words := significantWords(sentence)
+		if len(words) == 0 {
+			continue
+		}
+		total := 0
+		for _, word := range words {
+			total += freq[word]
+		}
+		ranked = append(ranked, scored{index: i, score: float64(total) / float64(len(words))})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	// Набираем предложения, пока помещаемся в лимит токенов
+	selected := make([]int, 0)
+	tokens := 0
+	for _, r := range ranked {
+		sentenceTokens := tokenizer.Count(tokenizer.DefaultModel, sentences[r.index])
+		if s.maxTokens > 0 && tokens+sentenceTokens > s.maxTokens && len(selected) > 0 {
+			continue
+		}
+		selected = append(selected, r.index)
+		tokens += sentenceTokens
+	}
+	sort.Ints(selected)
+
+	parts := make([]string, 0, len(selected))
+	for _, i := range selected {
+		parts = append(parts, sentences[i])
+	}
+
+	content := strings.Join(parts, " ")
+	if content == "" {
+		content = truncateTokens(strings.Join(sentences, " "), s.maxTokens)
+	}
+
+	return SummaryText{
+		Content:       content,
+		Model:         ExtractiveModel,
+		PromptVersion: extractivePromptVersion,
+	}
+}
+
+// splitSentences разбивает тексты на предложения
+func splitSentences(texts []string) []string {
+	sentences := make([]string, 0)
+	for _, text := range texts {
+		start := 0
+		runes := []rune(text)
+		for i, r := range runes {
+			if r == '.' || r == '!' || r == '?' || r == '\n' {
+				if sentence := strings.TrimSpace(string(runes[start : i+1])); len([]rune(sentence)) > 1 {
+					sentences = append(sentences, sentence)
+				}
+				start = i + 1
+			}
+		}
+		if sentence := strings.TrimSpace(string(runes[start:])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+	return sentences
+}
+
+// truncateTokens оставляет начальные слова text, которые помещаются в maxTokens
+// (0 - без ограничения); первое слово остается всегда
+func truncateTokens(text string, maxTokens int) string {
+	words := strings.Fields(text)
+	if maxTokens <= 0 || len(words) == 0 {
+		return strings.Join(words, " ")
+	}
+
+	kept := words[:1]
+	for i := 2; i <= len(words); i++ {
+		if tokenizer.Count(tokenizer.DefaultModel, strings.Join(words[:i], " ")) > maxTokens {
+			break
+		}
+		kept = words[:i]
+	}
+	return strings.Join(kept, " ")
+}
+
+// significantWords возвращает слова длиннее трех символов в нижнем регистре
+func significantWords(sentence string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) > 3 {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// FallbackSummarizer использует основной суммаризатор, а при его ошибке - запасной,
+// чтобы сжатие никогда не блокировало диалог. Summary, созданные запасным
+// суммаризатором, помечаются его моделью и пересчитываются RefreshStaleSummaries.
+type FallbackSummarizer struct {
+	primary  Summarizer
+	fallback Summarizer
+
+	// LastError последняя ошибка основного суммаризатора (для диагностики)
+	LastError error
+}
+
+// NewFallbackSummarizer создает суммаризатор с запасным вариантом
+func NewFallbackSummarizer(primary, fallback Summarizer) *FallbackSummarizer {
+	return &FallbackSummarizer{primary: primary, fallback: fallback}
+}
+
+// Model возвращает модель основного суммаризатора
+func (s *FallbackSummarizer) Model() string {
+	return s.primary.Model()
+}
+
+// PromptVersion возвращает версию промптов основного суммаризатора
+func (s *FallbackSummarizer) PromptVersion() string {
+	return s.primary.PromptVersion()
+}
+
+// Summarize сжимает блок основным суммаризатором, при ошибке - запасным
+func (s *FallbackSummarizer) Summarize(ctx context.Context, messages []Message) (SummaryText, error) {
+	result, err := s.primary.Summarize(ctx, messages)
+	if err == nil {
+		return result, nil
+	}
+	s.recordFallback(ctx, err)
+	return s.fallback.Summarize(ctx, messages)
+}
+
+// Merge объединяет summary основным суммаризатором, при ошибке - запасным
+func (s *FallbackSummarizer) Merge(ctx context.Context, summaries []Summary) (SummaryText, error) {
+	result, err := s.primary.Merge(ctx, summaries)
+	if err == nil {
+		return result, nil
+	}
+	s.recordFallback(ctx, err)
+	return s.fallback.Merge(ctx, summaries)
+}
+
+// recordFallback запоминает ошибку основного суммаризатора и отмечает переход
+// на запасной событием в текущем спане
+func (s *FallbackSummarizer) recordFallback(ctx context.Context, err error) {
+	s.LastError = err
+	trace.SpanFromContext(ctx).AddEvent("fallback", trace.WithAttributes(
+		telemetry.AttrFallbackReason.String(err.Error()),
+		telemetry.AttrSummarizerModel.String(s.fallback.Model()),
+	))
+}
@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+)
+
+// Summary сжатый блок истории
+type Summary struct {
+	Content       string    `json:"content"`            // Краткое содержание блока
+	Level         int       `json:"level"`              // Уровень: 0 - summary сообщений, N - summary блоков уровня N-1
+	StartIndex    int       `json:"start_index"`        // Индекс первого сообщения блока в полной истории
+	EndIndex      int       `json:"end_index"`          // Индекс, следующий за последним сообщением блока
+	Model         string    `json:"model"`              // Модель, создавшая summary
+	PromptVersion string    `json:"prompt_version"`     // Версия промпта суммаризации
+	CreatedAt     time.Time `json:"created_at"`         // Время создания summary
+	Children      []Summary `json:"children,omitempty"` // Блоки уровня ниже, из которых собран этот блок
+}
+
+// Size возвращает количество сообщений, покрытых блоком
+func (s Summary) Size() int {
+	return s.EndIndex - s.StartIndex
+}
+
+// LevelStats статистика по одному уровню summary
+type LevelStats struct {
+	Level    int // Уровень
+	Blocks   int // Активных блоков этого уровня
+	Tokens   int // Токенов в блоках
+	Messages int // Сообщений, покрытых блоками
+}
+
+// ExpandSummary возвращает исходные сообщения, покрытые summary (для отладки)
+func (cm *ContextManager) ExpandSummary(s Summary) []Message {
+	if s.StartIndex < 0 || s.EndIndex > len(cm.fullHistory) || s.StartIndex >= s.EndIndex {
+		return nil
+	}
+	return cm.fullHistory[s.StartIndex:s.EndIndex]
+}
+
+// levelRun возвращает диапазон [from, to) активных блоков указанного уровня.
+// Блоки верхних уровней всегда старше, поэтому блоки одного уровня идут подряд.
+func (cm *ContextManager) levelRun(level int) (from, to int) {
+	from = -1
+	for i, s := range cm.summaries {
+		if s.Level != level {
+			continue
+		}
+		if from < 0 {
+			from = i
+		}
+		to = i + 1
+	}
+	if from < 0 {
+		return 0, 0
+	}
+	return from, to
+}
+
+// mergeLevelsIfNeeded объединяет блоки уровня N в один блок уровня N+1,
+// пока суммарный размер блоков какого-либо уровня превышает бюджет
+func (cm *ContextManager) mergeLevelsIfNeeded(ctx context.Context) error {
+	if cm.config.SummaryBudgetTokens <= 0 {
+		return nil
+	}
+
+	for level := 0; ; level++ {
+		from, to := cm.levelRun(level)
+		if to-from == 0 {
+			return nil
+		}
+
+		run := cm.summaries[from:to]
+		tokens := 0
+		for _, s := range run {
+			tokens += cm.countTokens(s.Content)
+		}
+
+		// Один блок объединять не с чем - переходим к следующему уровню
+		if tokens <= cm.config.SummaryBudgetTokens || len(run) < 2 {
+			continue
+		}
+
+		merged, err := cm.mergeSummaries(ctx, run)
+		if err != nil {
+			return err
+		}
+
+		children := make([]Summary, len(run))
+		copy(children, run)
+
+		parent := Summary{
+			Content:       merged.Content,
+			Level:         level + 1,
+			StartIndex:    run[0].StartIndex,
+			EndIndex:      run[len(run)-1].EndIndex,
+			Model:         merged.Model,
+			PromptVersion: merged.PromptVersion,
+			CreatedAt:     time.Now(),
+			Children:      children,
+		}
+
+		summaries := make([]Summary, 0, len(cm.summaries)-len(run)+1)
+		summaries = append(summaries, cm.summaries[:from]...)
+		summaries = append(summaries, parent)
+		summaries = append(summaries, cm.summaries[to:]...)
+		cm.summaries = summaries
+	}
+}
+
+// isStale проверяет, создан ли блок другой моделью или версией промпта
+func (cm *ContextManager) isStale(s Summary) bool {
+	return s.Model != cm.summarizer.Model() || s.PromptVersion != cm.summarizer.PromptVersion()
+}
+
+// countStale считает устаревшие блоки в дереве summary
+func (cm *ContextManager) countStale(summaries []Summary) int {
+	count := 0
+	for _, s := range summaries {
+		if cm.isStale(s) {
+			count++
+		}
+		count += cm.countStale(s.Children)
+	}
+	return count
+}
+
+// StaleSummaries возвращает количество устаревших блоков на всех уровнях
+func (cm *ContextManager) StaleSummaries() int {
+	return cm.countStale(cm.summaries)
+}
+
+// RefreshStaleSummaries пересчитывает только устаревшие блоки
+// (созданные другой моделью или версией промпта) и возвращает их количество.
+// Блок верхнего уровня пересобирается, если пересчитан хотя бы один из его дочерних блоков.
+func (cm *ContextManager) RefreshStaleSummaries(ctx context.Context) (refreshed int, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ContextManager.RefreshStaleSummaries")
+	defer func() {
+		span.SetAttributes(telemetry.AttrSummaryRefreshed.Int(refreshed))
+		telemetry.RecordError(span, err)
+		span.End()
+	}()
+
+	for i := range cm.summaries {
+		n, err := cm.refreshSummary(ctx, &cm.summaries[i])
+		refreshed += n
+		if err != nil {
+			return refreshed, fmt.Errorf("failed to refresh summary %d: %w", i+1, err)
+		}
+	}
+	return refreshed, nil
+}
+
+// refreshSummary рекурсивно пересчитывает устаревший блок и его дочерние блоки
+func (cm *ContextManager) refreshSummary(ctx context.Context, s *Summary) (int, error) {
+	refreshed := 0
+	for i := range s.Children {
+		n, err := cm.refreshSummary(ctx, &s.Children[i])
+		refreshed += n
+		if err != nil {
+			return refreshed, err
+		}
+	}
+
+	if refreshed == 0 && !cm.isStale(*s) {
+		return 0, nil
+	}
+
+	var result SummaryText
+	var err error
+	if len(s.Children) > 0 {
+		result, err = cm.mergeSummaries(ctx, s.Children)
+	} else {
+		result, err = cm.createSummary(ctx, cm.fullHistory[s.StartIndex:s.EndIndex])
+	}
+	if err != nil {
+		return refreshed, err
+	}
+
+	s.Content = result.Content
+	s.Model = result.Model
+	s.PromptVersion = result.PromptVersion
+	s.CreatedAt = time.Now()
+
+	return refreshed + 1, nil
+}
+
+// levelStats собирает статистику по уровням активных summary
+func (cm *ContextManager) levelStats() []LevelStats {
+	byLevel := make(map[int]*LevelStats)
+	maxLevel := -1
+
+	for _, s := range cm.summaries {
+		stats, ok := byLevel[s.Level]
+		if !ok {
+			stats = &LevelStats{Level: s.Level}
+			byLevel[s.Level] = stats
+		}
+		stats.Blocks++
+		stats.Tokens += cm.countTokens(s.Content)
+		stats.Messages += s.Size()
+
+		if s.Level > maxLevel {
+			maxLevel = s.Level
+		}
+	}
+
+	levels := make([]LevelStats, 0, len(byLevel))
+	for level := 0; level <= maxLevel; level++ {
+		if stats, ok := byLevel[level]; ok {
+			levels = append(levels, *stats)
+		}
+	}
+
+	return levels
+}
+
+// validateSummaryRange проверяет, что блоки идут подряд начиная с сообщения start,
+// а дочерние блоки точно покрывают диапазон родителя. Возвращает конец диапазона.
+func validateSummaryRange(summaries []Summary, start int, label string) (int, error) {
+	expectedStart := start
+	for i, summary := range summaries {
+		name := fmt.Sprintf("%s %d", label, i+1)
+
+		if summary.StartIndex != expectedStart {
+			return 0, fmt.Errorf("%s начинается с сообщения %d, ожидалось %d", name, summary.StartIndex, expectedStart)
+		}
+		if summary.EndIndex <= summary.StartIndex {
+			return 0, fmt.Errorf("%s имеет пустой диапазон [%d, %d)", name, summary.StartIndex, summary.EndIndex)
+		}
+		if summary.Content == "" {
+			return 0, fmt.Errorf("%s не содержит summary", name)
+		}
+
+		if len(summary.Children) == 0 {
+			if summary.Level != 0 {
+				return 0, fmt.Errorf("%s уровня %d не содержит дочерних блоков", name, summary.Level)
+			}
+		} else {
+			for _, child := range summary.Children {
+				if child.Level != summary.Level-1 {
+					return 0, fmt.Errorf("%s уровня %d содержит блок уровня %d", name, summary.Level, child.Level)
+				}
+			}
+			end, err := validateSummaryRange(summary.Children, summary.StartIndex, name+" →")
+			if err != nil {
+				return 0, err
+			}
+			if end != summary.EndIndex {
+				return 0, fmt.Errorf("%s покрывает сообщения до %d, а дочерние блоки - до %d", name, summary.EndIndex, end)
+			}
+		}
+
+		expectedStart = summary.EndIndex
+	}
+
+	return expectedStart, nil
+}
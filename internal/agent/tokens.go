@@ -2,6 +2,8 @@ package agent
 
 import (
 	"fmt"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
 )
 
 // TokenStats статистика использования токенов
@@ -135,36 +137,7 @@ func (ts *TokenStats) GetWarningMessage() string {
 	return ""
 }
 
-// ModelLimits информация о лимитах моделей
-var ModelLimits = map[string]int{
-	"gpt-4o-mini":         128000,
-	"gpt-4o":              128000,
-	"gpt-4-turbo-preview": 128000,
-	"gpt-4":               8192,
-	"gpt-3.5-turbo":       16385,
-}
-
-// ModelPricing информация о ценах моделей (input/output per 1M tokens)
-var ModelPricing = map[string][2]float64{
-	"gpt-4o-mini":         {0.150, 0.600},
-	"gpt-4o":              {2.50, 10.00},
-	"gpt-4-turbo-preview": {10.00, 30.00},
-	"gpt-4":               {30.00, 60.00},
-	"gpt-3.5-turbo":       {0.50, 1.50},
-}
-
-// GetModelLimit возвращает лимит контекста для модели
-func GetModelLimit(model string) int {
-	if limit, ok := ModelLimits[model]; ok {
-		return limit
-	}
-	return 4096 // Дефолтное значение для неизвестных моделей
-}
-
-// GetModelPricing возвращает цены для модели
-func GetModelPricing(model string) (inputPrice, outputPrice float64) {
-	if pricing, ok := ModelPricing[model]; ok {
-		return pricing[0], pricing[1]
-	}
-	return 0.50, 1.50 // Дефолтные значения
+// NewTokenStatsForModel создает статистику токенов с лимитом и ценами модели из каталога
+func NewTokenStatsForModel(model models.Model) *TokenStats {
+	return NewTokenStats(model.ContextWindow, model.InputPrice, model.OutputPrice)
 }
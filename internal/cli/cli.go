@@ -0,0 +1,327 @@
+// Package cli реализует единый интерфейс командной строки advent:
+// подкоманды, общие флаги, коды завершения и автодополнение.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+	"golang.org/x/term"
+)
+
+// Command подкоманда advent
+type Command struct {
+	Name        string   // Имя подкоманды
+	Preset      string   // Сценарий дня (day1, ..., day9), под именем которого команда тоже доступна
+	Summary     string   // Краткое описание для справки
+	Args        []string // Допустимые позиционные аргументы (для справки и автодополнения)
+	NoConfig    bool     // Не загружать конфигурацию (команде не нужен ключ API)
+	Interactive bool     // Читает stdin в цикле: Ctrl+C завершает процесс сразу
+	JSON        bool     // Поддерживает -output json
+
+	// Flags регистрирует флаги команды (nil - только общие флаги)
+	Flags func(fs *flag.FlagSet)
+	// Run выполняет команду
+	Run func(ctx context.Context, env *Env) error
+}
+
+// App приложение с набором подкоманд
+type App struct {
+	Name     string
+	Summary  string
+	Commands []*Command
+}
+
+// options общие флаги, кроме флагов конфигурации
+type options struct {
+	output  string
+	noColor bool
+}
+
+// newOptions возвращает общие флаги со значениями по умолчанию
+func newOptions() *options {
+	return &options{output: OutputText}
+}
+
+// bind регистрирует общие флаги в fs; текущие значения становятся значениями по умолчанию,
+// чтобы флаги, указанные до команды, не сбрасывались при разборе флагов команды
+func (o *options) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.output, "output", o.output, "формат вывода: text или json")
+	fs.BoolVar(&o.noColor, "no-color", o.noColor, "без цветного вывода (также NO_COLOR)")
+}
+
+// Run разбирает аргументы, выполняет команду и возвращает код завершения
+func (a *App) Run(args []string) int {
+	log.SetFlags(0)
+	log.SetOutput(redact.NewWriter(os.Stderr))
+
+	opts := newOptions()
+	global := a.newFlagSet(a.Name)
+	cfgFlags := config.BindFlags(global)
+	opts.bind(global)
+	global.Usage = func() { a.printHelp(global.Output()) }
+
+	if err := global.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+	if global.NArg() == 0 {
+		a.printHelp(os.Stderr)
+		return ExitUsage
+	}
+
+	name := global.Arg(0)
+	switch name {
+	case "help":
+		return a.help(global.Args()[1:])
+	case "completion":
+		return a.completion(global.Args()[1:])
+	}
+
+	cmd := a.lookup(name)
+	if cmd == nil {
+		log.Printf("Ошибка: неизвестная команда %q (список команд: %s help)", name, a.Name)
+		return ExitUsage
+	}
+
+	fs := a.commandFlagSet(cmd, cfgFlags, opts)
+	if err := fs.Parse(global.Args()[1:]); err != nil {
+		return parseExitCode(err)
+	}
+
+	env := &Env{
+		Command: cmd.Name,
+		Preset:  cmd.Preset,
+		Args:    fs.Args(),
+		Flags:   cfgFlags,
+		Output:  opts.output,
+		stdout:  os.Stdout,
+	}
+	err := a.execute(cmd, env, *opts)
+	if err != nil {
+		log.Printf("Ошибка: %v", err)
+	}
+	return ExitCode(err)
+}
+
+// execute проверяет общие флаги, готовит окружение и выполняет команду
+func (a *App) execute(cmd *Command, env *Env, opts options) error {
+	switch opts.output {
+	case OutputText:
+	case OutputJSON:
+		if !cmd.JSON {
+			return Usagef("команда %s не поддерживает -output json", cmd.Name)
+		}
+		// Оформленный вывод команды уходит в stderr, в stdout - только результат
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	default:
+		return Usagef("неизвестный формат вывода %q (допустимо: text, json)", opts.output)
+	}
+
+	utils.SetColor(!opts.noColor && os.Getenv("NO_COLOR") == "" &&
+		term.IsTerminal(int(os.Stdout.Fd())))
+
+	ctx := context.Background()
+	if !cmd.Interactive {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// Первый Ctrl+C отменяет запросы, повторный завершает процесс сразу
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+	}
+
+	if cmd.NoConfig {
+		return run(ctx, cmd, env)
+	}
+
+	cfg, err := env.Flags.Load()
+	if err != nil {
+		return &configError{err: err}
+	}
+	env.Config = cfg
+
+	shutdownTracing, err := telemetry.SetupTracing(cfg.Tracing, a.Name)
+	if err != nil {
+		return &configError{err: fmt.Errorf("настройка трассировки: %w", err)}
+	}
+	defer shutdownTracing(context.Background())
+
+	ctx, span := telemetry.Tracer().Start(ctx, a.Name+" "+cmd.Name)
+	defer span.End()
+
+	err = run(ctx, cmd, env)
+	if err != nil {
+		telemetry.RecordError(span, err)
+	}
+	return err
+}
+
+// run выполняет команду; прерванная сигналом команда завершается с ошибкой,
+// даже если сценарий сам пропустил неудавшиеся запросы
+func run(ctx context.Context, cmd *Command, env *Env) error {
+	if err := cmd.Run(ctx, env); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// lookup ищет команду по имени или имени сценария дня
+func (a *App) lookup(name string) *Command {
+	for _, cmd := range a.Commands {
+		if cmd.Name == name || cmd.Preset == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// newFlagSet создает набор флагов, который возвращает ошибки вместо выхода из программы
+func (a *App) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// commandFlagSet создает набор флагов команды: ее собственные и общие,
+// чтобы общие флаги можно было указывать и после имени команды
+func (a *App) commandFlagSet(cmd *Command, cfgFlags *config.Flags, opts *options) *flag.FlagSet {
+	fs := a.newFlagSet(a.Name + " " + cmd.Name)
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+	own := flagNames(fs)
+
+	cfgFlags.Bind(fs)
+	opts.bind(fs)
+	fs.Usage = func() { a.printCommandHelp(fs.Output(), cmd, fs, own) }
+	return fs
+}
+
+// flagNames возвращает имена флагов набора
+func flagNames(fs *flag.FlagSet) map[string]bool {
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	return names
+}
+
+// parseExitCode код завершения для ошибки разбора флагов (сообщение уже выведено пакетом flag)
+func parseExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	return ExitUsage
+}
+
+// help выводит справку по приложению, команде или списку всех флагов
+func (a *App) help(args []string) int {
+	if len(args) == 0 {
+		a.printHelp(os.Stdout)
+		return ExitOK
+	}
+
+	if args[0] == "flags" {
+		fs := a.newFlagSet(a.Name)
+		config.BindFlags(fs)
+		newOptions().bind(fs)
+		fs.SetOutput(os.Stdout)
+		fmt.Printf("Общие флаги (указываются до или после команды):\n\n")
+		fs.PrintDefaults()
+		return ExitOK
+	}
+
+	cmd := a.lookup(args[0])
+	if cmd == nil {
+		log.Printf("Ошибка: неизвестная команда %q", args[0])
+		return ExitUsage
+	}
+	fs := a.commandFlagSet(cmd, &config.Flags{}, newOptions())
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+	return ExitOK
+}
+
+// printHelp выводит общую справку
+func (a *App) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "%s - %s\n\n", a.Name, a.Summary)
+	fmt.Fprintf(w, "Использование:\n  %s [общие флаги] <команда> [флаги команды] [аргументы]\n\n", a.Name)
+
+	fmt.Fprintln(w, "Команды:")
+	for _, cmd := range a.Commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "  %-16s %s\n", "completion", "скрипт автодополнения: bash, zsh или fish")
+	fmt.Fprintf(w, "  %-16s %s\n", "help", "справка по команде или всем флагам (help flags)")
+
+	presets := a.presets()
+	if len(presets) > 0 {
+		fmt.Fprintln(w, "\nСценарии дней (можно запускать по имени):")
+		for _, cmd := range presets {
+			fmt.Fprintf(w, "  %-16s = %s %s\n", cmd.Preset, a.Name, cmd.Name)
+		}
+	}
+
+	fmt.Fprintln(w, "\nОсновные общие флаги:")
+	fmt.Fprintln(w, "  -config путь      файл конфигурации")
+	fmt.Fprintln(w, "  -profile имя      профиль: cheap, quality, local или свой")
+	fmt.Fprintln(w, "  -model имя        модель диалога")
+	fmt.Fprintln(w, "  -output формат    text или json")
+	fmt.Fprintln(w, "  -no-color         без цветного вывода")
+	fmt.Fprintf(w, "Все флаги конфигурации: %s help flags\n", a.Name)
+
+	fmt.Fprintln(w, "\nКоды завершения:")
+	fmt.Fprintln(w, "  0 успешно, 1 ошибка выполнения, 2 неверные аргументы, 3 ошибка конфигурации,")
+	fmt.Fprintln(w, "  4 превышен лимит расходов, 130 прервано")
+}
+
+// printCommandHelp выводит справку по команде и только ее собственные флаги
+func (a *App) printCommandHelp(w io.Writer, cmd *Command, fs *flag.FlagSet, own map[string]bool) {
+	fmt.Fprintf(w, "%s %s - %s\n\n", a.Name, cmd.Name, cmd.Summary)
+
+	usage := fmt.Sprintf("%s %s [флаги]", a.Name, cmd.Name)
+	if len(cmd.Args) > 0 {
+		usage += " <" + strings.Join(cmd.Args, "|") + ">"
+	}
+	fmt.Fprintf(w, "Использование:\n  %s\n", usage)
+	if cmd.Preset != "" {
+		fmt.Fprintf(w, "  %s %s [флаги]\n", a.Name, cmd.Preset)
+	}
+
+	if len(own) > 0 {
+		fmt.Fprintln(w, "\nФлаги команды:")
+		fs.VisitAll(func(f *flag.Flag) {
+			if own[f.Name] {
+				fmt.Fprintf(w, "  -%s\n    \t%s\n", f.Name, f.Usage)
+			}
+		})
+	}
+	fmt.Fprintf(w, "\nОбщие флаги: %s help flags\n", a.Name)
+}
+
+// presets возвращает команды со сценариями дней в порядке дней
+func (a *App) presets() []*Command {
+	var presets []*Command
+	for _, cmd := range a.Commands {
+		if cmd.Preset != "" {
+			presets = append(presets, cmd)
+		}
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Preset < presets[j].Preset })
+	return presets
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+)
+
+// flagValues допустимые значения флагов для автодополнения
+var flagValues = map[string][]string{
+	"output":           {OutputText, OutputJSON},
+	"profile":          {"cheap", "quality", "local"},
+	"response-format":  {"text", "json_object"},
+	"tracing-exporter": {"otlp", "stdout", "file"},
+	"verify":           {"llm", "parser", "off"},
+}
+
+// completionCommand описание команды для скрипта автодополнения
+type completionCommand struct {
+	names   []string // Имя и сценарий дня
+	summary string
+	flags   []string // Все флаги с дефисом
+	args    []string
+}
+
+// completion выводит скрипт автодополнения для оболочки
+func (a *App) completion(args []string) int {
+	if len(args) != 1 {
+		log.Printf("Использование: %s completion bash|zsh|fish", a.Name)
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "bash":
+		a.writeBashCompletion(os.Stdout)
+	case "zsh":
+		fmt.Printf("#compdef %s\n\nautoload -U +X bashcompinit && bashcompinit\n\n", a.Name)
+		a.writeBashCompletion(os.Stdout)
+	case "fish":
+		a.writeFishCompletion(os.Stdout)
+	default:
+		log.Printf("Ошибка: неизвестная оболочка %q (допустимо: bash, zsh, fish)", args[0])
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// completionCommands собирает команды, их флаги и аргументы
+func (a *App) completionCommands() (commands []completionCommand, global []string, boolFlags []string) {
+	fs := a.newFlagSet(a.Name)
+	config.BindFlags(fs)
+	newOptions().bind(fs)
+	global, boolFlags = completionFlags(fs)
+
+	for _, cmd := range a.Commands {
+		names := []string{cmd.Name}
+		if cmd.Preset != "" {
+			names = append(names, cmd.Preset)
+		}
+
+		fs := a.commandFlagSet(cmd, &config.Flags{}, newOptions())
+		flags, bools := completionFlags(fs)
+		boolFlags = append(boolFlags, bools...)
+
+		commands = append(commands, completionCommand{
+			names:   names,
+			summary: cmd.Summary,
+			flags:   flags,
+			args:    cmd.Args,
+		})
+	}
+
+	commands = append(commands,
+		completionCommand{names: []string{"completion"}, summary: "скрипт автодополнения", args: []string{"bash", "zsh", "fish"}},
+		completionCommand{names: []string{"help"}, summary: "справка", args: append([]string{"flags"}, a.commandNames()...)},
+	)
+	return commands, global, uniq(boolFlags)
+}
+
+// completionFlags возвращает флаги набора и отдельно флаги без значения
+func completionFlags(fs *flag.FlagSet) (flags, bools []string) {
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "-"+f.Name)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			bools = append(bools, "-"+f.Name)
+		}
+	})
+	return flags, bools
+}
+
+// commandNames возвращает имена команд и сценариев дней
+func (a *App) commandNames() []string {
+	var names []string
+	for _, cmd := range a.Commands {
+		names = append(names, cmd.Name)
+		if cmd.Preset != "" {
+			names = append(names, cmd.Preset)
+		}
+	}
+	return names
+}
+
+// writeBashCompletion выводит функцию автодополнения для bash (и zsh через bashcompinit)
+func (a *App) writeBashCompletion(w io.Writer) {
+	commands, global, boolFlags := a.completionCommands()
+	fn := "_" + strings.ReplaceAll(a.Name, "-", "_")
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.names...)
+	}
+
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, `    local cur prev cmd skip word words`)
+	fmt.Fprintln(w, `    cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `    prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintf(w, "    local bools=\" %s \"\n\n", strings.Join(boolFlags, " "))
+
+	fmt.Fprintln(w, `    case "$prev" in`)
+	for _, name := range sortedKeys(flagValues) {
+		fmt.Fprintf(w, "        -%s|--%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n",
+			name, name, strings.Join(flagValues[name], " "))
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w)
+
+	// Первое слово, которое не флаг и не значение флага, - команда
+	fmt.Fprintln(w, `    cmd=""; skip=""`)
+	fmt.Fprintln(w, `    for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do`)
+	fmt.Fprintln(w, `        if [[ -n "$skip" ]]; then skip=""; continue; fi`)
+	fmt.Fprintln(w, `        case "$word" in`)
+	fmt.Fprintln(w, `            -*=*) ;;`)
+	fmt.Fprintln(w, `            -*) [[ "$bools" == *" ${word/#--/-} "* ]] || skip=1 ;;`)
+	fmt.Fprintln(w, `            *) cmd="$word"; break ;;`)
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	fmt.Fprintf(w, "        \"\") words=%q; [[ \"$cur\" == -* ]] && words=%q ;;\n",
+		strings.Join(names, " "), strings.Join(global, " "))
+	for _, c := range commands {
+		fmt.Fprintf(w, "        %s) words=%q; [[ \"$cur\" == -* ]] && words=%q ;;\n",
+			strings.Join(c.names, "|"), strings.Join(c.args, " "), strings.Join(c.flags, " "))
+	}
+	fmt.Fprintln(w, `        *) words="" ;;`)
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    COMPREPLY=($(compgen -W "$words" -- "$cur"))`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, a.Name)
+}
+
+// writeFishCompletion выводит правила автодополнения для fish
+func (a *App) writeFishCompletion(w io.Writer) {
+	commands, global, _ := a.completionCommands()
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.names...)
+	}
+	noCommand := fmt.Sprintf("not __fish_seen_subcommand_from %s", strings.Join(names, " "))
+
+	fmt.Fprintf(w, "complete -c %s -f\n", a.Name)
+	for _, c := range commands {
+		for _, name := range c.names {
+			fmt.Fprintf(w, "complete -c %s -n %q -a %s -d %q\n", a.Name, noCommand, name, c.summary)
+		}
+	}
+	for _, f := range global {
+		fmt.Fprintf(w, "complete -c %s -n %q -o %s%s\n", a.Name, noCommand, f[1:], fishValues(f[1:]))
+	}
+
+	for _, c := range commands {
+		seen := "__fish_seen_subcommand_from " + strings.Join(c.names, " ")
+		if len(c.args) > 0 {
+			fmt.Fprintf(w, "complete -c %s -n %q -a %q\n", a.Name, seen, strings.Join(c.args, " "))
+		}
+		for _, f := range c.flags {
+			fmt.Fprintf(w, "complete -c %s -n %q -o %s%s\n", a.Name, seen, f[1:], fishValues(f[1:]))
+		}
+	}
+}
+
+// fishValues возвращает опции fish для значений флага
+func fishValues(name string) string {
+	values, ok := flagValues[name]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" -r -a %q", strings.Join(values, " "))
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uniq(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
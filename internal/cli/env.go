@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// Форматы вывода (-output)
+const (
+	OutputText = "text" // Оформленный вывод для человека
+	OutputJSON = "json" // Результат команды одним JSON-документом в stdout
+)
+
+// Env окружение выполнения команды
+type Env struct {
+	Command string         // Имя команды
+	Preset  string         // Пресет конфигурации команды (день задания), пусто - без пресета
+	Args    []string       // Позиционные аргументы после флагов команды
+	Config  *config.Config // Конфигурация (nil для команд с NoConfig)
+	Flags   *config.Flags  // Флаги конфигурации, для команд, загружающих ее сами
+	Output  string         // OutputText или OutputJSON
+
+	stdout  io.Writer // Исходный stdout: в режиме json оформленный вывод уходит в stderr
+	budget  *usage.Budget
+	meter   *telemetry.Meter
+	metrics *telemetry.Metrics
+	session string // Идентификатор запуска для журнала и метрик
+}
+
+// JSON сообщает, запрошен ли вывод в JSON
+func (e *Env) JSON() bool {
+	return e.Output == OutputJSON
+}
+
+// Stdout возвращает поток для результата команды
+func (e *Env) Stdout() io.Writer {
+	return e.stdout
+}
+
+// Emit выводит результат команды в JSON, если он запрошен (-output json).
+// В текстовом режиме ничего не делает: результат уже выведен по ходу работы.
+func (e *Env) Emit(result any) error {
+	if !e.JSON() {
+		return nil
+	}
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// Budget возвращает лимиты расходов из конфигурации (одни на все клиенты команды)
+func (e *Env) Budget() (*usage.Budget, error) {
+	if e.budget != nil {
+		return e.budget, nil
+	}
+	budget, err := usage.OpenBudget(e.Config.Budget)
+	if err != nil {
+		return nil, err
+	}
+	e.budget = budget
+	return budget, nil
+}
+
+// Metrics возвращает метрики Prometheus команды; при первом вызове запускает
+// /metrics на адресе из конфигурации (адрес не задан - nil, метрики отключены)
+func (e *Env) Metrics() (*telemetry.Metrics, error) {
+	if e.metrics != nil || e.Config.MetricsAddr == "" {
+		return e.metrics, nil
+	}
+	metrics, err := telemetry.StartMetrics(e.Config.MetricsAddr, e.sessionID())
+	if err != nil {
+		return nil, fmt.Errorf("запуск метрик: %w", err)
+	}
+	e.metrics = metrics
+	return metrics, nil
+}
+
+// sessionID возвращает идентификатор запуска (один на команду)
+func (e *Env) sessionID() string {
+	if e.session == "" {
+		e.session = usage.NewSessionID()
+	}
+	return e.session
+}
+
+// Meter возвращает учет запросов команды (один на все клиенты команды): каждый
+// запрос проверяется лимитами расходов из конфигурации, пишется в журнал
+// использования под именем пресета (day1...day9) или команды и учитывается
+// в метриках (если задан адрес метрик). Если журнал недоступен, запросы
+// учитываются без него, с предупреждением.
+func (e *Env) Meter() (*telemetry.Meter, error) {
+	if e.meter != nil {
+		return e.meter, nil
+	}
+	budget, err := e.Budget()
+	if err != nil {
+		return nil, fmt.Errorf("загрузка бюджета: %w", err)
+	}
+
+	ledger, err := usage.Open()
+	if err != nil {
+		utils.PrintWarning(fmt.Sprintf("Журнал использования недоступен: %v", err))
+	}
+
+	command := e.Preset
+	if command == "" {
+		command = e.Command
+	}
+	metrics, err := e.Metrics()
+	if err != nil {
+		return nil, err
+	}
+
+	meter := telemetry.NewMeter(ledger, e.sessionID(), command)
+	meter.SetBudget(budget)
+	meter.SetMetrics(metrics)
+	e.meter = meter
+	return meter, nil
+}
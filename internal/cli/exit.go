@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+)
+
+// Коды завершения advent
+const (
+	ExitOK          = 0   // Успешно
+	ExitError       = 1   // Ошибка выполнения (API, файлы, сеть)
+	ExitUsage       = 2   // Неверные аргументы: неизвестная команда, флаг или значение
+	ExitConfig      = 3   // Ошибка конфигурации: файл, профиль, окружение, ключ API
+	ExitBudget      = 4   // Превышен лимит расходов
+	ExitInterrupted = 130 // Прервано сигналом (Ctrl+C)
+)
+
+// UsageError ошибка в аргументах командной строки
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// Usagef создает UsageError с форматированным сообщением
+func Usagef(format string, args ...any) error {
+	return &UsageError{Message: fmt.Sprintf(format, args...)}
+}
+
+// configError ошибка загрузки конфигурации
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return "загрузка конфигурации: " + e.err.Error()
+}
+
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// ConfigError помечает ошибку как ошибку конфигурации (код ExitConfig)
+// для команд, которые загружают конфигурацию сами
+func ConfigError(err error) error {
+	return &configError{err: err}
+}
+
+// ExitCode возвращает код завершения для ошибки команды
+func ExitCode(err error) int {
+	var usageErr *UsageError
+	var cfgErr *configError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &cfgErr):
+		return ExitConfig
+	case errors.Is(err, usage.ErrBudgetExceeded):
+		return ExitBudget
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	}
+	return ExitError
+}
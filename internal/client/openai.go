@@ -2,8 +2,15 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -11,23 +18,61 @@ import (
 type OpenAIClient struct {
 	client *openai.Client
 	ctx    context.Context
+	model  string           // Модель для запросов без CompletionRequest.Model
+	meter  *telemetry.Meter // Бюджет и журнал использования (опционально)
 }
 
 // NewOpenAIClient создает новый OpenAI клиент
 func NewOpenAIClient(apiKey string) *OpenAIClient {
+	return NewOpenAIClientWithConfig(openai.DefaultConfig(apiKey), "")
+}
+
+// NewOpenAIClientWithConfig создает клиент для любого OpenAI-совместимого API
+// с моделью по умолчанию (пусто - gpt-4o-mini)
+func NewOpenAIClientWithConfig(config openai.ClientConfig, model string) *OpenAIClient {
+	if model == "" {
+		model = openai.GPT4oMini
+	}
 	return &OpenAIClient{
-		client: openai.NewClient(apiKey),
+		client: openai.NewClientWithConfig(config),
 		ctx:    context.Background(),
+		model:  model,
 	}
 }
 
+// SetMeter подключает учет запросов: каждый запрос пишется в журнал использования,
+// а при заданном бюджете CreateCompletion вернет usage.ErrBudgetExceeded,
+// если запрос может превысить лимит
+func (c *OpenAIClient) SetMeter(meter *telemetry.Meter) {
+	c.meter = meter
+}
+
 // CompletionRequest представляет запрос к API
 type CompletionRequest struct {
+	Model          string                         // Пусто - модель клиента
+	System         string                         // Системный промпт (пусто - без системного сообщения)
+	History        []openai.ChatCompletionMessage // Сообщения диалога перед Prompt
 	Prompt         string
 	MaxTokens      int
-	Temperature    float32
+	Temperature    *float32 // nil - значение API по умолчанию (1.0); 0 отправляется как 0, см. Temperature
 	Stop           []string
 	ResponseFormat *openai.ChatCompletionResponseFormat
+	Templates      []string // Шаблоны промптов запроса: name@version#hash (для трассировки)
+}
+
+// Temperature возвращает температуру для CompletionRequest.Temperature
+func Temperature(t float32) *float32 {
+	return &t
+}
+
+// APITemperature значение для openai.ChatCompletionRequest.Temperature.
+// go-openai не отправляет нулевую температуру (omitempty), и API подставляет 1.0;
+// минимальное ненулевое значение равносильно 0.
+func APITemperature(t float32) float32 {
+	if t == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return t
 }
 
 // CompletionResponse представляет ответ от API
@@ -36,28 +81,49 @@ type CompletionResponse struct {
 	TotalTokens      int
 	PromptTokens     int
 	CompletionTokens int
+	CachedTokens     int     // Токены запроса, взятые из кэша
+	Cost             float64 // Стоимость по каталогу моделей (0 - модели нет в каталоге)
 	Model            string
 	FinishReason     string
 }
 
 // CreateCompletion выполняет запрос к OpenAI API
 func (c *OpenAIClient) CreateCompletion(req CompletionRequest) (*CompletionResponse, error) {
+	return c.CreateCompletionContext(c.ctx, req)
+}
+
+// CreateCompletionContext как CreateCompletion, но спан запроса становится дочерним для спана из ctx
+func (c *OpenAIClient) CreateCompletionContext(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "OpenAIClient.CreateCompletion")
+	defer span.End()
+	if len(req.Templates) > 0 {
+		span.SetAttributes(telemetry.AttrPromptTemplates.StringSlice(req.Templates))
+	}
+
+	resp, err := c.createCompletion(ctx, req)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// createCompletion проверяет бюджет и отправляет запрос
+func (c *OpenAIClient) createCompletion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
 	chatReq := openai.ChatCompletionRequest{
-		Model: openai.GPT4oMini,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: req.Prompt,
-			},
-		},
+		Model:    c.model,
+		Messages: requestMessages(req),
 	}
 
 	// Опциональные параметры
+	if req.Model != "" {
+		chatReq.Model = req.Model
+	}
 	if req.MaxTokens > 0 {
 		chatReq.MaxTokens = req.MaxTokens
 	}
-	if req.Temperature > 0 {
-		chatReq.Temperature = req.Temperature
+	if req.Temperature != nil {
+		chatReq.Temperature = APITemperature(*req.Temperature)
 	}
 	if len(req.Stop) > 0 {
 		chatReq.Stop = req.Stop
@@ -66,21 +132,48 @@ func (c *OpenAIClient) CreateCompletion(req CompletionRequest) (*CompletionRespo
 		chatReq.ResponseFormat = req.ResponseFormat
 	}
 
-	resp, err := c.client.CreateChatCompletion(c.ctx, chatReq)
+	// Бюджет проверяется до отправки, расходы учитываются после ответа
+	resp, record, err := c.meter.ChatCompletion(ctx, c.client, chatReq, strings.Join(req.Templates, ","))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при запросе к OpenAI API: %w", err)
+		if errors.Is(err, usage.ErrBudgetExceeded) || errors.Is(err, models.ErrUnknownModel) {
+			return nil, err
+		}
+		return nil, redact.Error(fmt.Errorf("ошибка при запросе к OpenAI API: %w", err))
 	}
 
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("получен пустой ответ от API")
 	}
 
+	cached := 0
+	if resp.Usage.PromptTokensDetails != nil {
+		cached = resp.Usage.PromptTokensDetails.CachedTokens
+	}
+
 	return &CompletionResponse{
 		Content:          resp.Choices[0].Message.Content,
 		TotalTokens:      resp.Usage.TotalTokens,
 		PromptTokens:     resp.Usage.PromptTokens,
 		CompletionTokens: resp.Usage.CompletionTokens,
+		CachedTokens:     cached,
+		Cost:             record.Cost,
 		Model:            resp.Model,
 		FinishReason:     string(resp.Choices[0].FinishReason),
 	}, nil
 }
+
+// requestMessages собирает сообщения запроса: системный промпт, история и Prompt
+func requestMessages(req CompletionRequest) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.History)+2)
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.System,
+		})
+	}
+	messages = append(messages, req.History...)
+	return append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: req.Prompt,
+	})
+}
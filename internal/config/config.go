@@ -1,23 +1,262 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"os"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/secrets"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+	openai "github.com/sashabaranov/go-openai"
 )
 
-// Config содержит конфигурацию приложения
+// Config содержит конфигурацию приложения.
+// Значения собираются слоями: значения по умолчанию, файл конфигурации,
+// выбранный профиль, переменные окружения и флаги командной строки.
 type Config struct {
-	OpenAIKey string
+	OpenAIKey    string `yaml:"-"` // Ключ API (никогда не читается из файла конфигурации)
+	APIKeySource string `yaml:"-"` // Откуда взят ключ: OPENAI_API_KEY, api_key_cmd, связка ключей, файл
+	Profile      string `yaml:"-"` // Примененный профиль (пусто - без профиля)
+
+	// Secrets источники ключа API помимо OPENAI_API_KEY
+	Secrets secrets.Config `yaml:"secrets"`
+
+	BaseURL        string   `yaml:"base_url"`        // Адрес OpenAI-совместимого API (пусто - api.openai.com)
+	Model          string   `yaml:"model"`           // Модель диалога
+	Temperature    float32  `yaml:"temperature"`     // Температура (0-2)
+	MaxTokens      int      `yaml:"max_tokens"`      // Максимальный размер ответа (0 - без ограничения)
+	SystemPrompt   string   `yaml:"system_prompt"`   // Системный промпт (пусто - промпт задания)
+	Stop           []string `yaml:"stop"`            // Стоп-последовательности
+	ResponseFormat string   `yaml:"response_format"` // "text" или "json_object" (пусто - text)
+
+	// Шаблоны промптов (internal/prompts): системный промпт по ссылке name@version
+	// заменяет system_prompt, свои шаблоны дополняют встроенную библиотеку
+	SystemPromptRef string `yaml:"system_prompt_ref"`
+	PromptsDir      string `yaml:"prompts_dir"`
+
+	Context    agent.ContextConfig `yaml:"context"`
+	Summarizer SummarizerSettings  `yaml:"summarizer"`
+	Judge      JudgeSettings       `yaml:"judge"`
+	Budget     usage.BudgetConfig  `yaml:"budget"`
+
+	// MetricsAddr адрес HTTP сервера с /metrics для Prometheus (пусто - выключен)
+	MetricsAddr string `yaml:"metrics_addr"`
+
+	// Tracing экспорт трассировки OpenTelemetry
+	Tracing telemetry.TracingConfig `yaml:"tracing"`
+
+	prompts      *prompts.Library // Встроенные шаблоны и шаблоны из PromptsDir
+	systemPrompt prompts.Rendered // Системный промпт, построенный по SystemPromptRef
 }
 
-// Load загружает конфигурацию из .env файла
+// SummarizerSettings настройки суммаризатора истории (пустые значения - по умолчанию)
+type SummarizerSettings struct {
+	Model          string   `yaml:"model"`       // Модель суммаризации
+	MaxTokens      int      `yaml:"max_tokens"`  // Устарело: если задан, заменяет context.max_summary_tokens
+	Language       string   `yaml:"language"`    // Язык summary
+	Temperature    *float32 `yaml:"temperature"` // Температура (не задана - 0.3, 0 - детерминированно)
+	PromptFile     string   `yaml:"prompt_file"` // Файл шаблона промпта
+	PromptTemplate string   `yaml:"prompt"`      // Шаблон промпта (по умолчанию - содержимое PromptFile)
+	Offline        bool     `yaml:"offline"`     // Только экстрактивная суммаризация без LLM
+}
+
+// JudgeSettings настройки LLM-судьи, оценивающего ответы (дни 4, 5 и эксперименты)
+type JudgeSettings struct {
+	Model string `yaml:"model"` // Модель судьи (пусто - модель диалога)
+}
+
+// Default возвращает значения по умолчанию
+func Default() Config {
+	return Config{
+		Model:       openai.GPT4oMini,
+		Temperature: 0.7,
+		Secrets:     secrets.Config{Keyring: true},
+		Context: agent.ContextConfig{
+			CompressThresholdTokens: 2000,
+			RecentTokens:            1000,
+			MaxSummaryTokens:        200,
+		},
+	}
+}
+
+// Load загружает конфигурацию из файла (AGENT_CONFIG или DefaultPath),
+// профиля (AGENT_PROFILE или profile в файле) и переменных окружения
 func Load() (*Config, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY не установлен в переменных окружения")
+	return load("", "", nil)
+}
+
+// load собирает конфигурацию по слоям; path и profile - значения флагов (пусто - не заданы)
+func load(path, profile string, flags []flagValue) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if path == "" {
+		path = os.Getenv("AGENT_CONFIG")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = DefaultPath()
+	}
+
+	file, err := readFile(path, explicit)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.apply(&cfg); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		profile = os.Getenv("AGENT_PROFILE")
+	}
+	if profile == "" {
+		profile = file.profile
+	}
+	if profile != "" {
+		if err := file.applyProfile(&cfg, profile); err != nil {
+			return nil, err
+		}
+		cfg.Profile = profile
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+	if err := applyFlags(&cfg, flags); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.finish(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// finish подставляет значения, зависящие от других настроек, и читает файлы
+func (c *Config) finish() error {
+	key, source, err := secrets.APIKey(c.Secrets)
+	// Локальному OpenAI-совместимому API ключ обычно не нужен
+	if err != nil && !(errors.Is(err, secrets.ErrNotFound) && c.BaseURL != "") {
+		return err
+	}
+	c.OpenAIKey, c.APIKeySource = key, source
+
+	// Ключ мог попасть в сообщения об ошибках, которые выводит log
+	log.SetOutput(redact.NewWriter(log.Writer()))
+
+	if c.Context.Model == "" {
+		c.Context.Model = c.Model
+	}
+
+	// Размер summary задается одним значением: context.max_summary_tokens,
+	// summarizer.max_tokens (если задан) его заменяет
+	if c.Summarizer.MaxTokens > 0 {
+		c.Context.MaxSummaryTokens = c.Summarizer.MaxTokens
+	}
+
+	if c.Summarizer.PromptTemplate == "" && c.Summarizer.PromptFile != "" {
+		data, err := os.ReadFile(c.Summarizer.PromptFile)
+		if err != nil {
+			return &FieldError{Source: c.Summarizer.PromptFile, Key: "summarizer.prompt_file", Message: err.Error()}
+		}
+		c.Summarizer.PromptTemplate = string(data)
+	}
+
+	c.prompts = prompts.Default()
+	if c.PromptsDir != "" {
+		c.prompts = c.prompts.Clone()
+		if err := c.prompts.LoadDir(c.PromptsDir); err != nil {
+			return err
+		}
+	}
+	if c.SystemPromptRef != "" {
+		rendered, err := c.prompts.Render(c.SystemPromptRef, nil)
+		if err != nil {
+			return &FieldError{Source: c.SystemPromptRef, Key: "system_prompt_ref", Message: err.Error()}
+		}
+		c.SystemPrompt, c.systemPrompt = rendered.Text, rendered
+	}
+
+	return nil
+}
+
+// Prompts возвращает библиотеку шаблонов промптов: встроенные и из prompts_dir
+func (c *Config) Prompts() *prompts.Library {
+	if c.prompts == nil {
+		return prompts.Default()
+	}
+	return c.prompts
+}
+
+// ClientConfig возвращает настройки клиента OpenAI API
+func (c *Config) ClientConfig() openai.ClientConfig {
+	config := openai.DefaultConfig(c.OpenAIKey)
+	if c.BaseURL != "" {
+		config.BaseURL = c.BaseURL
+	}
+	return config
+}
+
+// AgentConfig возвращает настройки агента
+func (c *Config) AgentConfig() agent.AgentConfig {
+	return agent.AgentConfig{
+		APIKey:       c.OpenAIKey,
+		BaseURL:      c.BaseURL,
+		Model:        c.Model,
+		Temperature:  client.Temperature(c.Temperature),
+		MaxTokens:    c.MaxTokens,
+		SystemPrompt: c.SystemPrompt,
+
+		SystemPromptRef: c.systemPrompt.String(),
+	}
+}
+
+// CompletionRequest возвращает запрос к клиенту с параметрами из конфигурации
+func (c *Config) CompletionRequest(prompt string) client.CompletionRequest {
+	req := client.CompletionRequest{
+		Model:       c.Model,
+		Prompt:      prompt,
+		MaxTokens:   c.MaxTokens,
+		Temperature: client.Temperature(c.Temperature),
+		Stop:        c.Stop,
+	}
+	if c.ResponseFormat != "" {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatType(c.ResponseFormat),
+		}
+	}
+	return req
+}
+
+// NewSummarizer создает суммаризатор по настройкам summarizer:
+// LLM с экстрактивным запасным вариантом или только экстрактивный в офлайн-режиме.
+// Размер summary - context.max_summary_tokens. meter - учет запросов суммаризации
+// (nil - без бюджета и журнала).
+func (c *Config) NewSummarizer(client *openai.Client, meter *telemetry.Meter) (agent.Summarizer, error) {
+	settings := c.Summarizer
+	maxTokens := c.Context.MaxSummaryTokens
+
+	extractive := agent.NewExtractiveSummarizer(maxTokens)
+	if settings.Offline {
+		return extractive, nil
+	}
+
+	llm, err := agent.NewLLMSummarizer(client, agent.SummarizerConfig{
+		Model:          settings.Model,
+		PromptTemplate: settings.PromptTemplate,
+		MaxTokens:      maxTokens,
+		Temperature:    settings.Temperature,
+		Language:       settings.Language,
+	})
+	if err != nil {
+		return nil, err
 	}
+	llm.SetMeter(meter)
 
-	return &Config{
-		OpenAIKey: apiKey,
-	}, nil
+	return agent.NewFallbackSummarizer(llm, extractive), nil
 }
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed profiles.yaml
+var builtinProfiles []byte
+
+// DefaultPath возвращает путь к файлу конфигурации по умолчанию:
+// $XDG_CONFIG_HOME/ai-advent/config.yaml (обычно ~/.config/ai-advent/config.yaml)
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ai-advent", "config.yaml")
+}
+
+// fileFormat формат файла конфигурации: настройки верхнего уровня,
+// профиль по умолчанию и именованные профили с теми же ключами
+type fileFormat struct {
+	Config   `yaml:",inline"`
+	Profile  string               `yaml:"profile"`
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+}
+
+// strictFormat используется только для проверки ключей, в том числе внутри профилей
+type strictFormat struct {
+	Config   `yaml:",inline"`
+	Profile  string            `yaml:"profile"`
+	Profiles map[string]Config `yaml:"profiles"`
+}
+
+// configFile прочитанный файл конфигурации (пустой, если файла нет)
+type configFile struct {
+	path     string
+	data     []byte
+	profile  string
+	profiles map[string]yaml.Node
+}
+
+// readFile читает файл конфигурации. Отсутствующий файл по умолчанию
+// не считается ошибкой, а явно указанный (флаг, AGENT_CONFIG) - считается.
+func readFile(path string, explicit bool) (*configFile, error) {
+	file := &configFile{path: path}
+	if path == "" {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return file, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
+	}
+
+	// Неизвестные ключи и неверные типы - с номером строки
+	var strict strictFormat
+	if err := decodeStrict(data, &strict); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var parsed fileFormat
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	file.data = data
+	file.profile = parsed.Profile
+	file.profiles = parsed.Profiles
+	return file, nil
+}
+
+// decodeStrict разбирает YAML, запрещая неизвестные ключи
+func decodeStrict(data []byte, out any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// apply накладывает настройки верхнего уровня файла на cfg
+func (f *configFile) apply(cfg *Config) error {
+	if f.data == nil {
+		return nil
+	}
+
+	// Декодирование в заполненную структуру меняет только указанные в файле поля
+	parsed := fileFormat{Config: *cfg}
+	if err := yaml.Unmarshal(f.data, &parsed); err != nil {
+		return fmt.Errorf("%s: %w", f.path, err)
+	}
+	*cfg = parsed.Config
+
+	return cfg.check(f.path, "")
+}
+
+// applyProfile накладывает профиль: сначала встроенный, затем одноименный из файла
+func (f *configFile) applyProfile(cfg *Config, name string) error {
+	builtin, err := parseBuiltinProfiles()
+	if err != nil {
+		return err
+	}
+
+	node, inBuiltin := builtin[name]
+	if inBuiltin {
+		if err := node.Decode(cfg); err != nil {
+			return fmt.Errorf("встроенный профиль %s: %w", name, err)
+		}
+		if err := cfg.check("встроенный профиль "+name, ""); err != nil {
+			return err
+		}
+	}
+
+	node, inFile := f.profiles[name]
+	if inFile {
+		if err := node.Decode(cfg); err != nil {
+			return fmt.Errorf("%s: профиль %s: %w", f.path, name, err)
+		}
+		if err := cfg.check(f.path, "profiles."+name+"."); err != nil {
+			return err
+		}
+	}
+
+	if !inBuiltin && !inFile {
+		return fmt.Errorf("неизвестный профиль %q (доступны: %s)", name, strings.Join(f.profileNames(builtin), ", "))
+	}
+	return nil
+}
+
+// profileNames возвращает отсортированные имена встроенных профилей и профилей из файла
+func (f *configFile) profileNames(builtin map[string]yaml.Node) []string {
+	seen := make(map[string]bool)
+	for name := range builtin {
+		seen[name] = true
+	}
+	for name := range f.profiles {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseBuiltinProfiles разбирает встроенные профили
+func parseBuiltinProfiles() (map[string]yaml.Node, error) {
+	var profiles map[string]yaml.Node
+	if err := yaml.Unmarshal(builtinProfiles, &profiles); err != nil {
+		return nil, fmt.Errorf("встроенные профили: %w", err)
+	}
+	return profiles, nil
+}
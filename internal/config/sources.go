@@ -0,0 +1,269 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// binding связывает ключ настройки с переменной окружения и флагом
+type binding struct {
+	key    string // Ключ в файле конфигурации
+	env    string // Переменная окружения
+	usage  string // Описание для флага
+	isBool bool   // Флаг без значения
+	set    func(c *Config, value string) error
+}
+
+// flagName возвращает имя флага: ключ, в котором точки и подчеркивания заменены дефисами
+func (b binding) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(b.key)
+}
+
+// bindings все настройки, которые можно переопределить из окружения и флагами
+var bindings = []binding{
+	{key: "base_url", env: "OPENAI_BASE_URL", usage: "адрес OpenAI-совместимого API",
+		set: stringValue(func(c *Config) *string { return &c.BaseURL })},
+	{key: "model", env: "AGENT_MODEL", usage: "модель диалога",
+		set: stringValue(func(c *Config) *string { return &c.Model })},
+	{key: "temperature", env: "AGENT_TEMPERATURE", usage: "температура (0-2)",
+		set: float32Value(func(c *Config) *float32 { return &c.Temperature })},
+	{key: "max_tokens", env: "AGENT_MAX_TOKENS", usage: "максимальный размер ответа (0 - без ограничения)",
+		set: intValue(func(c *Config) *int { return &c.MaxTokens })},
+	{key: "system_prompt", env: "AGENT_SYSTEM_PROMPT", usage: "системный промпт",
+		set: stringValue(func(c *Config) *string { return &c.SystemPrompt })},
+	{key: "system_prompt_ref", env: "AGENT_SYSTEM_PROMPT_REF", usage: "шаблон системного промпта name@version (заменяет system_prompt)",
+		set: stringValue(func(c *Config) *string { return &c.SystemPromptRef })},
+	{key: "prompts_dir", env: "PROMPTS_DIR", usage: "каталог своих шаблонов промптов (*.tmpl)",
+		set: stringValue(func(c *Config) *string { return &c.PromptsDir })},
+	{key: "stop", env: "AGENT_STOP", usage: "стоп-последовательности через запятую",
+		set: listValue(func(c *Config) *[]string { return &c.Stop })},
+	{key: "response_format", env: "AGENT_RESPONSE_FORMAT", usage: "формат ответа: text или json_object",
+		set: stringValue(func(c *Config) *string { return &c.ResponseFormat })},
+
+	{key: "secrets.api_key_cmd", env: "OPENAI_API_KEY_CMD", usage: "команда, печатающая ключ API (например, pass show openai)",
+		set: stringValue(func(c *Config) *string { return &c.Secrets.APIKeyCmd })},
+	{key: "secrets.file", env: "SECRETS_FILE", usage: "зашифрованный файл с ключом API",
+		set: stringValue(func(c *Config) *string { return &c.Secrets.File })},
+	{key: "secrets.keyring", env: "SECRETS_KEYRING", usage: "искать ключ API в связке ключей ОС", isBool: true,
+		set: boolValue(func(c *Config) *bool { return &c.Secrets.Keyring })},
+
+	{key: "context.compress_threshold_tokens", env: "CONTEXT_COMPRESS_THRESHOLD_TOKENS", usage: "сжимать историю, когда несжатая середина больше N токенов",
+		set: intValue(func(c *Config) *int { return &c.Context.CompressThresholdTokens })},
+	{key: "context.recent_tokens", env: "CONTEXT_RECENT_TOKENS", usage: "сколько токенов последних сообщений не сжимать",
+		set: intValue(func(c *Config) *int { return &c.Context.RecentTokens })},
+	{key: "context.max_summary_tokens", env: "CONTEXT_MAX_SUMMARY_TOKENS", usage: "максимальный размер одного summary",
+		set: intValue(func(c *Config) *int { return &c.Context.MaxSummaryTokens })},
+	{key: "context.summary_budget_tokens", env: "CONTEXT_SUMMARY_BUDGET_TOKENS", usage: "бюджет токенов на summary одного уровня (0 - без иерархии)",
+		set: intValue(func(c *Config) *int { return &c.Context.SummaryBudgetTokens })},
+
+	{key: "summarizer.model", env: "SUMMARY_MODEL", usage: "модель суммаризации",
+		set: stringValue(func(c *Config) *string { return &c.Summarizer.Model })},
+	{key: "summarizer.max_tokens", env: "SUMMARY_MAX_TOKENS", usage: "устарело: заменяет context.max_summary_tokens",
+		set: intValue(func(c *Config) *int { return &c.Summarizer.MaxTokens })},
+	{key: "summarizer.language", env: "SUMMARY_LANGUAGE", usage: "язык summary",
+		set: stringValue(func(c *Config) *string { return &c.Summarizer.Language })},
+	{key: "summarizer.temperature", env: "SUMMARY_TEMPERATURE", usage: "температура суммаризации (0-2, по умолчанию 0.3)",
+		set: float32PtrValue(func(c *Config) **float32 { return &c.Summarizer.Temperature })},
+	{key: "summarizer.prompt_file", env: "SUMMARY_PROMPT_FILE", usage: "файл шаблона промпта суммаризации",
+		set: stringValue(func(c *Config) *string { return &c.Summarizer.PromptFile })},
+	{key: "summarizer.offline", env: "SUMMARY_OFFLINE", usage: "экстрактивная суммаризация без LLM", isBool: true,
+		set: boolValue(func(c *Config) *bool { return &c.Summarizer.Offline })},
+
+	{key: "judge.model", env: "JUDGE_MODEL", usage: "модель LLM-судьи (пусто - модель диалога)",
+		set: stringValue(func(c *Config) *string { return &c.Judge.Model })},
+
+	{key: "budget.session.cost_usd", env: "BUDGET_SESSION_USD", usage: "лимит расходов на запуск в долларах",
+		set: floatValue(func(c *Config) *float64 { return &c.Budget.Session.CostUSD })},
+	{key: "budget.session.tokens", env: "BUDGET_SESSION_TOKENS", usage: "лимит токенов на запуск",
+		set: intValue(func(c *Config) *int { return &c.Budget.Session.Tokens })},
+	{key: "budget.day.cost_usd", env: "BUDGET_DAY_USD", usage: "лимит расходов на день в долларах",
+		set: floatValue(func(c *Config) *float64 { return &c.Budget.Day.CostUSD })},
+	{key: "budget.day.tokens", env: "BUDGET_DAY_TOKENS", usage: "лимит токенов на день",
+		set: intValue(func(c *Config) *int { return &c.Budget.Day.Tokens })},
+	{key: "budget.total.cost_usd", env: "BUDGET_TOTAL_USD", usage: "лимит расходов за все время в долларах",
+		set: floatValue(func(c *Config) *float64 { return &c.Budget.Total.CostUSD })},
+	{key: "budget.total.tokens", env: "BUDGET_TOTAL_TOKENS", usage: "лимит токенов за все время",
+		set: intValue(func(c *Config) *int { return &c.Budget.Total.Tokens })},
+	{key: "budget.warn_ratio", env: "BUDGET_WARN_RATIO", usage: "доля лимита для предупреждения (0-1)",
+		set: floatValue(func(c *Config) *float64 { return &c.Budget.WarnRatio })},
+
+	{key: "metrics_addr", env: "METRICS_ADDR", usage: "адрес /metrics для Prometheus",
+		set: stringValue(func(c *Config) *string { return &c.MetricsAddr })},
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "экспорт трассировки: otlp, stdout или file",
+		set: stringValue(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{key: "tracing.file", env: "TRACING_FILE", usage: "файл спанов для экспорта file",
+		set: stringValue(func(c *Config) *string { return &c.Tracing.File })},
+}
+
+// applyEnv накладывает заданные переменные окружения
+func applyEnv(cfg *Config) error {
+	set := make(map[string]string)
+	for _, b := range bindings {
+		value := os.Getenv(b.env)
+		if value == "" {
+			continue
+		}
+		if err := b.set(cfg, value); err != nil {
+			return &FieldError{Source: b.env, Key: b.key, Message: err.Error()}
+		}
+		set[b.key] = b.env
+	}
+
+	return cfg.checkSources(set, "переменные окружения")
+}
+
+// flagValue значение флага, заданное в командной строке
+type flagValue struct {
+	binding binding
+	value   string
+}
+
+// applyFlags накладывает флаги в порядке их указания
+func applyFlags(cfg *Config, flags []flagValue) error {
+	set := make(map[string]string)
+	for _, f := range flags {
+		name := "-" + f.binding.flagName()
+		if err := f.binding.set(cfg, f.value); err != nil {
+			return &FieldError{Source: name, Key: f.binding.key, Message: err.Error()}
+		}
+		set[f.binding.key] = name
+	}
+
+	return cfg.checkSources(set, "флаги")
+}
+
+// checkSources проверяет конфигурацию и указывает в ошибке источник значения
+func (c *Config) checkSources(set map[string]string, fallback string) error {
+	if len(set) == 0 {
+		return nil
+	}
+
+	err := c.validate()
+	if err == nil {
+		return nil
+	}
+	if source, ok := set[err.Key]; ok {
+		err.Source = source
+	} else {
+		err.Source = fallback
+	}
+	return err
+}
+
+// Flags флаги командной строки для всех настроек
+type Flags struct {
+	configPath string
+	profile    string
+	values     []flagValue
+}
+
+// BindFlags регистрирует в fs флаги -config, -profile и по флагу на каждую настройку
+// (-model, -temperature, -context-recent-tokens, ...). Значения применяются в Flags.Load.
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	f.Bind(fs)
+	return f
+}
+
+// Bind регистрирует те же флаги еще в одном наборе, например в наборе подкоманды,
+// чтобы флаги конфигурации можно было указывать и до, и после нее
+func (f *Flags) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.configPath, "config", f.configPath, "файл конфигурации (по умолчанию AGENT_CONFIG или "+DefaultPath()+")")
+	fs.StringVar(&f.profile, "profile", f.profile, "профиль конфигурации: cheap, quality, local или свой из файла")
+
+	for _, b := range bindings {
+		b := b
+		record := func(value string) error {
+			f.values = append(f.values, flagValue{binding: b, value: value})
+			return nil
+		}
+		if b.isBool {
+			fs.BoolFunc(b.flagName(), b.usage, record)
+		} else {
+			fs.Func(b.flagName(), b.usage, record)
+		}
+	}
+}
+
+// Load загружает конфигурацию как config.Load и накладывает поверх нее флаги
+func (f *Flags) Load() (*Config, error) {
+	return load(f.configPath, f.profile, f.values)
+}
+
+func stringValue(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func listValue(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+func intValue(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("ожидалось целое число, получено %q", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func floatValue(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("ожидалось число, получено %q", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func float32Value(field func(*Config) *float32) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("ожидалось число, получено %q", value)
+		}
+		*field(c) = float32(f)
+		return nil
+	}
+}
+
+func float32PtrValue(field func(*Config) **float32) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var f float32
+		if err := float32Value(func(*Config) *float32 { return &f })(c, value); err != nil {
+			return err
+		}
+		*field(c) = &f
+		return nil
+	}
+}
+
+func boolValue(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("ожидалось true/false или 1/0, получено %q", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
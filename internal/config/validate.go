@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+)
+
+// FieldError неверное значение настройки с указанием, откуда оно взято
+type FieldError struct {
+	Source  string // Файл, профиль, переменная окружения или флаг
+	Key     string // Ключ настройки, например context.recent_tokens
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Source, e.Key, e.Message)
+}
+
+// check проверяет конфигурацию после наложения слоя из source;
+// prefix добавляется к ключу (для профилей из файла - profiles.<имя>.)
+func (c *Config) check(source, prefix string) error {
+	if err := c.validate(); err != nil {
+		err.Source = source
+		err.Key = prefix + err.Key
+		return err
+	}
+	return nil
+}
+
+// validate возвращает первую найденную ошибку (без источника)
+func (c *Config) validate() *FieldError {
+	var summaryTemperature float32 // Не задана - проверять нечего
+	if c.Summarizer.Temperature != nil {
+		summaryTemperature = *c.Summarizer.Temperature
+	}
+
+	checks := []struct {
+		key     string
+		invalid bool
+		message string
+	}{
+		{"model", c.Model == "", "модель не указана"},
+		{"temperature", c.Temperature < 0 || c.Temperature > 2,
+			fmt.Sprintf("должна быть от 0 до 2, получено %v", c.Temperature)},
+		{"max_tokens", c.MaxTokens < 0, negative(c.MaxTokens)},
+		{"response_format", c.ResponseFormat != "" && c.ResponseFormat != "text" && c.ResponseFormat != "json_object",
+			fmt.Sprintf("допустимо text или json_object, получено %q", c.ResponseFormat)},
+		{"system_prompt_ref", c.SystemPromptRef != "" && !validRef(c.SystemPromptRef),
+			fmt.Sprintf("ожидалась ссылка вида name@version, получено %q", c.SystemPromptRef)},
+		{"base_url", c.BaseURL != "" && !validURL(c.BaseURL),
+			fmt.Sprintf("ожидался адрес вида http://host:port/v1, получено %q", c.BaseURL)},
+
+		{"context.compress_threshold_tokens", c.Context.CompressThresholdTokens <= 0,
+			fmt.Sprintf("должен быть положительным, получено %d", c.Context.CompressThresholdTokens)},
+		{"context.recent_tokens", c.Context.RecentTokens < 0, negative(c.Context.RecentTokens)},
+		{"context.max_summary_tokens", c.Context.MaxSummaryTokens <= 0,
+			fmt.Sprintf("должен быть положительным, получено %d", c.Context.MaxSummaryTokens)},
+		{"context.summary_budget_tokens", c.Context.SummaryBudgetTokens < 0, negative(c.Context.SummaryBudgetTokens)},
+
+		{"summarizer.max_tokens", c.Summarizer.MaxTokens < 0, negative(c.Summarizer.MaxTokens)},
+		{"summarizer.temperature", summaryTemperature < 0 || summaryTemperature > 2,
+			fmt.Sprintf("должна быть от 0 до 2, получено %v", summaryTemperature)},
+
+		{"budget.session.cost_usd", c.Budget.Session.CostUSD < 0, negative(c.Budget.Session.CostUSD)},
+		{"budget.session.tokens", c.Budget.Session.Tokens < 0, negative(c.Budget.Session.Tokens)},
+		{"budget.day.cost_usd", c.Budget.Day.CostUSD < 0, negative(c.Budget.Day.CostUSD)},
+		{"budget.day.tokens", c.Budget.Day.Tokens < 0, negative(c.Budget.Day.Tokens)},
+		{"budget.total.cost_usd", c.Budget.Total.CostUSD < 0, negative(c.Budget.Total.CostUSD)},
+		{"budget.total.tokens", c.Budget.Total.Tokens < 0, negative(c.Budget.Total.Tokens)},
+		{"budget.warn_ratio", c.Budget.WarnRatio < 0 || c.Budget.WarnRatio > 1,
+			fmt.Sprintf("должна быть от 0 до 1, получено %v", c.Budget.WarnRatio)},
+
+		{"tracing.exporter", !validExporter(c.Tracing.Exporter),
+			fmt.Sprintf("допустимо otlp, stdout или file, получено %q", c.Tracing.Exporter)},
+		{"tracing.file", c.Tracing.Exporter == telemetry.ExporterFile && c.Tracing.File == "",
+			"для экспорта в файл укажите путь"},
+	}
+
+	for _, check := range checks {
+		if check.invalid {
+			return &FieldError{Key: check.key, Message: check.message}
+		}
+	}
+	return nil
+}
+
+// negative сообщение для отрицательного значения
+func negative[T int | float64](value T) string {
+	return fmt.Sprintf("не может быть отрицательным, получено %v", value)
+}
+
+// validURL проверяет, что адрес содержит схему http(s) и хост
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validRef проверяет ссылку на шаблон промпта
+func validRef(ref string) bool {
+	_, _, err := prompts.ParseRef(ref)
+	return err == nil
+}
+
+// validExporter проверяет имя экспортера трассировки
+func validExporter(exporter string) bool {
+	switch exporter {
+	case telemetry.ExporterNone, telemetry.ExporterOTLP, telemetry.ExporterStdout, telemetry.ExporterFile:
+		return true
+	}
+	return false
+}
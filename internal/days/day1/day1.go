@@ -0,0 +1,81 @@
+// Package day1 - первый запрос к OpenAI API (команда advent ask)
+package day1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// defaultPrompt промпт задания, если вопрос не передан аргументами
+const defaultPrompt = "Привет! Расскажи, что ты умеешь делать?"
+
+// Result результат запроса для -output json
+type Result struct {
+	Prompt           string `json:"prompt"`
+	Content          string `json:"content"`
+	Model            string `json:"model"`
+	FinishReason     string `json:"finish_reason"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+}
+
+// Run отправляет один запрос: аргументы команды или промпт задания
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+
+	// Создание клиента
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+
+	// Учитываем запросы в журнале использования и лимитах расходов (если заданы)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiClient.SetMeter(meter)
+
+	// Заголовок
+	utils.PrintHeader("Day 1: Первый запрос к OpenAI API")
+
+	// Запрос
+	prompt := defaultPrompt
+	if len(env.Args) > 0 {
+		prompt = strings.Join(env.Args, " ")
+	}
+	utils.PrintSection("📝", "ЗАПРОС")
+	fmt.Printf("Промпт: %s\n\n", prompt)
+
+	// Выполнение запроса
+	resp, err := aiClient.CreateCompletionContext(ctx, cfg.CompletionRequest(prompt))
+	if err != nil {
+		return fmt.Errorf("выполнение запроса: %w", err)
+	}
+
+	// Вывод ответа
+	utils.PrintSection("💬", "ОТВЕТ")
+	fmt.Printf("%s\n\n", resp.Content)
+
+	// Статистика
+	utils.PrintSection("📊", "СТАТИСТИКА")
+	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
+	utils.PrintKeyValue("Модель", resp.Model)
+	utils.PrintKeyValue("Finish reason", resp.FinishReason)
+
+	utils.PrintDivider()
+	utils.PrintSuccess("Задание Day 1 выполнено!")
+
+	return env.Emit(Result{
+		Prompt:           prompt,
+		Content:          resp.Content,
+		Model:            resp.Model,
+		FinishReason:     resp.FinishReason,
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+		TotalTokens:      resp.TotalTokens,
+	})
+}
@@ -0,0 +1,60 @@
+package day2
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// strictSchema схема ответа на запрос 3
+var strictSchema = map[string]any{
+	"type":                 "object",
+	"required":             []any{"definition", "types", "applications"},
+	"additionalProperties": false,
+	"properties": map[string]any{
+		"definition":   map[string]any{"type": "string", "minLength": 1},
+		"types":        map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 3, "maxItems": 3},
+		"applications": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 2, "maxItems": 2},
+	},
+}
+
+// constrainedChecks проверки ответа на запрос 2: лимит слов и остановка на стоп-последовательности
+func constrainedChecks() []eval.Evaluator {
+	words, _ := eval.WordCount(0, 150)
+	return []eval.Evaluator{words, eval.StopSequences()}
+}
+
+// strictChecks проверки ответа на запрос 3: валидный JSON нужной структуры
+func strictChecks() []eval.Evaluator {
+	schema, err := eval.JSONSchema(strictSchema)
+	if err != nil {
+		log.Printf("Схема ответа: %v", err)
+		return []eval.Evaluator{eval.ValidJSON()}
+	}
+	return []eval.Evaluator{eval.ValidJSON(), schema}
+}
+
+// printChecks выводит результаты проверок ответа
+func printChecks(ctx context.Context, in eval.Input, checks []eval.Evaluator) {
+	fmt.Println("Проверки:")
+	for _, c := range checks {
+		score, err := c.Evaluate(ctx, in)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("  %s: %v", c.Name(), err))
+			continue
+		}
+
+		line := "  " + score.Evaluator
+		if score.Detail != "" {
+			line += ": " + score.Detail
+		}
+		if score.Pass {
+			utils.PrintSuccess(line)
+		} else {
+			utils.PrintError(line)
+		}
+	}
+}
@@ -1,51 +1,76 @@
-package main
+// Package day2 - контроль формата ответов (команда advent format)
+package day2
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
 	openai "github.com/sashabaranov/go-openai"
 )
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
+// Run сравнивает ответы на один вопрос с разным уровнем контроля формата
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+
+	// Создание клиента
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+
+	// Учитываем запросы в журнале использования и лимитах расходов (если заданы)
+	meter, err := env.Meter()
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		return err
 	}
+	aiClient.SetMeter(meter)
 
-	// Создание клиента
-	aiClient := client.NewOpenAIClient(cfg.OpenAIKey)
+	// Промпты из библиотеки шаблонов: один вопрос с разными требованиями к формату
+	lib := cfg.Prompts()
+	basePrompt, err := lib.Render("day2/free@1", nil)
+	if err != nil {
+		return err
+	}
+	controlledPrompt, err := lib.Render("day2/constrained@1", map[string]any{"stop": stopPhrase})
+	if err != nil {
+		return err
+	}
+	strictPrompt, err := lib.Render("day2/strict-json@1", nil)
+	if err != nil {
+		return err
+	}
 
 	// Заголовок
 	utils.PrintHeader("Day 2: Сравнение запросов с разным уровнем контроля")
 
-	// Базовый запрос
-	basePrompt := "Расскажи про искусственный интеллект"
-
 	// 1. Запрос без ограничений
-	runRequestWithoutConstraints(aiClient, basePrompt)
+	runRequestWithoutConstraints(ctx, aiClient, basePrompt)
 
 	// 2. Запрос с ограничениями
-	runRequestWithConstraints(aiClient)
+	runRequestWithConstraints(ctx, aiClient, controlledPrompt)
 
 	// 3. Запрос с жесткими ограничениями (JSON)
-	runRequestWithStrictConstraints(aiClient)
+	runRequestWithStrictConstraints(ctx, aiClient, strictPrompt)
 
 	// Сравнение результатов
 	printComparison()
+	return nil
 }
 
-func runRequestWithoutConstraints(aiClient *client.OpenAIClient, prompt string) {
+// stopPhrase фраза, которой заканчивается ответ на запрос с ограничениями
+const stopPhrase = "[КОНЕЦ ОТВЕТА]"
+
+func runRequestWithoutConstraints(ctx context.Context, aiClient *client.OpenAIClient, prompt prompts.Rendered) {
 	utils.PrintSection("📝", "ЗАПРОС 1: БЕЗ ОГРАНИЧЕНИЙ")
-	fmt.Printf("Промпт: %s\n\n", prompt)
+	fmt.Printf("Промпт: %s\n\n", prompt.Text)
 
-	resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      prompt,
-		Temperature: 0.7,
+	resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      prompt.Text,
+		Templates:   []string{prompt.String()},
+		Temperature: client.Temperature(0.7),
 	})
 
 	if err != nil {
@@ -59,28 +84,18 @@ func runRequestWithoutConstraints(aiClient *client.OpenAIClient, prompt string)
 	utils.PrintDivider()
 }
 
-func runRequestWithConstraints(aiClient *client.OpenAIClient) {
+func runRequestWithConstraints(ctx context.Context, aiClient *client.OpenAIClient, controlledPrompt prompts.Rendered) {
 	utils.PrintSection("📝", "ЗАПРОС 2: С ОГРАНИЧЕНИЯМИ")
 
-	controlledPrompt := `Расскажи про искусственный интеллект.
-
-ФОРМАТ ОТВЕТА:
-1. Определение (1 предложение)
-2. Основные направления (список из 3 пунктов)
-3. Практическое применение (2-3 примера)
-
-ОГРАНИЧЕНИЯ:
-- Максимум 150 слов
-- Структурированный формат
-- Завершить ответ фразой "[КОНЕЦ ОТВЕТА]"`
+	fmt.Printf("Промпт:\n%s\n\n", controlledPrompt.Text)
 
-	fmt.Printf("Промпт:\n%s\n\n", controlledPrompt)
-
-	resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      controlledPrompt,
+	stop := []string{stopPhrase}
+	resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      controlledPrompt.Text,
+		Templates:   []string{controlledPrompt.String()},
 		MaxTokens:   300,
-		Temperature: 0.7,
-		Stop:        []string{"[КОНЕЦ ОТВЕТА]"},
+		Temperature: client.Temperature(0.7),
+		Stop:        stop,
 	})
 
 	if err != nil {
@@ -92,32 +107,21 @@ func runRequestWithConstraints(aiClient *client.OpenAIClient) {
 	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
 	utils.PrintKeyValue("Модель", resp.Model)
 	utils.PrintKeyValue("Finish reason", resp.FinishReason)
+	fmt.Println()
+	printChecks(ctx, eval.Input{Response: resp.Content, Stop: stop}, constrainedChecks())
 	utils.PrintDivider()
 }
 
-func runRequestWithStrictConstraints(aiClient *client.OpenAIClient) {
+func runRequestWithStrictConstraints(ctx context.Context, aiClient *client.OpenAIClient, strictPrompt prompts.Rendered) {
 	utils.PrintSection("📝", "ЗАПРОС 3: С ЖЕСТКИМИ ОГРАНИЧЕНИЯМИ (JSON)")
 
-	strictPrompt := `Расскажи про искусственный интеллект.
+	fmt.Printf("Промпт:\n%s\n\n", strictPrompt.Text)
 
-СТРОГИЙ ФОРМАТ ОТВЕТА (JSON):
-{
-  "definition": "краткое определение (1 предложение)",
-  "types": ["тип1", "тип2", "тип3"],
-  "applications": ["применение1", "применение2"]
-}
-
-ТРЕБОВАНИЯ:
-- Только валидный JSON
-- Без дополнительных пояснений
-- Максимум 50 токенов`
-
-	fmt.Printf("Промпт:\n%s\n\n", strictPrompt)
-
-	resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-		Prompt:      strictPrompt,
+	resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      strictPrompt.Text,
+		Templates:   []string{strictPrompt.String()},
 		MaxTokens:   150,
-		Temperature: 0.3,
+		Temperature: client.Temperature(0.3),
 		ResponseFormat: &openai.ChatCompletionResponseFormat{
 			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
 		},
@@ -132,6 +136,8 @@ func runRequestWithStrictConstraints(aiClient *client.OpenAIClient) {
 	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
 	utils.PrintKeyValue("Модель", resp.Model)
 	utils.PrintKeyValue("Finish reason", resp.FinishReason)
+	fmt.Println()
+	printChecks(ctx, eval.Input{Response: resp.Content}, strictChecks())
 	utils.PrintDivider()
 }
 
@@ -0,0 +1,619 @@
+// Package day3 - разные способы рассуждения (команда advent reasoning)
+package day3
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/debate"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/river"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/thought"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// Результат выполнения стратегии
+type StrategyResult struct {
+	StrategyName  string          `json:"strategy"`
+	Prompt        string          `json:"prompt"`
+	Templates     []string        `json:"templates,omitempty"` // Шаблоны промптов: name@version#hash
+	Response      string          `json:"response"`
+	TokensUsed    int             `json:"tokens_used"`
+	ExecutionTime time.Duration   `json:"execution_time_ns"`
+	AnswerCorrect bool            `json:"answer_correct"`
+	AnswerQuality int             `json:"answer_quality"`     // Оценка качества от 1 до 10 (0 - не проверялось)
+	Verdict       *river.Verdict  `json:"verdict,omitempty"`  // Проверка ответа стратегии
+	Verdicts      []river.Verdict `json:"verdicts,omitempty"` // Проверка итоговой позиции каждого эксперта
+	Debate        *debate.Result  `json:"debate,omitempty"`   // Стенограмма и расходы обсуждения экспертов
+	Tree          *thought.Result `json:"tree,omitempty"`     // Дерево мыслей: лучший путь и все варианты
+
+	answer string       // Ответ для проверки (пусто - стратегия не дала ответа)
+	moves  []river.Item // Ходы решения, если они известны без разбора ответа (дерево мыслей)
+}
+
+// Способы извлечь ходы из ответа для проверки
+const (
+	VerifyLLM    = "llm"    // Дополнительный запрос со структурированным выводом, при ошибке - разбор текста
+	VerifyParser = "parser" // Только разбор текста, без запросов
+	VerifyOff    = "off"    // Без проверки
+)
+
+// VerifyModes допустимые значения -verify
+var VerifyModes = []string{VerifyLLM, VerifyParser, VerifyOff}
+
+// verifyMode значение флага -verify
+var verifyMode = VerifyLLM
+
+// debateFlags флаги обсуждения экспертов
+var debateFlags = struct {
+	rounds     int
+	transcript string
+}{rounds: 1}
+
+// BindFlags регистрирует флаги команды
+func BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&verifyMode, "verify", verifyMode,
+		"проверка ответов симуляцией переправы: llm (извлечь ходы запросом), parser (разбор текста), off")
+	fs.IntVar(&debateFlags.rounds, "rounds", debateFlags.rounds, "раундов обсуждения экспертов после первых ответов")
+	fs.StringVar(&debateFlags.transcript, "transcript", "", "файл стенограммы обсуждения экспертов (.md или .json)")
+	fs.IntVar(&treeFlags.branches, "tot-branches", treeFlags.branches, "дерево мыслей: вариантов следующего хода на шаг")
+	fs.IntVar(&treeFlags.Beam, "tot-beam", treeFlags.Beam, "дерево мыслей: сколько лучших вариантов продолжать")
+	fs.IntVar(&treeFlags.Depth, "tot-depth", treeFlags.Depth, "дерево мыслей: максимум ходов")
+	fs.StringVar(&treeFlags.eval, "tot-eval", treeFlags.eval, "дерево мыслей: оценка хода checker (симуляция переправы) или llm (промпт оценки)")
+}
+
+// Run решает задачу о переправе пятью стратегиями и сравнивает их
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+
+	// Создание клиента
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+
+	// Учитываем запросы в журнале использования и лимитах расходов (если заданы)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiClient.SetMeter(meter)
+
+	switch verifyMode {
+	case VerifyLLM, VerifyParser, VerifyOff:
+	default:
+		return cli.Usagef("неизвестный способ проверки %q (допустимо: %s)", verifyMode, strings.Join(VerifyModes, ", "))
+	}
+	if debateFlags.rounds < 0 {
+		return cli.Usagef("-rounds не может быть отрицательным")
+	}
+	if err := validateTreeFlags(); err != nil {
+		return cli.Usagef("%v", err)
+	}
+
+	// Промпты стратегий из библиотеки шаблонов
+	rendered, err := renderPrompts(cfg.Prompts())
+	if err != nil {
+		return err
+	}
+
+	// Заголовок
+	utils.PrintHeader("Day 3: Разные способы рассуждения")
+
+	// Описание задачи
+	printProblemDescription()
+
+	// Хранилище результатов. Ошибка одной стратегии не мешает остальным,
+	// а превышение бюджета и отмена останавливают задание.
+	results := make([]StrategyResult, 0, 5)
+	add := func(r StrategyResult, err error) error {
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return err
+			}
+			log.Printf("Ошибка: %v\n", err)
+		}
+		results = append(results, r)
+		return nil
+	}
+
+	// 1. Прямой ответ
+	if err := add(runStrategy1DirectAnswer(ctx, aiClient, rendered["river/direct@1"])); err != nil {
+		return err
+	}
+
+	// 2. Пошаговое решение
+	if err := add(runStrategy2StepByStep(ctx, aiClient, rendered["river/step-by-step@1"])); err != nil {
+		return err
+	}
+
+	// 3. Мета-промпт (сначала генерируем промпт)
+	if err := add(runStrategy3MetaPrompt(ctx, aiClient, rendered["river/meta@1"])); err != nil {
+		return err
+	}
+
+	// 4. Группа экспертов: обсуждение агентов и итог модератора
+	experts, err := runStrategy4ExpertPanel(ctx, expertAgents(cfg, meter, rendered), rendered["river/direct@1"], cfg.Prompts())
+	if debateFlags.transcript != "" && experts.Debate != nil {
+		if err := experts.Debate.WriteTranscript(debateFlags.transcript); err != nil {
+			return err
+		}
+		utils.PrintSuccess("Стенограмма обсуждения сохранена: " + debateFlags.transcript)
+	}
+	if err := add(experts, err); err != nil {
+		return err
+	}
+
+	// 5. Дерево мыслей
+	if err := add(runStrategy5TreeOfThought(ctx, aiClient, rendered["river/direct@1"], cfg.Prompts())); err != nil {
+		return err
+	}
+
+	// Проверка ответов симуляцией переправы
+	if err := verifyResults(ctx, aiClient, cfg.Prompts(), results); err != nil {
+		return err
+	}
+
+	// Сравнение результатов
+	compareResults(results)
+
+	utils.PrintDivider()
+	utils.PrintSuccess("Задание Day 3 выполнено!")
+
+	return env.Emit(results)
+}
+
+// Шаблоны промптов стратегий во встроенной библиотеке (можно переопределить в prompts_dir)
+var promptNames = []string{
+	"river/direct@1",
+	"river/step-by-step@1",
+	"river/meta@1",
+	"river/expert-logic@1",
+	"river/expert-games@1",
+	"river/expert-verifier@1",
+	debate.ModeratorPrompt,
+}
+
+// renderPrompts строит промпты стратегий по закрепленным версиям шаблонов
+func renderPrompts(lib *prompts.Library) (map[string]prompts.Rendered, error) {
+	rendered := make(map[string]prompts.Rendered, len(promptNames))
+	for _, name := range promptNames {
+		r, err := lib.Render(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		rendered[name] = r
+	}
+	return rendered, nil
+}
+
+func printProblemDescription() {
+	utils.PrintSection("🧩", "ЗАДАЧА")
+
+	fmt.Println("Классическая задача о переправе:")
+	fmt.Println()
+	fmt.Println("Фермеру нужно перевезти через реку волка, козу и капусту.")
+	fmt.Println("В лодке помещается только фермер и один из них.")
+	fmt.Println()
+	fmt.Println("Ограничения:")
+	fmt.Println("  - Волк не может оставаться наедине с козой (съест)")
+	fmt.Println("  - Коза не может оставаться наедине с капустой (съест)")
+	fmt.Println()
+	fmt.Println("Вопрос: Как перевезти всех через реку?")
+	fmt.Println()
+
+	utils.PrintDivider()
+}
+
+// Стратегия 1: Прямой ответ без дополнительных инструкций
+func runStrategy1DirectAnswer(ctx context.Context, aiClient *client.OpenAIClient, tmpl prompts.Rendered) (StrategyResult, error) {
+	utils.PrintSection("1️⃣", "СТРАТЕГИЯ 1: Прямой ответ")
+
+	prompt := tmpl.Text
+
+	fmt.Printf("Промпт:\n%s\n\n", prompt)
+
+	start := time.Now()
+	resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      prompt,
+		Templates:   []string{tmpl.String()},
+		Temperature: client.Temperature(0.7),
+		MaxTokens:   500,
+	})
+	elapsed := time.Since(start)
+
+	if err != nil {
+		return StrategyResult{}, err
+	}
+
+	fmt.Printf("Ответ:\n%s\n\n", resp.Content)
+	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
+	utils.PrintKeyValue("Время выполнения", elapsed.String())
+	utils.PrintDivider()
+
+	return StrategyResult{
+		StrategyName:  "Прямой ответ",
+		Prompt:        prompt,
+		Templates:     []string{tmpl.String()},
+		Response:      resp.Content,
+		TokensUsed:    resp.TotalTokens,
+		ExecutionTime: elapsed,
+		answer:        resp.Content,
+	}, nil
+}
+
+// Стратегия 2: Пошаговое решение
+func runStrategy2StepByStep(ctx context.Context, aiClient *client.OpenAIClient, tmpl prompts.Rendered) (StrategyResult, error) {
+	utils.PrintSection("2️⃣", "СТРАТЕГИЯ 2: Пошаговое решение")
+
+	prompt := tmpl.Text
+
+	fmt.Printf("Промпт:\n%s\n\n", prompt)
+
+	start := time.Now()
+	resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      prompt,
+		Templates:   []string{tmpl.String()},
+		Temperature: client.Temperature(0.7),
+		MaxTokens:   800,
+	})
+	elapsed := time.Since(start)
+
+	if err != nil {
+		return StrategyResult{}, err
+	}
+
+	fmt.Printf("Ответ:\n%s\n\n", resp.Content)
+	utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
+	utils.PrintKeyValue("Время выполнения", elapsed.String())
+	utils.PrintDivider()
+
+	return StrategyResult{
+		StrategyName:  "Пошаговое решение",
+		Prompt:        prompt,
+		Templates:     []string{tmpl.String()},
+		Response:      resp.Content,
+		TokensUsed:    resp.TotalTokens,
+		ExecutionTime: elapsed,
+		answer:        resp.Content,
+	}, nil
+}
+
+// Стратегия 3: Мета-промпт (сначала генерируем промпт)
+func runStrategy3MetaPrompt(ctx context.Context, aiClient *client.OpenAIClient, tmpl prompts.Rendered) (StrategyResult, error) {
+	utils.PrintSection("3️⃣", "СТРАТЕГИЯ 3: Мета-промпт")
+
+	// Шаг 1: Генерация промпта
+	metaPrompt := tmpl.Text
+
+	fmt.Println("Шаг 1: Генерация оптимального промпта")
+	fmt.Printf("Мета-промпт:\n%s\n\n", metaPrompt)
+
+	start := time.Now()
+
+	// Генерируем промпт
+	respPrompt, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      metaPrompt,
+		Templates:   []string{tmpl.String()},
+		Temperature: client.Temperature(0.7),
+		MaxTokens:   400,
+	})
+
+	if err != nil {
+		return StrategyResult{}, fmt.Errorf("генерация промпта: %w", err)
+	}
+
+	generatedPrompt := respPrompt.Content
+	fmt.Printf("Сгенерированный промпт:\n%s\n\n", generatedPrompt)
+	utils.PrintTokenStats(respPrompt.TotalTokens, respPrompt.PromptTokens, respPrompt.CompletionTokens)
+
+	fmt.Println("\nШаг 2: Использование сгенерированного промпта")
+
+	// Используем сгенерированный промпт
+	respFinal, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      generatedPrompt,
+		Temperature: client.Temperature(0.7),
+		MaxTokens:   600,
+	})
+
+	elapsed := time.Since(start)
+
+	if err != nil {
+		return StrategyResult{}, fmt.Errorf("решение по сгенерированному промпту: %w", err)
+	}
+
+	fmt.Printf("Итоговый ответ:\n%s\n\n", respFinal.Content)
+	utils.PrintTokenStats(respFinal.TotalTokens, respFinal.PromptTokens, respFinal.CompletionTokens)
+	utils.PrintKeyValue("Время выполнения", elapsed.String())
+	utils.PrintKeyValue("Токенов всего", fmt.Sprintf("%d", respPrompt.TotalTokens+respFinal.TotalTokens))
+	utils.PrintDivider()
+
+	return StrategyResult{
+		StrategyName:  "Мета-промпт",
+		Prompt:        generatedPrompt,
+		Templates:     []string{tmpl.String()},
+		Response:      respFinal.Content,
+		TokensUsed:    respPrompt.TotalTokens + respFinal.TotalTokens,
+		ExecutionTime: elapsed,
+		answer:        respFinal.Content,
+	}, nil
+}
+
+// expert участник группы экспертов
+type expert struct {
+	Role   string
+	Emoji  string
+	Prompt string // Шаблон первого сообщения
+}
+
+// Эксперты группы: у каждого свой агент с отдельной историей
+var experts = []expert{
+	{Role: "Логик-аналитик", Emoji: "🧠", Prompt: "river/expert-logic@1"},
+	{Role: "Игровой теоретик", Emoji: "🎮", Prompt: "river/expert-games@1"},
+	{Role: "Критик-верификатор", Emoji: "🔍", Prompt: "river/expert-verifier@1"},
+}
+
+// moderatorName имя модератора в стенограмме
+const moderatorName = "Модератор"
+
+// panel участники обсуждения и модератор
+type panel struct {
+	participants []debate.Participant
+	moderator    debate.Participant
+	templates    []string
+}
+
+// expertAgents создает агентов экспертов и модератора с общим учетом запросов
+func expertAgents(cfg *config.Config, meter *telemetry.Meter, rendered map[string]prompts.Rendered) panel {
+	newAgent := func(systemPrompt prompts.Rendered, temperature float32, maxTokens int) *agent.Agent {
+		agentConfig := cfg.AgentConfig()
+		agentConfig.SystemPrompt, agentConfig.SystemPromptRef = systemPrompt.Text, ""
+		if systemPrompt.Text != "" {
+			agentConfig.SystemPromptRef = systemPrompt.String()
+		}
+		agentConfig.Temperature, agentConfig.MaxTokens = client.Temperature(temperature), maxTokens
+		a := agent.NewAgent(agentConfig)
+		a.SetMeter(meter)
+		return a
+	}
+
+	var p panel
+	for _, e := range experts {
+		// Роль эксперта задана в первом сообщении, поэтому системного промпта у эксперта нет
+		p.participants = append(p.participants, debate.Participant{
+			Name:    e.Emoji + " " + e.Role,
+			Agent:   newAgent(prompts.Rendered{}, 0.7, 500),
+			Opening: rendered[e.Prompt].Text,
+		})
+		p.templates = append(p.templates, rendered[e.Prompt].String())
+	}
+	moderator := rendered[debate.ModeratorPrompt]
+	p.moderator = debate.Participant{Name: moderatorName, Agent: newAgent(moderator, 0.3, 800)}
+	p.templates = append(p.templates, moderator.String())
+	return p
+}
+
+// Стратегия 4: Группа экспертов - эксперты отвечают, обсуждают ответы друг друга,
+// модератор сводит их позиции в один ответ на вопрос question
+func runStrategy4ExpertPanel(ctx context.Context, p panel, question prompts.Rendered, lib *prompts.Library) (StrategyResult, error) {
+	utils.PrintSection("4️⃣", "СТРАТЕГИЯ 4: Группа экспертов")
+	utils.PrintKeyValue("Раундов обсуждения", fmt.Sprintf("%d", debateFlags.rounds))
+
+	d := debate.New(p.participants, p.moderator, debateFlags.rounds, lib)
+	d.SetProgress(printTurn)
+
+	start := time.Now()
+	result, err := d.Run(ctx, question.Text)
+	elapsed := time.Since(start)
+	if err != nil {
+		// Частичный результат показываем, ошибку решает Run
+		err = fmt.Errorf("обсуждение: %w", err)
+		if result == nil {
+			return StrategyResult{}, err
+		}
+	}
+
+	printDebateSummary(result)
+	utils.PrintKeyValue("Время выполнения", elapsed.String())
+	utils.PrintKeyValue("Токенов всего", fmt.Sprintf("%d", result.TotalTokens()))
+	utils.PrintDivider()
+
+	return StrategyResult{
+		StrategyName:  "Группа экспертов",
+		Prompt:        "См. промпты для каждого эксперта выше",
+		Templates:     append(append(p.templates, question.String()), result.Templates...),
+		Response:      result.Final,
+		TokensUsed:    result.TotalTokens(),
+		ExecutionTime: elapsed,
+		Debate:        result,
+		answer:        result.Final,
+	}, err
+}
+
+// printTurn выводит реплику обсуждения сразу после ответа
+func printTurn(t debate.Turn) {
+	switch {
+	case t.Round == 0:
+		fmt.Printf("\n%s\n", t.Participant)
+		fmt.Printf("Промпт:\n%s\n\n", t.Prompt)
+	case t.Round > debateFlags.rounds:
+		fmt.Printf("\n%s: итоговый ответ\n", t.Participant)
+	default:
+		fmt.Printf("\n%s: раунд %d\n", t.Participant, t.Round)
+	}
+	fmt.Printf("Ответ:\n%s\n\n", t.Content)
+	utils.PrintTokenStats(t.PromptTokens+t.CompletionTokens, t.PromptTokens, t.CompletionTokens)
+}
+
+// printDebateSummary выводит разногласия и расходы по участникам
+func printDebateSummary(r *debate.Result) {
+	fmt.Println()
+	if r.Final != "" {
+		if r.Dissent != "" {
+			utils.PrintWarning("Разногласия: " + r.Dissent)
+		} else {
+			utils.PrintInfo("Модератор не отметил разногласий")
+		}
+	}
+
+	fmt.Printf("\n%-24s %8s %8s %12s\n", "Участник", "Запросов", "Токенов", "Стоимость")
+	for _, u := range r.Usage {
+		fmt.Printf("%-24s %8d %8d %12s\n", truncate(u.Participant, 24), u.Requests, u.TotalTokens, fmt.Sprintf("$%.6f", u.Cost))
+	}
+	fmt.Printf("%-24s %8s %8d %12s\n", "Итого", "", r.TotalTokens(), fmt.Sprintf("$%.6f", r.Cost()))
+}
+
+// Сравнение результатов всех стратегий
+func compareResults(results []StrategyResult) {
+	utils.PrintSection("📊", "СРАВНЕНИЕ РЕЗУЛЬТАТОВ")
+
+	// Таблица с основными метриками
+	fmt.Println("\n┌─────────────────────────┬───────────┬─────────────────┬────────┬──────────────────────────────────────────┐")
+	fmt.Println("│ Стратегия               │ Токены    │ Время           │ Оценка │ Решение                                  │")
+	fmt.Println("├─────────────────────────┼───────────┼─────────────────┼────────┼──────────────────────────────────────────┤")
+
+	for _, result := range results {
+		fmt.Printf("│ %-23s │ %9d │ %15s │ %6s │ %-40s │\n",
+			truncate(result.StrategyName, 23),
+			result.TokensUsed,
+			result.ExecutionTime.Round(time.Millisecond),
+			qualityLabel(result),
+			truncate(verdictLabel(result), 40),
+		)
+	}
+
+	fmt.Println("└─────────────────────────┴───────────┴─────────────────┴────────┴──────────────────────────────────────────┘")
+
+	// Анализ каждой стратегии
+	fmt.Print("\n📝 ДЕТАЛЬНЫЙ АНАЛИЗ:\n\n")
+
+	analyses := []struct {
+		name string
+		pros []string
+		cons []string
+	}{
+		{
+			name: "1. Прямой ответ",
+			pros: []string{
+				"Быстрое выполнение",
+				"Минимальное количество токенов",
+				"Простая реализация",
+			},
+			cons: []string{
+				"Может быть неполным",
+				"Нет гарантии детального рассуждения",
+				"Зависит от базовых знаний модели",
+			},
+		},
+		{
+			name: "2. Пошаговое решение",
+			pros: []string{
+				"Детальное объяснение процесса",
+				"Структурированный подход",
+				"Меньше ошибок благодаря проверке на каждом шаге",
+				"Понятно, как модель пришла к решению",
+			},
+			cons: []string{
+				"Больше токенов (выше стоимость)",
+				"Дольше выполнение",
+			},
+		},
+		{
+			name: "3. Мета-промпт",
+			pros: []string{
+				"Оптимизированный промпт под конкретную задачу",
+				"Модель сама формулирует лучший подход",
+				"Потенциально более качественное решение",
+			},
+			cons: []string{
+				"Два запроса вместо одного (2x стоимость)",
+				"Самое долгое выполнение",
+				"Не всегда лучше прямого пошагового подхода",
+			},
+		},
+		{
+			name: "4. Группа экспертов",
+			pros: []string{
+				"Разные углы зрения на задачу",
+				"Взаимная проверка решений",
+				"Более глубокий анализ",
+				"Можно выбрать лучшее решение",
+			},
+			cons: []string{
+				"Максимальное количество токенов",
+				"Несколько последовательных запросов",
+				"Избыточно для простых задач",
+			},
+		},
+		{
+			name: "5. Дерево мыслей",
+			pros: []string{
+				"Несколько вариантов на каждом шаге вместо одной цепочки",
+				"Ошибочные и ведущие по кругу ходы отсекаются сразу",
+				"Программная проверка шага делает поиск надежным",
+			},
+			cons: []string{
+				"Много коротких запросов: по одному на каждое расширение",
+				"Нужна оценка шага - промпт оценки или проверка для задачи",
+				"Сложнее в реализации",
+			},
+		},
+	}
+
+	for i, analysis := range analyses {
+		fmt.Printf("%s\n", analysis.name)
+
+		fmt.Println("\nПлюсы:")
+		for _, pro := range analysis.pros {
+			utils.PrintSuccess(pro)
+		}
+
+		fmt.Println("\nМинусы:")
+		for _, con := range analysis.cons {
+			utils.PrintError(con)
+		}
+
+		if i < len(analyses)-1 {
+			fmt.Println()
+		}
+	}
+
+	// Итоговый вывод
+	fmt.Println()
+	utils.PrintSection("🎯", "ВЫВОДЫ")
+
+	fmt.Println("Для простых задач:")
+	utils.PrintInfo("  → Прямой ответ или пошаговое решение")
+
+	fmt.Println("\nДля сложных логических задач:")
+	utils.PrintInfo("  → Пошаговое решение (баланс качества и стоимости)")
+
+	fmt.Println("\nДля критически важных решений:")
+	utils.PrintInfo("  → Группа экспертов (взаимная проверка)")
+
+	fmt.Println("\nДля задач с проверяемыми шагами (головоломки, планирование):")
+	utils.PrintInfo("  → Дерево мыслей (поиск с оценкой каждого шага)")
+
+	fmt.Println("\nДля исследовательских задач:")
+	utils.PrintInfo("  → Мета-промпт (оптимизация подхода)")
+
+	fmt.Println()
+	printCorrectness(results)
+	utils.PrintInfo("Выбор стратегии зависит от баланса между качеством, стоимостью и временем")
+}
+
+// Вспомогательная функция для обрезки строк
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
@@ -0,0 +1,234 @@
+package day3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/river"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/thought"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// Способы оценить шаг дерева мыслей
+const (
+	TreeEvalChecker = "checker" // Симуляция переправы: расстояние до цели
+	TreeEvalLLM     = "llm"     // Промпт оценки
+)
+
+// TreeEvalModes допустимые значения -tot-eval
+var TreeEvalModes = []string{TreeEvalChecker, TreeEvalLLM}
+
+// treeFlags флаги дерева мыслей
+var treeFlags = struct {
+	thought.Options
+	branches int
+	eval     string
+}{Options: thought.DefaultOptions(), branches: 3, eval: TreeEvalChecker}
+
+// riverStep состояние задачи о переправе после хода фермера
+type riverStep struct {
+	state river.State
+	move  river.Item
+	root  bool
+}
+
+// String описание хода с положением после него
+func (s riverStep) String() string {
+	if s.root {
+		return "Все на левом берегу"
+	}
+	text := "Фермер плывет один на " + s.state.Farmer.String() + " берег"
+	if s.move != river.Nobody {
+		text = fmt.Sprintf("Фермер перевозит %s на %s берег", accusative(s.move), s.state.Farmer)
+	}
+	if left := s.state.Left(); len(left) > 0 {
+		return fmt.Sprintf("%s (на левом берегу: %s)", text, strings.Join(left, ", "))
+	}
+	return text + " (на левом берегу никого)"
+}
+
+// Done возвращает true, когда все на правом берегу
+func (s riverStep) Done() bool {
+	return s.state == river.Goal
+}
+
+// Key положение на берегах: повтор положения - ход по кругу
+func (s riverStep) Key() string {
+	return fmt.Sprintf("%d%d%d%d", s.state.Farmer, s.state.Wolf, s.state.Goat, s.state.Cabbage)
+}
+
+// accusative название груза в винительном падеже
+func accusative(item river.Item) string {
+	switch item {
+	case river.Goat:
+		return "козу"
+	case river.Cabbage:
+		return "капусту"
+	case river.Wolf:
+		return "волка"
+	}
+	return item.Name()
+}
+
+// parseRiverStep разбирает вариант хода от модели: ровно один ход,
+// допустимый по правилам из текущего положения
+func parseRiverStep(path []thought.State, text string) (thought.State, error) {
+	moves := river.ParseMoves(text)
+	if len(moves) != 1 {
+		return nil, fmt.Errorf("ожидался один ход, найдено %d", len(moves))
+	}
+	last := path[len(path)-1].(riverStep)
+	next, err := last.state.Apply(moves[0])
+	if err != nil {
+		return nil, err
+	}
+	return riverStep{state: next, move: moves[0]}, nil
+}
+
+// riverChecker оценивает ход симуляцией переправы: чем меньше ходов
+// осталось до цели из нового положения, тем выше оценка
+type riverChecker struct {
+	shortest int // Ходов в кратчайшем решении из начального положения
+}
+
+func newRiverChecker() riverChecker {
+	return riverChecker{shortest: len(river.Solve(river.Start))}
+}
+
+// Evaluate оценивает последний ход пути
+func (c riverChecker) Evaluate(_ context.Context, path []thought.State) (thought.Score, error) {
+	state := path[len(path)-1].(riverStep).state
+	if state == river.Goal {
+		return thought.Score{Value: 1, Reason: "все на правом берегу"}, nil
+	}
+	remaining := river.Solve(state)
+	if remaining == nil {
+		return thought.Score{Reason: "из этого положения решения нет"}, nil
+	}
+	return thought.Score{
+		Value:  1 - float64(len(remaining))/float64(c.shortest+1),
+		Reason: fmt.Sprintf("до цели %d ходов", len(remaining)),
+	}, nil
+}
+
+// Стратегия 5: Дерево мыслей - модель предлагает варианты следующего хода,
+// варианты оцениваются, и решение продолжается от лучших
+func runStrategy5TreeOfThought(ctx context.Context, aiClient *client.OpenAIClient, problem prompts.Rendered, lib *prompts.Library) (StrategyResult, error) {
+	utils.PrintSection("5️⃣", "СТРАТЕГИЯ 5: Дерево мыслей")
+	utils.PrintKeyValue("Параметры", fmt.Sprintf("вариантов на шаг %d, ширина луча %d, глубина до %d, оценка %s",
+		treeFlags.branches, treeFlags.Beam, treeFlags.Depth, treeFlags.eval))
+	fmt.Println()
+
+	expander := thought.NewLLMExpander(aiClient, lib, problem.Text, treeFlags.branches)
+	expander.SetParser(parseRiverStep)
+
+	var evaluator thought.Evaluator = newRiverChecker()
+	var llmEvaluator *thought.LLMEvaluator
+	if treeFlags.eval == TreeEvalLLM {
+		llmEvaluator = thought.NewLLMEvaluator(aiClient, lib, problem.Text)
+		evaluator = llmEvaluator
+	}
+
+	search := thought.New(expander, evaluator, treeFlags.Options)
+	search.SetProgress(printNode)
+
+	start := time.Now()
+	result, err := search.Run(ctx, riverStep{state: river.Start, root: true})
+	elapsed := time.Since(start)
+	if err != nil {
+		// Частичный результат показываем, ошибку решает Run
+		err = fmt.Errorf("дерево мыслей: %w", err)
+		if result == nil {
+			return StrategyResult{}, err
+		}
+	}
+
+	tokens := expander.Tokens()
+	templates := []string{problem.String()}
+	if ref := expander.Template(); ref != "" {
+		templates = append(templates, ref)
+	}
+	if llmEvaluator != nil {
+		tokens += llmEvaluator.Tokens()
+		if ref := llmEvaluator.Template(); ref != "" {
+			templates = append(templates, ref)
+		}
+	}
+
+	answer := pathText(result)
+	fmt.Println()
+	if result.Solved {
+		utils.PrintSuccess(fmt.Sprintf("Решение найдено: %d ходов", len(result.Path)))
+	} else {
+		utils.PrintWarning(fmt.Sprintf("Решение не найдено, лучший путь: %d ходов", len(result.Path)))
+	}
+	if answer != "" {
+		fmt.Printf("\nЛучший путь:\n%s\n\n", answer)
+	}
+	utils.PrintKeyValue("Узлов дерева", fmt.Sprintf("%d (расширений %d, оценок %d, отброшено вариантов %d)",
+		len(result.Tree), result.Expansions, result.Evaluations, expander.Rejected()))
+	utils.PrintKeyValue("Время выполнения", elapsed.String())
+	utils.PrintKeyValue("Токенов всего", fmt.Sprintf("%d", tokens))
+	utils.PrintDivider()
+
+	return StrategyResult{
+		StrategyName:  "Дерево мыслей",
+		Prompt:        problem.Text,
+		Templates:     templates,
+		Response:      answer,
+		TokensUsed:    tokens,
+		ExecutionTime: elapsed,
+		Tree:          result,
+		answer:        answer,
+		moves:         pathMoves(result),
+	}, err
+}
+
+// pathMoves ходы лучшего пути из состояний дерева (корень ходом не считается)
+func pathMoves(r *thought.Result) []river.Item {
+	moves := make([]river.Item, 0, len(r.Path))
+	for _, s := range r.States() {
+		if step := s.(riverStep); !step.root {
+			moves = append(moves, step.move)
+		}
+	}
+	return moves
+}
+
+// printNode выводит оцененный вариант хода
+func printNode(n thought.Node) {
+	indent := strings.Repeat("  ", n.Depth-1)
+	switch n.Pruned {
+	case thought.PrunedRepeat:
+		fmt.Printf("%s%d. %s - повтор положения\n", indent, n.Depth, n.Step)
+	case thought.PrunedDeadEnd:
+		fmt.Printf("%s%d. %s - тупик: %s\n", indent, n.Depth, n.Step, n.Score.Reason)
+	default:
+		fmt.Printf("%s%d. %s - %.2f %s\n", indent, n.Depth, n.Step, n.Score.Value, n.Score.Reason)
+	}
+}
+
+// pathText лучший путь нумерованным списком ходов (пусто - ходов нет)
+func pathText(r *thought.Result) string {
+	lines := make([]string, 0, len(r.Path))
+	for i, n := range r.Path {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, n.Step))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateTreeFlags проверяет флаги дерева мыслей
+func validateTreeFlags() error {
+	switch {
+	case treeFlags.eval != TreeEvalChecker && treeFlags.eval != TreeEvalLLM:
+		return fmt.Errorf("неизвестный способ оценки %q (допустимо: %s)", treeFlags.eval, strings.Join(TreeEvalModes, ", "))
+	case treeFlags.branches < 1 || treeFlags.Beam < 1 || treeFlags.Depth < 1:
+		return errors.New("-tot-branches, -tot-beam и -tot-depth должны быть положительными")
+	}
+	return nil
+}
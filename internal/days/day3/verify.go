@@ -0,0 +1,141 @@
+package day3
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/river"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// verifyResults проверяет ответы стратегий симуляцией переправы и заполняет
+// AnswerCorrect, AnswerQuality и вердикты. У группы экспертов проверяется
+// итог модератора, а итоговые позиции экспертов - отдельно, для сравнения.
+func verifyResults(ctx context.Context, aiClient *client.OpenAIClient, lib *prompts.Library, results []StrategyResult) error {
+	if verifyMode == VerifyOff {
+		return nil
+	}
+
+	utils.PrintSection("🔎", "ПРОВЕРКА РЕШЕНИЙ")
+	for i := range results {
+		r := &results[i]
+		if r.answer == "" {
+			continue
+		}
+
+		// Ходы дерева мыслей известны: разбирать их описание не нужно
+		var verdict river.Verdict
+		if r.moves != nil {
+			verdict = river.Verify(r.moves)
+		} else {
+			var err error
+			if verdict, err = verifyAnswer(ctx, aiClient, lib, r.answer); err != nil {
+				return err
+			}
+		}
+		r.Verdict = &verdict
+		r.AnswerCorrect = verdict.Correct()
+		r.AnswerQuality = answerQuality(verdict)
+		fmt.Printf("%s: %s\n", r.StrategyName, verdict)
+
+		if r.Debate == nil {
+			continue
+		}
+		for _, position := range r.Debate.Positions() {
+			v, err := verifyAnswer(ctx, aiClient, lib, position.Content)
+			if err != nil {
+				return err
+			}
+			r.Verdicts = append(r.Verdicts, v)
+			fmt.Printf("   %s: %s\n", position.Participant, v)
+		}
+	}
+	utils.PrintDivider()
+	return nil
+}
+
+// verifyAnswer извлекает ходы из ответа и проверяет их. При -verify llm ходы
+// извлекает дополнительный запрос, а если он не удался (и ошибка не фатальна) -
+// разбор текста.
+func verifyAnswer(ctx context.Context, aiClient *client.OpenAIClient, lib *prompts.Library, answer string) (river.Verdict, error) {
+	if verifyMode == VerifyLLM {
+		moves, err := river.Extract(ctx, aiClient, lib, answer)
+		if err == nil {
+			return river.Verify(moves), nil
+		}
+		if usage.IsFatal(ctx, err) {
+			return river.Verdict{}, err
+		}
+		log.Printf("Извлечение ходов запросом не удалось, разбираю текст: %v", err)
+	}
+	return river.Verify(river.ParseMoves(answer)), nil
+}
+
+// answerQuality оценка от 1 до 10 по результату проверки: кратчайшее верное
+// решение - 10, лишние ходы снижают оценку, ошибка - 2-5 в зависимости
+// от того, как далеко решение продвинулось, не разобрано - 1
+func answerQuality(v river.Verdict) int {
+	switch v.Status {
+	case river.StatusValid:
+		return max(6, 10-(len(v.Moves)-v.Shortest+1)/2)
+	case river.StatusInvalid:
+		return 2 + min(v.Step-1, 6)/2
+	}
+	return 1
+}
+
+func qualityLabel(r StrategyResult) string {
+	if r.Verdict == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/10", r.AnswerQuality)
+}
+
+func verdictLabel(r StrategyResult) string {
+	if r.Verdict == nil {
+		return "не проверялось"
+	}
+
+	mark := "✗ "
+	if r.AnswerCorrect {
+		mark = "✓ "
+	}
+	label := mark + r.Verdict.String()
+	if len(r.Verdicts) > 1 {
+		correct := 0
+		for _, v := range r.Verdicts {
+			if v.Correct() {
+				correct++
+			}
+		}
+		label = fmt.Sprintf("%sэксперты %d/%d верно; %s", mark, correct, len(r.Verdicts), r.Verdict)
+	}
+	return label
+}
+
+// printCorrectness выводит, сколько стратегий дали верное решение
+func printCorrectness(results []StrategyResult) {
+	checked, correct := 0, 0
+	for _, r := range results {
+		if r.Verdict == nil {
+			continue
+		}
+		checked++
+		if r.AnswerCorrect {
+			correct++
+		}
+	}
+
+	switch {
+	case checked == 0:
+		utils.PrintWarning("Решения не проверялись (-verify off)")
+	case correct == checked:
+		utils.PrintSuccess(fmt.Sprintf("Все проверенные стратегии (%d) дали верное решение (проверено симуляцией переправы)", checked))
+	default:
+		utils.PrintWarning(fmt.Sprintf("Верное решение у %d из %d стратегий (проверено симуляцией переправы)", correct, checked))
+	}
+}
@@ -1,14 +1,22 @@
-package main
+// Package day4 - эксперимент с температурой (команда advent temperature)
+package day4
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/metrics"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+	openai "github.com/sashabaranov/go-openai"
 )
 
 // Тип задачи
@@ -20,31 +28,108 @@ const (
 	AnalyticalTask TaskType = "analytical" // Аналитическая задача
 )
 
-// Результат теста с определенной температурой
+// Результат одного запроса с определенной температурой
 type TemperatureResult struct {
-	Temperature float32
-	Response    string
-	TokensUsed  int
-	TimeTaken   time.Duration
+	Temperature float32       `json:"temperature"`
+	Repetition  int           `json:"repetition"` // Номер повтора с 1
+	Response    string        `json:"response"`
+	TokensUsed  int           `json:"tokens_used"`
+	TimeTaken   time.Duration `json:"time_taken_ns"`
+	Grade       *eval.Grade   `json:"grade,omitempty"` // Оценка судьи (nil - без -judge)
+}
+
+// Метрики повторных ответов при одной температуре
+type TemperatureMetrics struct {
+	Temperature float32 `json:"temperature"`
+	metrics.Report
+	Grade *metrics.Stats `json:"grade,omitempty"` // Оценки судьи
 }
 
 // Набор результатов для одной задачи
 type TaskResults struct {
-	TaskType    TaskType
-	Prompt      string
+	TaskType    TaskType             `json:"task_type"`
+	Prompt      string               `json:"prompt"`
+	Template    string               `json:"template"` // name@version#hash
+	Description string               `json:"description"`
+	Results     []TemperatureResult  `json:"results"`
+	Metrics     []TemperatureMetrics `json:"metrics,omitempty"`
+}
+
+// task задача эксперимента
+type task struct {
+	Type        TaskType
+	Emoji       string
+	Title       string // Заголовок раздела
+	Name        string // Название в сравнении
 	Description string
-	Results     []TemperatureResult
+	Template    string // Шаблон промпта в библиотеке: name@version
+	MaxTokens   int
 }
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+// tasks задачи в порядке запуска
+var tasks = []task{
+	{
+		Type:        FactualTask,
+		Emoji:       "1️⃣",
+		Title:       "ФАКТИЧЕСКАЯ ЗАДАЧА: Математика",
+		Name:        "Фактическая задача",
+		Description: "Математическая задача с точным ответом",
+		MaxTokens:   150,
+		Template:    "day4/factual@1",
+	},
+	{
+		Type:        CreativeTask,
+		Emoji:       "2️⃣",
+		Title:       "КРЕАТИВНАЯ ЗАДАЧА: Написание истории",
+		Name:        "Креативная задача",
+		Description: "Креативное написание текста",
+		MaxTokens:   200,
+		Template:    "day4/creative@1",
+	},
+	{
+		Type:        AnalyticalTask,
+		Emoji:       "3️⃣",
+		Title:       "АНАЛИТИЧЕСКАЯ ЗАДАЧА: Анализ данных",
+		Name:        "Аналитическая задача",
+		Description: "Анализ данных и выводы",
+		MaxTokens:   150,
+		Template:    "day4/analytical@1",
+	},
+}
+
+// Значения флагов команды
+var (
+	useJudge       = false
+	repetitions    = 3
+	embeddingModel = metrics.DefaultEmbeddingModel
+)
+
+// BindFlags регистрирует флаги команды
+func BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&useJudge, "judge", useJudge,
+		"оценивать ответы LLM-судьей по рубрике задачи: по запросу на каждый ответ (по умолчанию - эвристики по ключевым словам)")
+	fs.IntVar(&repetitions, "repetitions", repetitions,
+		"число ответов на каждую задачу при каждой температуре")
+	fs.StringVar(&embeddingModel, "embedding-model", embeddingModel,
+		"модель эмбеддингов для сходства ответов по смыслу (пусто - без эмбеддингов)")
+}
+
+// Run сравнивает ответы на три типа задач при разных температурах
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+	if repetitions < 1 {
+		return cli.Usagef("-repetitions должно быть не меньше 1, получено %d", repetitions)
 	}
 
 	// Создание клиента
-	aiClient := client.NewOpenAIClient(cfg.OpenAIKey)
+	aiClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+
+	// Учитываем запросы в журнале использования и лимитах расходов (если заданы)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiClient.SetMeter(meter)
 
 	// Заголовок
 	utils.PrintHeader("Day 4: Эксперимент с температурой")
@@ -55,26 +140,50 @@ func main() {
 	// Температуры для тестирования
 	temperatures := []float32{0.0, 0.7, 1.2}
 
-	// Хранилище всех результатов
-	allResults := make([]TaskResults, 0, 3)
-
-	// 1. Фактическая задача (математика/факты)
-	allResults = append(allResults, runFactualTask(aiClient, temperatures))
+	// Фактическая, креативная и аналитическая задачи
+	allResults := make([]TaskResults, 0, len(tasks))
+	for _, t := range tasks {
+		prompt, err := cfg.Prompts().Render(t.Template, nil)
+		if err != nil {
+			return err
+		}
+		results, err := runTask(ctx, aiClient, t, prompt, temperatures)
+		if err != nil {
+			return err
+		}
+		allResults = append(allResults, results)
+	}
 
-	// 2. Креативная задача (написание текста)
-	allResults = append(allResults, runCreativeTask(aiClient, temperatures))
+	// Оценка судьей
+	if useJudge {
+		opts := eval.Options{Client: aiClient, JudgeModel: cfg.Judge.Model, Prompts: cfg.Prompts()}
+		if err := judgeResults(ctx, opts, allResults); err != nil {
+			return err
+		}
+	}
 
-	// 3. Аналитическая задача
-	allResults = append(allResults, runAnalyticalTask(aiClient, temperatures))
+	// Метрики разнообразия
+	var embedder metrics.Embedder
+	if embeddingModel != "" {
+		openaiEmbedder := metrics.NewOpenAIEmbedder(openai.NewClientWithConfig(cfg.ClientConfig()), embeddingModel)
+		openaiEmbedder.SetMeter(meter)
+		embedder = openaiEmbedder
+	}
+	if err := measureResults(ctx, embedder, allResults); err != nil {
+		return err
+	}
 
 	// Сравнение и анализ
 	compareResults(allResults)
+	printMetrics(allResults)
 
 	// Рекомендации
 	printRecommendations()
 
 	utils.PrintDivider()
 	utils.PrintSuccess("Задание Day 4 выполнено!")
+
+	return env.Emit(allResults)
 }
 
 func printExperimentDescription() {
@@ -94,203 +203,116 @@ func printExperimentDescription() {
 	fmt.Println("  2. Креативная    (написание историй)")
 	fmt.Println("  3. Аналитическая (анализ данных)")
 	fmt.Println()
+	fmt.Printf("Каждый промпт повторяется %d раз(а) при каждой температуре: метрики\n", repetitions)
+	fmt.Println("разнообразия (distinct-n, self-BLEU, ROUGE-L, сходство эмбеддингов)")
+	fmt.Println("показывают, насколько различаются ответы, в виде среднего ± отклонения.")
+	fmt.Println()
 
 	utils.PrintDivider()
 }
 
-// Задача 1: Фактическая (математика)
-func runFactualTask(aiClient *client.OpenAIClient, temperatures []float32) TaskResults {
-	utils.PrintSection("1️⃣", "ФАКТИЧЕСКАЯ ЗАДАЧА: Математика")
-
-	prompt := `Реши математическую задачу:
-
-У Маши было 15 яблок. Она отдала 1/3 своих яблок Пете,
-а затем купила еще 7 яблок. Сколько яблок стало у Маши?
-
-Ответь кратко: только решение и ответ.`
-
-	fmt.Printf("Промпт:\n%s\n\n", prompt)
-
-	results := TaskResults{
-		TaskType:    FactualTask,
-		Prompt:      prompt,
-		Description: "Математическая задача с точным ответом",
-		Results:     make([]TemperatureResult, 0, len(temperatures)),
-	}
-
-	for _, temp := range temperatures {
-		fmt.Printf("🌡️  Temperature = %.1f\n", temp)
-		fmt.Println(strings.Repeat("─", 80))
-
-		start := time.Now()
-		resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-			Prompt:      prompt,
-			Temperature: temp,
-			MaxTokens:   150,
-		})
-		elapsed := time.Since(start)
-
-		if err != nil {
-			log.Printf("Ошибка: %v\n", err)
-			continue
-		}
-
-		fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-		utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-		utils.PrintKeyValue("Время", elapsed.Round(time.Millisecond).String())
-		fmt.Println()
-
-		results.Results = append(results.Results, TemperatureResult{
-			Temperature: temp,
-			Response:    resp.Content,
-			TokensUsed:  resp.TotalTokens,
-			TimeTaken:   elapsed,
-		})
-	}
-
-	utils.PrintDivider()
-	return results
-}
-
-// Задача 2: Креативная (написание текста)
-func runCreativeTask(aiClient *client.OpenAIClient, temperatures []float32) TaskResults {
-	utils.PrintSection("2️⃣", "КРЕАТИВНАЯ ЗАДАЧА: Написание истории")
-
-	prompt := `Напиши короткую историю (3-4 предложения) о роботе,
-который впервые увидел закат.
-
-Используй яркие образы и эмоции.`
-
-	fmt.Printf("Промпт:\n%s\n\n", prompt)
+// runTask запрашивает ответы на задачу при каждой температуре по repetitions раз.
+// Ответ с ошибкой запроса пропускается.
+func runTask(ctx context.Context, aiClient *client.OpenAIClient, t task, prompt prompts.Rendered, temperatures []float32) (TaskResults, error) {
+	utils.PrintSection(t.Emoji, t.Title)
+	fmt.Printf("Промпт (%s):\n%s\n\n", prompt.Ref(), prompt.Text)
 
 	results := TaskResults{
-		TaskType:    CreativeTask,
-		Prompt:      prompt,
-		Description: "Креативное написание текста",
-		Results:     make([]TemperatureResult, 0, len(temperatures)),
+		TaskType:    t.Type,
+		Prompt:      prompt.Text,
+		Template:    prompt.String(),
+		Description: t.Description,
+		Results:     make([]TemperatureResult, 0, len(temperatures)*repetitions),
 	}
 
 	for _, temp := range temperatures {
-		fmt.Printf("🌡️  Temperature = %.1f\n", temp)
-		fmt.Println(strings.Repeat("─", 80))
-
-		start := time.Now()
-		resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-			Prompt:      prompt,
-			Temperature: temp,
-			MaxTokens:   200,
-		})
-		elapsed := time.Since(start)
+		for rep := 1; rep <= repetitions; rep++ {
+			if repetitions > 1 {
+				fmt.Printf("🌡️  Temperature = %.1f, ответ %d из %d\n", temp, rep, repetitions)
+			} else {
+				fmt.Printf("🌡️  Temperature = %.1f\n", temp)
+			}
+			fmt.Println(strings.Repeat("─", 80))
+
+			start := time.Now()
+			resp, err := aiClient.CreateCompletionContext(ctx, client.CompletionRequest{
+				Prompt:      prompt.Text,
+				Templates:   []string{prompt.String()},
+				Temperature: client.Temperature(temp),
+				MaxTokens:   t.MaxTokens,
+			})
+			elapsed := time.Since(start)
+
+			if err != nil {
+				if usage.IsFatal(ctx, err) {
+					return results, err
+				}
+				log.Printf("Ошибка: %v\n", err)
+				continue
+			}
 
-		if err != nil {
-			log.Printf("Ошибка: %v\n", err)
-			continue
+			fmt.Printf("Ответ:\n%s\n\n", resp.Content)
+			utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
+			utils.PrintKeyValue("Время", elapsed.Round(time.Millisecond).String())
+			fmt.Println()
+
+			results.Results = append(results.Results, TemperatureResult{
+				Temperature: temp,
+				Repetition:  rep,
+				Response:    resp.Content,
+				TokensUsed:  resp.TotalTokens,
+				TimeTaken:   elapsed,
+			})
 		}
-
-		fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-		utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-		utils.PrintKeyValue("Время", elapsed.Round(time.Millisecond).String())
-		fmt.Println()
-
-		results.Results = append(results.Results, TemperatureResult{
-			Temperature: temp,
-			Response:    resp.Content,
-			TokensUsed:  resp.TotalTokens,
-			TimeTaken:   elapsed,
-		})
 	}
 
 	utils.PrintDivider()
-	return results
+	return results, nil
 }
 
-// Задача 3: Аналитическая
-func runAnalyticalTask(aiClient *client.OpenAIClient, temperatures []float32) TaskResults {
-	utils.PrintSection("3️⃣", "АНАЛИТИЧЕСКАЯ ЗАДАЧА: Анализ данных")
-
-	prompt := `Проанализируй следующие данные продаж:
-- Январь: 100 единиц
-- Февраль: 150 единиц
-- Март: 120 единиц
-
-Какой тренд наблюдается? Дай краткую рекомендацию (2-3 предложения).`
-
-	fmt.Printf("Промпт:\n%s\n\n", prompt)
-
-	results := TaskResults{
-		TaskType:    AnalyticalTask,
-		Prompt:      prompt,
-		Description: "Анализ данных и выводы",
-		Results:     make([]TemperatureResult, 0, len(temperatures)),
-	}
-
-	for _, temp := range temperatures {
-		fmt.Printf("🌡️  Temperature = %.1f\n", temp)
-		fmt.Println(strings.Repeat("─", 80))
-
-		start := time.Now()
-		resp, err := aiClient.CreateCompletion(client.CompletionRequest{
-			Prompt:      prompt,
-			Temperature: temp,
-			MaxTokens:   150,
-		})
-		elapsed := time.Since(start)
-
-		if err != nil {
-			log.Printf("Ошибка: %v\n", err)
-			continue
+// byTemperature группирует ответы по температуре в порядке первого появления
+func byTemperature(results []TemperatureResult) ([]float32, map[float32][]TemperatureResult) {
+	var order []float32
+	groups := make(map[float32][]TemperatureResult)
+	for _, r := range results {
+		if _, ok := groups[r.Temperature]; !ok {
+			order = append(order, r.Temperature)
 		}
-
-		fmt.Printf("Ответ:\n%s\n\n", resp.Content)
-		utils.PrintTokenStats(resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
-		utils.PrintKeyValue("Время", elapsed.Round(time.Millisecond).String())
-		fmt.Println()
-
-		results.Results = append(results.Results, TemperatureResult{
-			Temperature: temp,
-			Response:    resp.Content,
-			TokensUsed:  resp.TotalTokens,
-			TimeTaken:   elapsed,
-		})
+		groups[r.Temperature] = append(groups[r.Temperature], r)
 	}
-
-	utils.PrintDivider()
-	return results
+	return order, groups
 }
 
 func compareResults(allResults []TaskResults) {
 	utils.PrintSection("📊", "СРАВНИТЕЛЬНЫЙ АНАЛИЗ")
 
 	for _, taskResult := range allResults {
-		taskName := ""
-		emoji := ""
-
-		switch taskResult.TaskType {
-		case FactualTask:
-			taskName = "Фактическая задача"
-			emoji = "1️⃣"
-		case CreativeTask:
-			taskName = "Креативная задача"
-			emoji = "2️⃣"
-		case AnalyticalTask:
-			taskName = "Аналитическая задача"
-			emoji = "3️⃣"
-		}
-
-		fmt.Printf("\n%s %s\n", emoji, taskName)
+		t := taskByType(taskResult.TaskType)
+		fmt.Printf("\n%s %s\n", t.Emoji, t.Name)
 		fmt.Println(strings.Repeat("─", 80))
 
 		// Анализ для каждой температуры
-		for _, result := range taskResult.Results {
-			fmt.Printf("\n🌡️  Temperature = %.1f:\n", result.Temperature)
+		temperatures, groups := byTemperature(taskResult.Results)
+		for _, temp := range temperatures {
+			group := groups[temp]
+			fmt.Printf("\n🌡️  Temperature = %.1f:\n", temp)
+
+			if taskResult.TaskType == FactualTask {
+				printFactualAnswers(group)
+			}
+			if hasGrades(group) {
+				printGrades(group)
+				continue
+			}
 
+			// Эвристики по первому ответу
 			switch taskResult.TaskType {
 			case FactualTask:
-				analyzeFactualResponse(result)
+				analyzeFactualResponse(group[0])
 			case CreativeTask:
-				analyzeCreativeResponse(result)
+				analyzeCreativeResponse(group[0])
 			case AnalyticalTask:
-				analyzeAnalyticalResponse(result)
+				analyzeAnalyticalResponse(group[0])
 			}
 		}
 
@@ -300,16 +322,44 @@ func compareResults(allResults []TaskResults) {
 	utils.PrintDivider()
 }
 
-func analyzeFactualResponse(result TemperatureResult) {
-	// Проверяем наличие правильного ответа (17 яблок)
-	hasCorrectAnswer := strings.Contains(result.Response, "17")
-	responseLength := len(strings.Split(result.Response, " "))
+func taskByType(t TaskType) task {
+	for _, candidate := range tasks {
+		if candidate.Type == t {
+			return candidate
+		}
+	}
+	return task{Type: t, Name: string(t)}
+}
 
-	if hasCorrectAnswer {
-		utils.PrintSuccess("Правильный ответ присутствует")
-	} else {
-		utils.PrintError("Правильный ответ не найден или неточен")
+// factualAnswer проверка ответа фактической задачи: 15 - 15/3 + 7 = 17
+var factualAnswer, _ = eval.Numeric(17, eval.NumericOptions{})
+
+// printFactualAnswers проверяет итоговое число в ответах (17 яблок)
+func printFactualAnswers(group []TemperatureResult) {
+	correct := 0
+	var wrong []string
+	for _, r := range group {
+		score, _ := eval.EvaluateText(factualAnswer, r.Response)
+		if score.Pass {
+			correct++
+		} else {
+			wrong = append(wrong, fmt.Sprintf("ответ %d: %s", r.Repetition, score.Detail))
+		}
+	}
+
+	summary := fmt.Sprintf("Правильный ответ (17) в %d из %d ответов", correct, len(group))
+	if len(wrong) == 0 {
+		utils.PrintSuccess(summary)
+		return
+	}
+	utils.PrintError(summary)
+	for _, w := range wrong {
+		fmt.Printf("    %s\n", w)
 	}
+}
+
+func analyzeFactualResponse(result TemperatureResult) {
+	responseLength := len(strings.Split(result.Response, " "))
 
 	utils.PrintKeyValue("  Длина ответа", fmt.Sprintf("%d слов", responseLength))
 
@@ -393,7 +443,7 @@ func printRecommendations() {
 	fmt.Println("│                 │ ⚠️  Внимание: может быть непредсказуемо и нелогично   │")
 	fmt.Println("└─────────────────┴──────────────────────────────────────────────────────┘")
 
-	fmt.Println("\n📝 КЛЮЧЕВЫЕ ВЫВОДЫ:\n")
+	fmt.Print("\n📝 КЛЮЧЕВЫЕ ВЫВОДЫ:\n\n")
 
 	fmt.Println("1. Для фактических задач:")
 	utils.PrintSuccess("   Используйте низкую температуру (0.0-0.3)")
@@ -415,7 +465,7 @@ func printRecommendations() {
 	utils.PrintInfo("   Температура НЕ влияет на количество токенов напрямую")
 	utils.PrintInfo("   Но высокая температура может генерировать более длинные ответы")
 
-	fmt.Println("\n💡 ПРАКТИЧЕСКИЙ СОВЕТ:\n")
+	fmt.Print("\n💡 ПРАКТИЧЕСКИЙ СОВЕТ:\n\n")
 	fmt.Println("   Начните с temperature = 0.7 (значение по умолчанию)")
 	fmt.Println("   Затем:")
 	fmt.Println("   • Уменьшите, если нужна большая точность")
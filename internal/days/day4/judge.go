@@ -0,0 +1,136 @@
+package day4
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/metrics"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// rubrics критерии судьи для каждого типа задачи
+var rubrics = map[TaskType]eval.Rubric{
+	FactualTask: {
+		{Name: "correctness", Description: "верный итоговый ответ и верные вычисления", Weight: 3},
+		{Name: "conciseness", Description: "кратко: только решение и ответ, как просит задание", Weight: 1},
+	},
+	CreativeTask: {
+		{Name: "originality", Description: "неожиданные образы и метафоры, нет штампов", Weight: 2},
+		{Name: "imagery", Description: "яркие образы и передача эмоций робота", Weight: 2},
+		{Name: "coherence", Description: "связный сюжет, 3-4 предложения, как просит задание", Weight: 1},
+	},
+	AnalyticalTask: {
+		{Name: "accuracy", Description: "тренд описан верно: рост в феврале и спад в марте", Weight: 2},
+		{Name: "recommendation", Description: "рекомендация конкретна и следует из данных", Weight: 2},
+		{Name: "conciseness", Description: "2-3 предложения, как просит задание", Weight: 1},
+	},
+}
+
+// references эталонные ответы для судьи
+var references = map[TaskType]string{
+	FactualTask: "15 - 15/3 = 10, 10 + 7 = 17. Ответ: 17 яблок.",
+}
+
+// judgeResults оценивает ответы судьей и сохраняет оценки в результатах.
+// Ответ, который судья не смог оценить, остается без оценки; фатальная
+// ошибка (usage.IsFatal) возвращается сразу.
+func judgeResults(ctx context.Context, opts eval.Options, allResults []TaskResults) error {
+	utils.PrintSection("⚖️", "ОЦЕНКА СУДЬЕЙ")
+
+	var spent eval.Usage
+	defer func() {
+		// Расходы на судью - отдельно от расходов на ответы
+		utils.PrintKeyValue("Расходы судьи", spent.String())
+		utils.PrintDivider()
+	}()
+	for i := range allResults {
+		task := &allResults[i]
+		judge, err := eval.NewJudge(opts.Client, opts.JudgeModel, rubrics[task.TaskType])
+		if err != nil {
+			return err
+		}
+		if opts.Prompts != nil {
+			judge.SetPrompts(opts.Prompts)
+		}
+
+		for j := range task.Results {
+			result := &task.Results[j]
+			grade, err := judge.Grade(ctx, task.Prompt, result.Response, references[task.TaskType])
+			spent.Add(grade.Usage)
+			if err != nil {
+				if usage.IsFatal(ctx, err) {
+					return err
+				}
+				log.Printf("Судья не оценил ответ (%s, temperature %.1f, ответ %d): %v", task.TaskType, result.Temperature, result.Repetition, err)
+				continue
+			}
+			result.Grade = &grade
+			fmt.Printf("%s, temperature %.1f, ответ %d: %.1f/10\n", task.Description, result.Temperature, result.Repetition, grade.Score)
+		}
+	}
+
+	return nil
+}
+
+func hasGrades(group []TemperatureResult) bool {
+	for _, r := range group {
+		if r.Grade != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// gradeStats статистика оценок судьи по ответам группы (nil - оценок нет)
+func gradeStats(group []TemperatureResult) *metrics.Stats {
+	var scores []float64
+	for _, r := range group {
+		if r.Grade != nil {
+			scores = append(scores, r.Grade.Score)
+		}
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	stats := metrics.Describe(scores)
+	return &stats
+}
+
+// printGrades выводит оценки судьи: итог и каждый критерий - среднее ± отклонение
+// по ответам, обоснования - первого оцененного ответа
+func printGrades(group []TemperatureResult) {
+	stats := gradeStats(group)
+	utils.PrintKeyValue("  Оценка судьи", fmt.Sprintf("%s из 10 (ответов: %d)", stats.Format(1), stats.N))
+
+	var first *eval.Grade
+	byCriterion := make(map[string][]float64)
+	var names []string
+	for _, r := range group {
+		if r.Grade == nil {
+			continue
+		}
+		if first == nil {
+			first = r.Grade
+		}
+		for _, c := range r.Grade.Criteria {
+			if _, ok := byCriterion[c.Name]; !ok {
+				names = append(names, c.Name)
+			}
+			byCriterion[c.Name] = append(byCriterion[c.Name], float64(c.Score))
+		}
+	}
+
+	justifications := make(map[string]string)
+	for _, c := range first.Criteria {
+		justifications[c.Name] = c.Justification
+	}
+	for _, name := range names {
+		fmt.Printf("    %-15s %s  %s\n", name, metrics.Describe(byCriterion[name]).Format(1), justifications[name])
+	}
+	if first.Justification != "" {
+		utils.PrintInfo("  " + first.Justification)
+	}
+}
@@ -0,0 +1,85 @@
+package day4
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/metrics"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// measureResults считает метрики разнообразия ответов для каждой задачи и температуры.
+// Нефатальная ошибка эмбеддингов отключает сходство по смыслу для оставшихся групп.
+func measureResults(ctx context.Context, embedder metrics.Embedder, allResults []TaskResults) error {
+	for i := range allResults {
+		task := &allResults[i]
+		temperatures, groups := byTemperature(task.Results)
+		task.Metrics = make([]TemperatureMetrics, 0, len(temperatures))
+
+		for _, temp := range temperatures {
+			group := groups[temp]
+			texts := make([]string, 0, len(group))
+			for _, r := range group {
+				texts = append(texts, r.Response)
+			}
+
+			report, err := metrics.Analyze(ctx, texts, embedder)
+			if err != nil {
+				if usage.IsFatal(ctx, err) {
+					return err
+				}
+				log.Printf("Сходство эмбеддингов отключено: %v", err)
+				embedder = nil
+			}
+
+			task.Metrics = append(task.Metrics, TemperatureMetrics{
+				Temperature: temp,
+				Report:      report,
+				Grade:       gradeStats(group),
+			})
+		}
+	}
+	return nil
+}
+
+func printMetrics(allResults []TaskResults) {
+	utils.PrintSection("📐", "МЕТРИКИ РАЗНООБРАЗИЯ")
+
+	fmt.Println("Среднее ± отклонение по ответам при одной температуре.")
+	fmt.Println("distinct-2 - доля уникальных биграмм во всех ответах вместе (выше - разнообразнее);")
+	fmt.Println("self-BLEU, ROUGE-L и эмбеддинги - сходство ответов между собой (выше - однообразнее).")
+
+	for _, taskResult := range allResults {
+		t := taskByType(taskResult.TaskType)
+		fmt.Printf("\n%s %s\n", t.Emoji, t.Name)
+		fmt.Println(strings.Repeat("─", 80))
+		fmt.Printf("%-6s %-12s %-12s %-10s %-12s %-12s %-12s\n",
+			"Temp", "Слов", "TTR", "distinct-2", "self-BLEU", "ROUGE-L", "Эмбеддинги")
+
+		for _, m := range taskResult.Metrics {
+			embedding := "-"
+			if m.Embedding != nil {
+				embedding = m.Embedding.Format(2)
+			}
+			fmt.Printf("%-6.1f %-12s %-12s %-10.2f %-12s %-12s %-12s\n",
+				m.Temperature,
+				m.Length.Format(0),
+				m.TTR.Format(2),
+				m.SetDistinct2,
+				m.SelfBLEU.Format(2),
+				m.SelfROUGE.Format(2),
+				embedding,
+			)
+		}
+	}
+
+	fmt.Println()
+	if repetitions < 2 {
+		utils.PrintInfo("Для сравнения ответов между собой запустите с -repetitions 2 или больше")
+	}
+
+	utils.PrintDivider()
+}
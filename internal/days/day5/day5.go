@@ -1,4 +1,5 @@
-package main
+// Package day5 - сравнение версий моделей (команда advent compare-models)
+package day5
 
 import (
 	"context"
@@ -6,38 +7,53 @@ import (
 	"log"
 	"time"
 
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
 	openai "github.com/sashabaranov/go-openai"
 )
 
-// Информация о модели
+// Информация о модели: название для вывода плюс лимиты и цены из каталога
 type ModelInfo struct {
-	Name        string
-	DisplayName string
-	Tier        string  // "weak", "medium", "strong"
-	InputPrice  float64 // цена за 1M токенов (input)
-	OutputPrice float64 // цена за 1M токенов (output)
+	DisplayName string `json:"display_name"`
+	models.Model
 }
 
 // Результат теста модели
 type ModelResult struct {
-	Model            ModelInfo
-	Response         string
-	PromptTokens     int
-	CompletionTokens int
-	TotalTokens      int
-	ExecutionTime    time.Duration
-	InputCost        float64
-	OutputCost       float64
-	TotalCost        float64
+	Model            ModelInfo     `json:"model"`
+	Response         string        `json:"response"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	TotalTokens      int           `json:"total_tokens"`
+	ExecutionTime    time.Duration `json:"execution_time_ns"`
+	InputCost        float64       `json:"input_cost_usd"`
+	OutputCost       float64       `json:"output_cost_usd"`
+	TotalCost        float64       `json:"total_cost_usd"`
+	Grade            *eval.Grade   `json:"grade,omitempty"`    // Оценка судьи (nil - без -judge)
+	Pairwise         *Pairwise     `json:"pairwise,omitempty"` // Сравнение с первой моделью
 }
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
+// Pairwise сравнение ответа с ответом первой модели (A - первая модель)
+type Pairwise struct {
+	Baseline string `json:"baseline"`
+	eval.Comparison
+}
+
+// Run сравнивает ответы, время и стоимость моделей разного уровня.
+// Модели можно передать аргументами команды, по умолчанию - три модели задания.
+func Run(ctx context.Context, env *cli.Env) error {
+	apiClient := openai.NewClientWithConfig(env.Config.ClientConfig())
+
+	// Учитываем запросы в журнале использования и лимитах расходов (если заданы)
+	meter, err := env.Meter()
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		return err
 	}
 
 	// Заголовок
@@ -46,55 +62,64 @@ func main() {
 	// Описание эксперимента
 	printExperimentDescription()
 
-	// Определяем модели для тестирования
-	models := []ModelInfo{
-		{
-			Name:        openai.GPT4oMini,
-			DisplayName: "GPT-4o-mini",
-			Tier:        "weak",
-			InputPrice:  0.150, // $0.150 per 1M input tokens
-			OutputPrice: 0.600, // $0.600 per 1M output tokens
-		},
-		{
-			Name:        openai.GPT4o,
-			DisplayName: "GPT-4o",
-			Tier:        "medium",
-			InputPrice:  2.50,  // $2.50 per 1M input tokens
-			OutputPrice: 10.00, // $10.00 per 1M output tokens
-		},
-		{
-			Name:        openai.GPT4TurboPreview,
-			DisplayName: "GPT-4 Turbo",
-			Tier:        "strong",
-			InputPrice:  10.00, // $10.00 per 1M input tokens
-			OutputPrice: 30.00, // $30.00 per 1M output tokens
-		},
+	// Определяем модели для тестирования (tier и цены берутся из каталога моделей)
+	candidates := []struct{ name, displayName string }{
+		{openai.GPT4oMini, "GPT-4o-mini"},
+		{openai.GPT4o, "GPT-4o"},
+		{openai.GPT4TurboPreview, "GPT-4 Turbo"},
+	}
+	if len(env.Args) > 0 {
+		candidates = candidates[:0]
+		for _, name := range env.Args {
+			candidates = append(candidates, struct{ name, displayName string }{name, name})
+		}
 	}
 
-	// Тестовый промпт - сложная задача, требующая рассуждений
-	prompt := `Реши следующую логическую задачу:
-
-В комнате находятся 3 лампочки, а выключатели для них - в другой комнате.
-Ты можешь включить любые выключатели, но зайти в комнату с лампочками можешь только один раз.
-Как определить, какой выключатель управляет какой лампочкой?
+	testModels := make([]ModelInfo, 0, len(candidates))
+	for _, m := range candidates {
+		model, err := models.Lookup(m.name)
+		if err != nil {
+			return cli.Usagef("%v", err)
+		}
+		testModels = append(testModels, ModelInfo{DisplayName: m.displayName, Model: model})
+	}
 
-Объясни решение пошагово и дай обоснование.`
+	// Тестовый промпт - сложная задача, требующая рассуждений
+	prompt, err := env.Config.Prompts().Render("day5/light-bulbs@1", nil)
+	if err != nil {
+		return err
+	}
 
 	utils.PrintSection("📋", "ТЕСТОВЫЙ ПРОМПТ")
-	fmt.Printf("%s\n\n", prompt)
+	fmt.Printf("%s\n\n", prompt.Text)
 	utils.PrintDivider()
 
 	// Запуск тестов для каждой модели
-	results := make([]ModelResult, 0, len(models))
+	results := make([]ModelResult, 0, len(testModels))
 
-	for _, model := range models {
-		result := testModel(cfg.OpenAIKey, model, prompt)
+	for _, model := range testModels {
+		result, err := testModel(ctx, apiClient, meter, model, prompt)
+		if err != nil {
+			return err
+		}
 		results = append(results, result)
 
 		// Небольшая пауза между запросами
 		time.Sleep(1 * time.Second)
 	}
 
+	// Оценка судьей
+	if useJudge {
+		cfg := env.Config
+		judgeClient := client.NewOpenAIClientWithConfig(cfg.ClientConfig(), cfg.Model)
+		judgeClient.SetMeter(meter)
+
+		opts := eval.Options{Client: judgeClient, JudgeModel: cfg.Judge.Model, Prompts: cfg.Prompts()}
+		if err := judgeResults(ctx, opts, prompt.Text, results); err != nil {
+			return err
+		}
+	}
+
 	// Сравнение результатов
 	compareModels(results)
 
@@ -103,6 +128,8 @@ func main() {
 
 	utils.PrintDivider()
 	utils.PrintSuccess("Задание Day 5 выполнено!")
+
+	return env.Emit(results)
 }
 
 func printExperimentDescription() {
@@ -135,14 +162,11 @@ func printExperimentDescription() {
 	utils.PrintDivider()
 }
 
-func testModel(apiKey string, model ModelInfo, prompt string) ModelResult {
+func testModel(ctx context.Context, client *openai.Client, meter *telemetry.Meter, model ModelInfo, prompt prompts.Rendered) (ModelResult, error) {
 	utils.PrintSection("🤖", fmt.Sprintf("ТЕСТИРОВАНИЕ: %s", model.DisplayName))
 	fmt.Printf("Tier: %s\n", model.Tier)
 	fmt.Printf("Цена: $%.3f (input) / $%.3f (output) per 1M tokens\n\n", model.InputPrice, model.OutputPrice)
 
-	client := openai.NewClient(apiKey)
-	ctx := context.Background()
-
 	start := time.Now()
 
 	// Создаем запрос
@@ -151,25 +175,28 @@ func testModel(apiKey string, model ModelInfo, prompt string) ModelResult {
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
+				Content: prompt.Text,
 			},
 		},
 		Temperature: 0.7,
 	}
 
-	resp, err := client.CreateChatCompletion(ctx, req)
+	resp, _, err := meter.ChatCompletion(ctx, client, req, prompt.String())
 	elapsed := time.Since(start)
 
 	if err != nil {
+		if usage.IsFatal(ctx, err) {
+			return ModelResult{Model: model}, err
+		}
 		log.Printf("❌ Ошибка при тестировании модели %s: %v\n", model.DisplayName, err)
 		utils.PrintDivider()
-		return ModelResult{Model: model}
+		return ModelResult{Model: model}, nil
 	}
 
 	if len(resp.Choices) == 0 {
 		log.Printf("❌ Пустой ответ от модели %s\n", model.DisplayName)
 		utils.PrintDivider()
-		return ModelResult{Model: model}
+		return ModelResult{Model: model}, nil
 	}
 
 	response := resp.Choices[0].Message.Content
@@ -178,8 +205,8 @@ func testModel(apiKey string, model ModelInfo, prompt string) ModelResult {
 	totalTokens := resp.Usage.TotalTokens
 
 	// Расчет стоимости
-	inputCost := float64(promptTokens) / 1_000_000 * model.InputPrice
-	outputCost := float64(completionTokens) / 1_000_000 * model.OutputPrice
+	inputCost := model.Cost(promptTokens, 0, 0)
+	outputCost := model.Cost(0, 0, completionTokens)
 	totalCost := inputCost + outputCost
 
 	// Вывод ответа (первые 500 символов)
@@ -210,7 +237,7 @@ func testModel(apiKey string, model ModelInfo, prompt string) ModelResult {
 		InputCost:        inputCost,
 		OutputCost:       outputCost,
 		TotalCost:        totalCost,
-	}
+	}, nil
 }
 
 func compareModels(results []ModelResult) {
@@ -245,7 +272,7 @@ func compareModels(results []ModelResult) {
 	fmt.Println("└──────────────────────┴─────────────┴────────────┴──────────────┴──────────────┘")
 
 	// Анализ качества ответов
-	fmt.Println("\n📝 АНАЛИЗ КАЧЕСТВА ОТВЕТОВ:\n")
+	fmt.Print("\n📝 АНАЛИЗ КАЧЕСТВА ОТВЕТОВ:\n\n")
 
 	for i, result := range results {
 		if result.TotalTokens == 0 {
@@ -259,6 +286,12 @@ func compareModels(results []ModelResult) {
 
 		utils.PrintKeyValue("  Длина ответа", fmt.Sprintf("%d символов, ~%d слов", responseLength, wordCount))
 
+		if result.Grade != nil {
+			printGrade(result.Grade)
+			fmt.Println()
+			continue
+		}
+
 		// Проверка наличия ключевых элементов правильного решения
 		hasTemperature := contains(result.Response, "температур") || contains(result.Response, "тепл") || contains(result.Response, "горяч")
 		hasSteps := contains(result.Response, "шаг") || contains(result.Response, "Шаг")
@@ -291,8 +324,10 @@ func compareModels(results []ModelResult) {
 		fmt.Println()
 	}
 
+	printPairwise(results)
+
 	// Сравнение скорости
-	fmt.Println("⚡ СРАВНЕНИЕ СКОРОСТИ:\n")
+	fmt.Print("⚡ СРАВНЕНИЕ СКОРОСТИ:\n\n")
 
 	if len(results) > 1 {
 		fastest := results[0]
@@ -322,7 +357,7 @@ func compareModels(results []ModelResult) {
 	fmt.Println()
 
 	// Сравнение стоимости
-	fmt.Println("💰 СРАВНЕНИЕ СТОИМОСТИ:\n")
+	fmt.Print("💰 СРАВНЕНИЕ СТОИМОСТИ:\n\n")
 
 	if len(results) > 1 {
 		cheapest := results[0]
@@ -352,7 +387,7 @@ func compareModels(results []ModelResult) {
 	fmt.Println()
 
 	// Расчет стоимости на 1000 запросов
-	fmt.Println("💵 СТОИМОСТЬ НА 1000 ЗАПРОСОВ:\n")
+	fmt.Print("💵 СТОИМОСТЬ НА 1000 ЗАПРОСОВ:\n\n")
 
 	for _, result := range results {
 		if result.TotalTokens == 0 {
@@ -392,7 +427,7 @@ func printRecommendations() {
 	fmt.Println("│                  │ ✅ Лучший выбор для: исследования, большие тексты, код │")
 	fmt.Println("└──────────────────┴────────────────────────────────────────────────────────┘")
 
-	fmt.Println("\n📝 КЛЮЧЕВЫЕ ВЫВОДЫ:\n")
+	fmt.Print("\n📝 КЛЮЧЕВЫЕ ВЫВОДЫ:\n\n")
 
 	fmt.Println("1. Закон убывающей отдачи:")
 	utils.PrintInfo("   Переход от слабой к средней модели дает больший прирост качества,")
@@ -410,7 +445,7 @@ func printRecommendations() {
 	utils.PrintInfo("   Слабая модель: до 10x быстрее, но может пропустить детали")
 	utils.PrintInfo("   Сильная модель: медленнее, но надежнее для критичных задач")
 
-	fmt.Println("\n💡 ПРАКТИЧЕСКИЕ СОВЕТЫ:\n")
+	fmt.Print("\n💡 ПРАКТИЧЕСКИЕ СОВЕТЫ:\n\n")
 
 	fmt.Println("   • Начните с GPT-4o-mini, переходите к более сильным при необходимости")
 	fmt.Println("   • Используйте A/B тестирование для оценки реальной разницы")
@@ -418,7 +453,7 @@ func printRecommendations() {
 	fmt.Println("   • Следите за новыми релизами - модели постоянно улучшаются")
 	fmt.Println()
 
-	fmt.Println("🔗 ПОЛЕЗНЫЕ ССЫЛКИ:\n")
+	fmt.Print("🔗 ПОЛЕЗНЫЕ ССЫЛКИ:\n\n")
 	fmt.Println("   • OpenAI Pricing: https://openai.com/api/pricing/")
 	fmt.Println("   • Model Documentation: https://platform.openai.com/docs/models")
 	fmt.Println("   • HuggingFace Leaderboard: https://huggingface.co/spaces/lmsys/chatbot-arena-leaderboard")
@@ -0,0 +1,140 @@
+package day5
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// useJudge значение флага -judge
+var useJudge = false
+
+// BindFlags регистрирует флаги команды
+func BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&useJudge, "judge", useJudge,
+		"оценивать ответы LLM-судьей и сравнивать попарно с первой моделью (по умолчанию - эвристики по ключевым словам)")
+}
+
+// rubric критерии судьи для задачи о лампочках
+var rubric = eval.Rubric{
+	{Name: "correctness", Description: "решение верное: одна лампочка горит, одна теплая, одна холодная", Weight: 3},
+	{Name: "reasoning", Description: "пошаговое объяснение, каждый шаг обоснован", Weight: 2},
+	{Name: "clarity", Description: "понятно и без лишних рассуждений", Weight: 1},
+}
+
+// reference эталонное решение для судьи
+const reference = `Включить первый выключатель на несколько минут, выключить его и включить второй.
+Зайти в комнату: горящая лампочка - второй выключатель, теплая выключенная - первый,
+холодная выключенная - третий.`
+
+// judgeResults оценивает ответы моделей судьей и сравнивает каждый ответ
+// с ответом первой модели (A - первая модель, B - сравниваемая).
+// Оценку прерывает только фатальная ошибка (usage.IsFatal).
+func judgeResults(ctx context.Context, opts eval.Options, prompt string, results []ModelResult) error {
+	judge, err := eval.NewJudge(opts.Client, opts.JudgeModel, rubric)
+	if err != nil {
+		return err
+	}
+	if opts.Prompts != nil {
+		judge.SetPrompts(opts.Prompts)
+	}
+
+	utils.PrintSection("⚖️", "ОЦЕНКА СУДЬЕЙ")
+
+	var spent eval.Usage
+	defer func() {
+		// Расходы на судью - отдельно от расходов на ответы
+		utils.PrintKeyValue("Расходы судьи", spent.String())
+		utils.PrintDivider()
+	}()
+	var baseline *ModelResult
+	for i := range results {
+		r := &results[i]
+		if r.TotalTokens == 0 {
+			continue
+		}
+
+		grade, err := judge.Grade(ctx, prompt, r.Response, reference)
+		spent.Add(grade.Usage)
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return err
+			}
+			log.Printf("Судья не оценил ответ %s: %v", r.Model.DisplayName, err)
+		} else {
+			r.Grade = &grade
+			fmt.Printf("%s: %.1f/10\n", r.Model.DisplayName, grade.Score)
+		}
+
+		if baseline == nil {
+			baseline = r
+			continue
+		}
+		cmp, err := judge.Compare(ctx, prompt, baseline.Response, r.Response)
+		spent.Add(cmp.Usage)
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return err
+			}
+			log.Printf("Судья не сравнил %s с %s: %v", r.Model.DisplayName, baseline.Model.DisplayName, err)
+			continue
+		}
+		r.Pairwise = &Pairwise{Baseline: baseline.Model.DisplayName, Comparison: cmp}
+	}
+
+	return nil
+}
+
+// printGrade выводит оценку судьи по критериям
+func printGrade(grade *eval.Grade) {
+	utils.PrintKeyValue("  Оценка судьи", fmt.Sprintf("%.1f/10", grade.Score))
+	for _, c := range grade.Criteria {
+		fmt.Printf("    %-12s %2d/10  %s\n", c.Name, c.Score, c.Justification)
+	}
+	if grade.Justification != "" {
+		utils.PrintInfo("  " + grade.Justification)
+	}
+}
+
+// printPairwise выводит итоги попарного сравнения с первой моделью
+func printPairwise(results []ModelResult) {
+	var compared []ModelResult
+	for _, r := range results {
+		if r.Pairwise != nil {
+			compared = append(compared, r)
+		}
+	}
+	if len(compared) == 0 {
+		return
+	}
+
+	fmt.Print("⚖️  ПОПАРНОЕ СРАВНЕНИЕ (A/B, оба порядка):\n\n")
+	for _, r := range compared {
+		p := r.Pairwise
+		winner := "ничья"
+		switch p.Winner {
+		case eval.WinnerA:
+			winner = p.Baseline
+		case eval.WinnerB:
+			winner = r.Model.DisplayName
+		}
+		line := fmt.Sprintf("%s vs %s: %.1f / %.1f, лучше - %s", p.Baseline, r.Model.DisplayName, p.ScoreA, p.ScoreB, winner)
+		if !p.Consistent {
+			line += " (порядки разошлись - предпочтение вызвано позицией)"
+		}
+		utils.PrintInfo("  " + line)
+		for _, round := range p.Rounds {
+			order := "прямой порядок"
+			if round.Swapped {
+				order = "обратный порядок"
+			}
+			fmt.Printf("      %s: %s\n", order, round.Justification)
+		}
+	}
+	fmt.Println()
+}
@@ -1,24 +1,22 @@
-package main
+// Package day6 - первый AI агент (команда advent chat)
+package day6
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
-	openai "github.com/sashabaranov/go-openai"
 )
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
+// Run запускает интерактивный диалог с агентом
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
 
 	// Заголовок
 	utils.PrintHeader("Day 6: Первый AI Агент")
@@ -27,24 +25,39 @@ func main() {
 	printWelcome()
 
 	// Создаем агента с конфигурацией
-	agentConfig := agent.AgentConfig{
-		APIKey:      cfg.OpenAIKey,
-		Model:       openai.GPT4oMini,
-		Temperature: 0.7,
-		MaxTokens:   500,
-		SystemPrompt: `Ты - полезный AI ассистент. Отвечай кратко и по делу.
-Если не знаешь ответа, так и скажи. Будь дружелюбным и профессиональным.`,
+	agentConfig := cfg.AgentConfig()
+	if agentConfig.MaxTokens == 0 {
+		agentConfig.MaxTokens = 500
+	}
+	if agentConfig.SystemPrompt == "" {
+		// Системный промпт задания - закрепленная версия шаблона (ее можно переопределить в prompts_dir)
+		rendered, err := cfg.Prompts().Render("assistant/chat@1", nil)
+		if err != nil {
+			return err
+		}
+		agentConfig.SystemPrompt, agentConfig.SystemPromptRef = rendered.Text, rendered.String()
 	}
 
 	aiAgent := agent.NewAgent(agentConfig)
 
+	// Учитываем каждый запрос в журнале использования, лимитах расходов
+	// и метриках Prometheus (если заданы)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiAgent.SetMeter(meter)
+	if meter.Metrics() != nil {
+		utils.PrintInfo(fmt.Sprintf("Метрики Prometheus: http://%s/metrics", cfg.MetricsAddr))
+	}
+
 	utils.PrintSuccess("✓ Агент инициализирован и готов к работе!")
 	utils.PrintInfo(fmt.Sprintf("Модель: %s", agentConfig.Model))
-	utils.PrintInfo(fmt.Sprintf("Temperature: %.1f", agentConfig.Temperature))
+	utils.PrintInfo(fmt.Sprintf("Temperature: %.1f", cfg.Temperature))
 	fmt.Println()
 
 	// Запускаем интерактивный режим
-	runInteractiveMode(aiAgent)
+	return runInteractiveMode(ctx, aiAgent)
 }
 
 func printWelcome() {
@@ -69,7 +82,7 @@ func printWelcome() {
 	utils.PrintDivider()
 }
 
-func runInteractiveMode(aiAgent *agent.Agent) {
+func runInteractiveMode(ctx context.Context, aiAgent *agent.Agent) error {
 	reader := bufio.NewReader(os.Stdin)
 	totalTokens := 0
 	requestCount := 0
@@ -80,6 +93,10 @@ func runInteractiveMode(aiAgent *agent.Agent) {
 
 		// Читаем ввод пользователя
 		input, err := reader.ReadString('\n')
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("Ошибка чтения ввода: %v", err))
 			continue
@@ -95,14 +112,16 @@ func runInteractiveMode(aiAgent *agent.Agent) {
 
 		// Обрабатываем команды
 		if strings.HasPrefix(input, "/") {
-			handleCommand(input, aiAgent, totalTokens, requestCount)
+			if handleCommand(input, aiAgent, totalTokens, requestCount) {
+				return nil
+			}
 			continue
 		}
 
 		// Отправляем запрос агенту
 		fmt.Print("\n🤖 Агент: ")
 
-		response, err := aiAgent.Ask(input)
+		response, err := aiAgent.AskContext(ctx, input)
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("\nОшибка: %v", err))
 			continue
@@ -123,7 +142,8 @@ func runInteractiveMode(aiAgent *agent.Agent) {
 	}
 }
 
-func handleCommand(cmd string, aiAgent *agent.Agent, totalTokens, requestCount int) {
+// handleCommand выполняет команду диалога и возвращает true для выхода
+func handleCommand(cmd string, aiAgent *agent.Agent, totalTokens, requestCount int) bool {
 	cmd = strings.ToLower(cmd)
 
 	switch cmd {
@@ -143,19 +163,20 @@ func handleCommand(cmd string, aiAgent *agent.Agent, totalTokens, requestCount i
 	case "/exit", "/quit":
 		fmt.Println()
 		utils.PrintSuccess("До свидания! 👋")
-		os.Exit(0)
+		return true
 
 	default:
 		utils.PrintError(fmt.Sprintf("\n❌ Неизвестная команда: %s", cmd))
 		fmt.Println("Используйте /help для списка доступных команд")
 	}
+	return false
 }
 
 func printHelp() {
 	fmt.Println()
 	utils.PrintSection("📖", "СПРАВКА")
 
-	fmt.Println("Доступные команды:\n")
+	fmt.Print("Доступные команды:\n\n")
 
 	commands := []struct {
 		cmd  string
@@ -194,13 +215,13 @@ func printHistory(aiAgent *agent.Agent) {
 
 		if msg.Role == "user" {
 			prefix = "💬 Вы"
-			color = "\033[36m" // Cyan
+			color = utils.ColorCyan
 		} else {
 			prefix = "🤖 Агент"
-			color = "\033[32m" // Green
+			color = utils.ColorGreen
 		}
 
-		fmt.Printf("\n%s%s [%s]:\033[0m\n", color, prefix, msg.Timestamp.Format("15:04:05"))
+		fmt.Printf("\n%s\n", utils.Colorize(color, fmt.Sprintf("%s [%s]:", prefix, msg.Timestamp.Format("15:04:05"))))
 
 		// Обрезаем длинные сообщения
 		content := msg.Content
@@ -230,7 +251,7 @@ func printStats(aiAgent *agent.Agent, totalTokens, requestCount int) {
 	utils.PrintKeyValue("Запросов выполнено", fmt.Sprintf("%d", requestCount))
 	utils.PrintKeyValue("Сообщений в истории", fmt.Sprintf("%d", historySize))
 	utils.PrintKeyValue("Токенов использовано", fmt.Sprintf("%d", totalTokens))
-	utils.PrintKeyValue("Токенов в памяти (оценка)", fmt.Sprintf("%d", estimatedTokens))
+	utils.PrintKeyValue("Токенов в контексте", fmt.Sprintf("%d", estimatedTokens))
 
 	if requestCount > 0 {
 		avgTokens := float64(totalTokens) / float64(requestCount)
@@ -1,15 +1,20 @@
-package main
+// Package day7 - агент с сохранением контекста (команда advent memory)
+package day7
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/memory"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
 	openai "github.com/sashabaranov/go-openai"
 )
@@ -17,14 +22,14 @@ import (
 const (
 	// Путь к файлу сохранения
 	defaultSaveFile = ".agent_history.json"
+
+	// Путь к файлу долговременной памяти фактов
+	defaultMemoryFile = ".agent_memory.json"
 )
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
+// Run запускает диалог с агентом, который сохраняет историю и факты между запусками
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
 
 	// Заголовок
 	utils.PrintHeader("Day 7: Агент с сохранением контекста")
@@ -39,20 +44,47 @@ func main() {
 		homeDir = "."
 	}
 	saveFilePath := filepath.Join(homeDir, defaultSaveFile)
+	memoryFilePath := filepath.Join(homeDir, defaultMemoryFile)
 
 	// Создаем агента
-	agentConfig := agent.AgentConfig{
-		APIKey:      cfg.OpenAIKey,
-		Model:       openai.GPT4oMini,
-		Temperature: 0.7,
-		MaxTokens:   500,
-		SystemPrompt: `Ты - полезный AI ассистент с долговременной памятью.
-Ты помнишь все предыдущие разговоры даже после перезапуска.
-Отвечай кратко и по делу. Будь дружелюбным и профессиональным.`,
+	agentConfig := cfg.AgentConfig()
+	if agentConfig.MaxTokens == 0 {
+		agentConfig.MaxTokens = 500
+	}
+	if agentConfig.SystemPrompt == "" {
+		// Системный промпт задания - закрепленная версия шаблона (ее можно переопределить в prompts_dir)
+		rendered, err := cfg.Prompts().Render("assistant/memory@1", nil)
+		if err != nil {
+			return err
+		}
+		agentConfig.SystemPrompt, agentConfig.SystemPromptRef = rendered.Text, rendered.String()
 	}
 
 	aiAgent := agent.NewAgent(agentConfig)
 
+	// Учитываем каждый запрос в журнале использования, лимитах расходов
+	// и метриках Prometheus (если заданы)
+	meter, err := env.Meter()
+	if err != nil {
+		return err
+	}
+	aiAgent.SetMeter(meter)
+	if meter.Metrics() != nil {
+		utils.PrintInfo(fmt.Sprintf("Метрики Prometheus: http://%s/metrics", cfg.MetricsAddr))
+	}
+
+	// Подключаем память фактов, извлекаемых из каждого хода диалога
+	extractor := memory.NewLLMExtractor(openai.NewClientWithConfig(cfg.ClientConfig()), agentConfig.Model)
+	extractor.SetMeter(meter)
+	extractor.SetPrompts(cfg.Prompts())
+	factMemory := memory.New(extractor)
+	if err := factMemory.Store.Load(memoryFilePath); err != nil {
+		utils.PrintError(fmt.Sprintf("Ошибка загрузки памяти фактов: %v", err))
+	} else if factMemory.Store.Len() > 0 {
+		utils.PrintSuccess(fmt.Sprintf("✓ Загружено фактов: %d", factMemory.Store.Len()))
+	}
+	aiAgent.SetMemory(factMemory)
+
 	// Пытаемся загрузить сохраненную историю
 	err = aiAgent.LoadHistory(saveFilePath)
 	if err != nil {
@@ -68,11 +100,12 @@ func main() {
 	}
 
 	utils.PrintInfo(fmt.Sprintf("Файл сохранения: %s", saveFilePath))
+	utils.PrintInfo(fmt.Sprintf("Файл памяти фактов: %s", memoryFilePath))
 	utils.PrintInfo(fmt.Sprintf("Модель: %s", agentConfig.Model))
 	fmt.Println()
 
 	// Запускаем интерактивный режим
-	runInteractiveMode(aiAgent, saveFilePath)
+	return runInteractiveMode(ctx, aiAgent, saveFilePath, memoryFilePath)
 }
 
 func printWelcome() {
@@ -85,10 +118,12 @@ func printWelcome() {
 	fmt.Println("  • Загрузка истории при запуске")
 	fmt.Println("  • Продолжение диалога после перезапуска")
 	fmt.Println("  • Контекст сохраняется между сессиями")
+	fmt.Println("  • Факты о вас запоминаются и используются в следующих ответах")
 	fmt.Println()
 	fmt.Println("Доступные команды:")
 	fmt.Println("  /help     - показать справку")
 	fmt.Println("  /history  - показать историю диалога")
+	fmt.Println("  /memory   - показать, изменить или забыть факты")
 	fmt.Println("  /save     - принудительно сохранить историю")
 	fmt.Println("  /clear    - очистить историю (с подтверждением)")
 	fmt.Println("  /stats    - показать статистику")
@@ -100,7 +135,7 @@ func printWelcome() {
 	utils.PrintDivider()
 }
 
-func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
+func runInteractiveMode(ctx context.Context, aiAgent *agent.Agent, saveFilePath, memoryFilePath string) error {
 	reader := bufio.NewReader(os.Stdin)
 	totalTokens := 0
 	requestCount := 0
@@ -111,6 +146,10 @@ func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
 
 		// Читаем ввод пользователя
 		input, err := reader.ReadString('\n')
+		if err == io.EOF {
+			fmt.Println()
+			return aiAgent.SaveHistory(saveFilePath)
+		}
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("Ошибка чтения ввода: %v", err))
 			continue
@@ -126,8 +165,8 @@ func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
 
 		// Обрабатываем команды
 		if strings.HasPrefix(input, "/") {
-			if handleCommand(input, aiAgent, saveFilePath, totalTokens, requestCount) {
-				return // Выход из программы
+			if handleCommand(input, aiAgent, saveFilePath, memoryFilePath, totalTokens, requestCount) {
+				return nil // Выход из программы
 			}
 			continue
 		}
@@ -135,7 +174,7 @@ func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
 		// Отправляем запрос агенту
 		fmt.Print("\n🤖 Агент: ")
 
-		response, err := aiAgent.Ask(input)
+		response, err := aiAgent.AskContext(ctx, input)
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("\nОшибка: %v", err))
 			continue
@@ -150,6 +189,17 @@ func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
 			utils.PrintError(fmt.Sprintf("\n⚠️  Ошибка автосохранения: %v", err))
 		}
 
+		// Сохраняем память фактов
+		if response.UsageError != nil {
+			utils.PrintError(fmt.Sprintf("\n⚠️  Учет использования API: %v", response.UsageError))
+		}
+		if response.MemoryError != nil {
+			utils.PrintError(fmt.Sprintf("\n⚠️  Ошибка извлечения фактов: %v", response.MemoryError))
+		}
+		if err := aiAgent.GetMemory().Store.Save(memoryFilePath); err != nil {
+			utils.PrintError(fmt.Sprintf("\n⚠️  Ошибка сохранения памяти фактов: %v", err))
+		}
+
 		// Обновляем статистику
 		totalTokens += response.TokensUsed
 		requestCount++
@@ -159,11 +209,18 @@ func runInteractiveMode(aiAgent *agent.Agent, saveFilePath string) {
 		utils.PrintKeyValue("├─ Токены", fmt.Sprintf("%d", response.TokensUsed))
 		utils.PrintKeyValue("├─ Время", response.ExecutionTime.String())
 		utils.PrintKeyValue("├─ Сообщений в истории", fmt.Sprintf("%d", aiAgent.GetHistorySize()))
+		utils.PrintKeyValue("├─ Новых фактов", fmt.Sprintf("%d", response.FactsLearned))
 		utils.PrintKeyValue("└─ Автосохранение", "✓")
 	}
 }
 
-func handleCommand(cmd string, aiAgent *agent.Agent, saveFilePath string, totalTokens, requestCount int) bool {
+func handleCommand(cmd string, aiAgent *agent.Agent, saveFilePath, memoryFilePath string, totalTokens, requestCount int) bool {
+	// Команда /memory принимает аргументы, регистр значений важен
+	if fields := strings.Fields(cmd); strings.ToLower(fields[0]) == "/memory" {
+		handleMemoryCommand(fields[1:], aiAgent.GetMemory(), memoryFilePath)
+		return false
+	}
+
 	cmd = strings.ToLower(cmd)
 
 	switch cmd {
@@ -224,7 +281,7 @@ func printHelp() {
 	fmt.Println()
 	utils.PrintSection("📖", "СПРАВКА")
 
-	fmt.Println("Доступные команды:\n")
+	fmt.Print("Доступные команды:\n\n")
 
 	commands := []struct {
 		cmd  string
@@ -232,6 +289,9 @@ func printHelp() {
 	}{
 		{"/help", "Показать эту справку"},
 		{"/history", "Показать историю диалога"},
+		{"/memory", "Показать запомненные факты"},
+		{"/memory edit N текст", "Изменить значение факта #N"},
+		{"/memory forget N", "Забыть факт #N"},
 		{"/save", "Принудительно сохранить историю"},
 		{"/clear", "Очистить историю (с подтверждением)"},
 		{"/stats", "Показать статистику использования"},
@@ -239,7 +299,7 @@ func printHelp() {
 	}
 
 	for _, c := range commands {
-		fmt.Printf("  %-12s - %s\n", c.cmd, c.desc)
+		fmt.Printf("  %-20s - %s\n", c.cmd, c.desc)
 	}
 
 	fmt.Println()
@@ -268,13 +328,13 @@ func printHistory(aiAgent *agent.Agent) {
 
 		if msg.Role == "user" {
 			prefix = "💬 Вы"
-			color = "\033[36m" // Cyan
+			color = utils.ColorCyan
 		} else {
 			prefix = "🤖 Агент"
-			color = "\033[32m" // Green
+			color = utils.ColorGreen
 		}
 
-		fmt.Printf("\n%s%s [%s]:\033[0m\n", color, prefix, msg.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Printf("\n%s\n", utils.Colorize(color, fmt.Sprintf("%s [%s]:", prefix, msg.Timestamp.Format("2006-01-02 15:04:05"))))
 
 		// Обрезаем длинные сообщения
 		content := msg.Content
@@ -313,7 +373,7 @@ func printStats(aiAgent *agent.Agent, saveFilePath string, totalTokens, requestC
 	utils.PrintKeyValue("Запросов в этой сессии", fmt.Sprintf("%d", requestCount))
 	utils.PrintKeyValue("Сообщений в истории", fmt.Sprintf("%d", historySize))
 	utils.PrintKeyValue("Токенов использовано (сессия)", fmt.Sprintf("%d", totalTokens))
-	utils.PrintKeyValue("Токенов в памяти (оценка)", fmt.Sprintf("%d", estimatedTokens))
+	utils.PrintKeyValue("Токенов в контексте", fmt.Sprintf("%d", estimatedTokens))
 
 	if requestCount > 0 {
 		avgTokens := float64(totalTokens) / float64(requestCount)
@@ -340,6 +400,74 @@ func printStats(aiAgent *agent.Agent, saveFilePath string, totalTokens, requestC
 	utils.PrintDivider()
 }
 
+// handleMemoryCommand обрабатывает /memory, /memory edit N значение и /memory forget N
+func handleMemoryCommand(args []string, factMemory *memory.Memory, memoryFilePath string) {
+	if len(args) == 0 {
+		printMemory(factMemory)
+		return
+	}
+
+	action := strings.ToLower(args[0])
+	if action != "edit" && action != "forget" {
+		utils.PrintError(fmt.Sprintf("\n❌ Неизвестное действие: %s", args[0]))
+		fmt.Println("Используйте /memory, /memory edit N значение или /memory forget N")
+		return
+	}
+
+	if len(args) < 2 {
+		utils.PrintError("\n❌ Укажите номер факта")
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("\n❌ Некорректный номер факта: %s", args[1]))
+		return
+	}
+
+	switch action {
+	case "edit":
+		if len(args) < 3 {
+			utils.PrintError("\n❌ Укажите новое значение факта")
+			return
+		}
+		err = factMemory.Store.Update(id, strings.Join(args[2:], " "))
+	case "forget":
+		err = factMemory.Store.Forget(id)
+	}
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("\n❌ %v", err))
+		return
+	}
+
+	if err := factMemory.Store.Save(memoryFilePath); err != nil {
+		utils.PrintError(fmt.Sprintf("\n⚠️  Изменение применено, но не сохранено: %v", err))
+		return
+	}
+	utils.PrintSuccess(fmt.Sprintf("\n✓ Факт #%d обновлен", id))
+}
+
+func printMemory(factMemory *memory.Memory) {
+	facts := factMemory.Store.List()
+
+	if len(facts) == 0 {
+		utils.PrintInfo("\n📭 Память фактов пуста")
+		return
+	}
+
+	fmt.Println()
+	utils.PrintSection("🧠", fmt.Sprintf("ПАМЯТЬ ФАКТОВ (%d)", len(facts)))
+
+	for _, fact := range facts {
+		fmt.Printf("#%-3d %s (уверенность %.0f%%, %s)\n",
+			fact.ID, fact, fact.Confidence*100, fact.UpdatedAt.Format("2006-01-02 15:04"))
+		if len(fact.PreviousValues) > 0 {
+			fmt.Printf("     ранее: %s\n", strings.Join(fact.PreviousValues, ", "))
+		}
+	}
+
+	utils.PrintDivider()
+}
+
 func confirmClear() bool {
 	fmt.Print("\n⚠️  Вы уверены, что хотите очистить историю? (yes/no): ")
 
@@ -1,20 +1,36 @@
-package main
+// Package day8 - работа с токенами (команда advent tokens)
+package day8
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
 
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
 	openai "github.com/sashabaranov/go-openai"
 )
 
-func main() {
-	// Загрузка конфигурации
-	cfg, err := config.Load()
+// meter учет запросов команды: журнал использования и лимиты расходов
+// (общий для всех экспериментов)
+var meter *telemetry.Meter
+
+// Run показывает учет токенов и стоимости на сценарии из аргумента
+// (short, long, overflow, all) или на выбранном в меню
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+
+	var err error
+	meter, err = env.Meter()
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		return err
 	}
 
 	// Заголовок
@@ -24,37 +40,44 @@ func main() {
 	printIntro()
 
 	// Демонстрация различных сценариев
-	fmt.Println("Выберите сценарий для демонстрации:\n")
-	fmt.Println("1. Короткий диалог (отслеживание токенов)")
-	fmt.Println("2. Длинный диалог (рост стоимости)")
-	fmt.Println("3. Переполнение контекста (демонстрация проблемы)")
-	fmt.Println("4. Все сценарии подряд")
-	fmt.Println()
-
-	fmt.Print("Выбор (1-4): ")
 	var choice int
-	fmt.Scanln(&choice)
+	if len(env.Args) > 0 {
+		choice = scenarioChoice(env.Args[0])
+		if choice == 0 {
+			return cli.Usagef("неизвестный сценарий %q (допустимо: %s)", env.Args[0], strings.Join(Scenarios, ", "))
+		}
+	} else {
+		fmt.Print("Выберите сценарий для демонстрации:\n\n")
+		fmt.Println("1. Короткий диалог (отслеживание токенов)")
+		fmt.Println("2. Длинный диалог (рост стоимости)")
+		fmt.Println("3. Переполнение контекста (демонстрация проблемы)")
+		fmt.Println("4. Все сценарии подряд")
+		fmt.Println()
+
+		fmt.Print("Выбор (1-4): ")
+		fmt.Scanln(&choice)
+	}
 
 	switch choice {
 	case 1:
-		runShortDialogScenario(cfg.OpenAIKey)
+		runShortDialogScenario(ctx, cfg)
 	case 2:
-		runLongDialogScenario(cfg.OpenAIKey)
+		runLongDialogScenario(ctx, cfg)
 	case 3:
-		runOverflowScenario(cfg.OpenAIKey)
+		runOverflowScenario(ctx, cfg)
 	case 4:
-		runShortDialogScenario(cfg.OpenAIKey)
+		runShortDialogScenario(ctx, cfg)
 		fmt.Println("\n" + utils.Repeat("=", 80) + "\n")
-		runLongDialogScenario(cfg.OpenAIKey)
+		runLongDialogScenario(ctx, cfg)
 		fmt.Println("\n" + utils.Repeat("=", 80) + "\n")
-		runOverflowScenario(cfg.OpenAIKey)
+		runOverflowScenario(ctx, cfg)
 	default:
 		fmt.Println("Неверный выбор. Запуск всех сценариев...")
-		runShortDialogScenario(cfg.OpenAIKey)
+		runShortDialogScenario(ctx, cfg)
 		fmt.Println("\n" + utils.Repeat("=", 80) + "\n")
-		runLongDialogScenario(cfg.OpenAIKey)
+		runLongDialogScenario(ctx, cfg)
 		fmt.Println("\n" + utils.Repeat("=", 80) + "\n")
-		runOverflowScenario(cfg.OpenAIKey)
+		runOverflowScenario(ctx, cfg)
 	}
 
 	// Итоговые выводы
@@ -62,6 +85,20 @@ func main() {
 
 	utils.PrintDivider()
 	utils.PrintSuccess("Задание Day 8 выполнено!")
+	return nil
+}
+
+// Scenarios имена сценариев для аргумента команды
+var Scenarios = []string{"short", "long", "overflow", "all"}
+
+// scenarioChoice возвращает номер пункта меню для имени сценария (0 - неизвестный)
+func scenarioChoice(name string) int {
+	for i, scenario := range Scenarios {
+		if scenario == name {
+			return i + 1
+		}
+	}
+	return 0
 }
 
 func printIntro() {
@@ -87,32 +124,38 @@ func printIntro() {
 	utils.PrintDivider()
 }
 
-func runShortDialogScenario(apiKey string) {
+func runShortDialogScenario(ctx context.Context, cfg *config.Config) {
 	utils.PrintSection("1️⃣", "СЦЕНАРИЙ 1: Короткий диалог")
 
-	fmt.Println("Демонстрация: отслеживание токенов в коротком диалоге\n")
+	fmt.Print("Демонстрация: отслеживание токенов в коротком диалоге\n\n")
 
 	// Создаем агента
 	agentConfig := agent.AgentConfig{
-		APIKey:       apiKey,
+		APIKey:       cfg.OpenAIKey,
+		BaseURL:      cfg.BaseURL,
 		Model:        openai.GPT4oMini,
-		Temperature:  0.7,
+		Temperature:  client.Temperature(0.7),
 		MaxTokens:    100,
 		SystemPrompt: "Ты - краткий помощник. Отвечай максимально кратко.",
 	}
 
 	aiAgent := agent.NewAgent(agentConfig)
+	attachUsageLedger(aiAgent)
 
 	// Получаем информацию о модели
-	modelLimit := agent.GetModelLimit(agentConfig.Model)
-	inputPrice, outputPrice := agent.GetModelPricing(agentConfig.Model)
+	model, err := models.Lookup(agentConfig.Model)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Ошибка: %v", err))
+		return
+	}
+	modelLimit := model.ContextWindow
 
 	// Создаем трекер токенов
-	tokenStats := agent.NewTokenStats(modelLimit, inputPrice, outputPrice)
+	tokenStats := agent.NewTokenStatsForModel(model)
 
 	utils.PrintInfo(fmt.Sprintf("Модель: %s", agentConfig.Model))
 	utils.PrintInfo(fmt.Sprintf("Лимит контекста: %d токенов", modelLimit))
-	utils.PrintInfo(fmt.Sprintf("Цена: $%.3f/$%.3f per 1M tokens", inputPrice, outputPrice))
+	utils.PrintInfo(fmt.Sprintf("Цена: $%.3f/$%.3f per 1M tokens", model.InputPrice, model.OutputPrice))
 	fmt.Println()
 
 	// Короткий диалог
@@ -125,7 +168,11 @@ func runShortDialogScenario(apiKey string) {
 	for i, msg := range messages {
 		fmt.Printf("\n💬 Вы: %s\n", msg)
 
-		resp, err := aiAgent.Ask(msg)
+		resp, err := aiAgent.AskContext(ctx, msg)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			utils.PrintError(fmt.Sprintf("Остановка: %v", err))
+			break
+		}
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("Ошибка: %v", err))
 			continue
@@ -147,28 +194,34 @@ func runShortDialogScenario(apiKey string) {
 	printFinalStats(tokenStats)
 }
 
-func runLongDialogScenario(apiKey string) {
+func runLongDialogScenario(ctx context.Context, cfg *config.Config) {
 	utils.PrintSection("2️⃣", "СЦЕНАРИЙ 2: Длинный диалог (рост стоимости)")
 
-	fmt.Println("Демонстрация: как растут токены и стоимость по мере диалога\n")
+	fmt.Print("Демонстрация: как растут токены и стоимость по мере диалога\n\n")
 
 	// Создаем агента
 	agentConfig := agent.AgentConfig{
-		APIKey:       apiKey,
+		APIKey:       cfg.OpenAIKey,
+		BaseURL:      cfg.BaseURL,
 		Model:        openai.GPT4oMini,
-		Temperature:  0.7,
+		Temperature:  client.Temperature(0.7),
 		MaxTokens:    200,
 		SystemPrompt: "Ты - подробный помощник. Давай развернутые ответы.",
 	}
 
 	aiAgent := agent.NewAgent(agentConfig)
+	attachUsageLedger(aiAgent)
 
 	// Получаем информацию о модели
-	modelLimit := agent.GetModelLimit(agentConfig.Model)
-	inputPrice, outputPrice := agent.GetModelPricing(agentConfig.Model)
+	model, err := models.Lookup(agentConfig.Model)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Ошибка: %v", err))
+		return
+	}
+	modelLimit := model.ContextWindow
 
 	// Создаем трекер токенов
-	tokenStats := agent.NewTokenStats(modelLimit, inputPrice, outputPrice)
+	tokenStats := agent.NewTokenStatsForModel(model)
 
 	utils.PrintInfo(fmt.Sprintf("Модель: %s", agentConfig.Model))
 	utils.PrintInfo(fmt.Sprintf("Лимит контекста: %d токенов", modelLimit))
@@ -192,7 +245,11 @@ func runLongDialogScenario(apiKey string) {
 	for i, msg := range messages {
 		fmt.Printf("\n💬 Вы (#%d): %s\n", i+1, msg)
 
-		resp, err := aiAgent.Ask(msg)
+		resp, err := aiAgent.AskContext(ctx, msg)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			utils.PrintError(fmt.Sprintf("Остановка: %v", err))
+			break
+		}
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("Ошибка: %v", err))
 			continue
@@ -224,38 +281,44 @@ func runLongDialogScenario(apiKey string) {
 	printGrowthAnalysis(tokenStats)
 }
 
-func runOverflowScenario(apiKey string) {
+func runOverflowScenario(ctx context.Context, cfg *config.Config) {
 	utils.PrintSection("3️⃣", "СЦЕНАРИЙ 3: Переполнение контекста")
 
-	fmt.Println("Демонстрация: что происходит при превышении лимита\n")
-	fmt.Println("⚠️  Для демонстрации используем GPT-4 с маленьким контекстом (8K токенов)\n")
+	fmt.Print("Демонстрация: что происходит при превышении лимита\n\n")
+	fmt.Print("⚠️  Для демонстрации используем GPT-4 с маленьким контекстом (8K токенов)\n\n")
 
 	// Используем GPT-4 с маленьким контекстом для демонстрации
 	agentConfig := agent.AgentConfig{
-		APIKey:      apiKey,
+		APIKey:      cfg.OpenAIKey,
+		BaseURL:     cfg.BaseURL,
 		Model:       "gpt-4",
-		Temperature: 0.7,
+		Temperature: client.Temperature(0.7),
 		MaxTokens:   500,
 		SystemPrompt: `Ты - очень подробный помощник.
 Давай максимально развернутые и детальные ответы с примерами.`,
 	}
 
 	aiAgent := agent.NewAgent(agentConfig)
+	attachUsageLedger(aiAgent)
 
 	// Получаем информацию о модели
-	modelLimit := agent.GetModelLimit(agentConfig.Model)
-	inputPrice, outputPrice := agent.GetModelPricing(agentConfig.Model)
+	model, err := models.Lookup(agentConfig.Model)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Ошибка: %v", err))
+		return
+	}
+	modelLimit := model.ContextWindow
 
 	// Создаем трекер токенов
-	tokenStats := agent.NewTokenStats(modelLimit, inputPrice, outputPrice)
+	tokenStats := agent.NewTokenStatsForModel(model)
 
 	utils.PrintInfo(fmt.Sprintf("Модель: %s", agentConfig.Model))
 	utils.PrintInfo(fmt.Sprintf("Лимит контекста: %d токенов (МАЛЕНЬКИЙ!)", modelLimit))
-	utils.PrintInfo(fmt.Sprintf("Цена: $%.2f/$%.2f per 1M tokens", inputPrice, outputPrice))
+	utils.PrintInfo(fmt.Sprintf("Цена: $%.2f/$%.2f per 1M tokens", model.InputPrice, model.OutputPrice))
 	fmt.Println()
 
 	// Генерируем много длинных сообщений
-	fmt.Println("Начинаем отправлять много длинных сообщений...\n")
+	fmt.Print("Начинаем отправлять много длинных сообщений...\n\n")
 
 	for i := 1; i <= 20; i++ {
 		msg := fmt.Sprintf(`Расскажи подробно о теме номер %d:
@@ -265,7 +328,7 @@ func runOverflowScenario(apiKey string) {
 
 		fmt.Printf("💬 Запрос #%d (длинный запрос про программирование)\n", i)
 
-		resp, err := aiAgent.Ask(msg)
+		resp, err := aiAgent.AskContext(ctx, msg)
 
 		// Обновляем статистику перед проверкой ошибки
 		if resp != nil {
@@ -273,6 +336,14 @@ func runOverflowScenario(apiKey string) {
 			tokenStats.UpdateContextSize(aiAgent.GetTotalTokens())
 		}
 
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			fmt.Println()
+			utils.PrintError(fmt.Sprintf("💸 %v", err))
+			utils.PrintInfo("Запрос не отправлен: бюджет остановил цикл до того, как он потратил лишние деньги")
+			fmt.Println()
+			break
+		}
+
 		if err != nil {
 			fmt.Println()
 			utils.PrintError(fmt.Sprintf("❌ ОШИБКА: %v", err))
@@ -337,6 +408,11 @@ func runOverflowScenario(apiKey string) {
 	fmt.Println()
 }
 
+// attachUsageLedger подключает к агенту учет запросов: журнал использования API и лимиты расходов
+func attachUsageLedger(aiAgent *agent.Agent) {
+	aiAgent.SetMeter(meter)
+}
+
 func printDetailedStats(stats *agent.TokenStats, resp *agent.Response, requestNum int) {
 	fmt.Printf("├─ Запрос #%d:\n", requestNum)
 	fmt.Printf("│  ├─ Токены запроса: %d\n", resp.PromptTokens)
@@ -1,64 +1,93 @@
-package main
+// Package day9 - управление контекстом, сжатие истории (команда advent compress)
+package day9
 
 import (
 	"context"
 	"fmt"
-	"log"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
-	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/config"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/cli"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
 	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
 	"github.com/sashabaranov/go-openai"
 )
 
-func main() {
+// longDialogContextConfig пороги сжатия для длинного диалога
+var longDialogContextConfig = agent.ContextConfig{
+	CompressThresholdTokens: 250,
+	RecentTokens:            200,
+	MaxSummaryTokens:        150,
+	SummaryBudgetTokens:     250,
+}
+
+// dialogModel модель диалога из конфигурации
+var dialogModel = openai.GPT4oMini
+
+// metrics метрики Prometheus (nil, если METRICS_ADDR не задан)
+var metrics *telemetry.Metrics
+
+// meter учет запросов команды: лимиты расходов и журнал использования
+var meter *telemetry.Meter
+
+// Run сравнивает длинный диалог без сжатия и со сжатием истории
+func Run(ctx context.Context, env *cli.Env) error {
+	cfg := env.Config
+
 	utils.PrintHeader("Day 9: Управление контекстом - сжатие истории")
 
-	cfg, err := config.Load()
+	client := openai.NewClientWithConfig(cfg.ClientConfig())
+	dialogModel = cfg.Model
+
+	// Все запросы, включая суммаризацию, проверяются бюджетом и пишутся в журнал
+	var err error
+	meter, err = env.Meter()
+	if err != nil {
+		return err
+	}
+
+	summarizer, err := cfg.NewSummarizer(client, meter)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	client := openai.NewClient(cfg.OpenAIKey)
+	metrics = meter.Metrics()
 
 	// Демонстрация 1: Длинный диалог без сжатия
 	fmt.Println("\n📝 СЦЕНАРИЙ 1: Длинный диалог БЕЗ сжатия")
 	utils.PrintSeparator()
-	runWithoutCompression(client)
+	runWithoutCompression(ctx, client)
 
-	fmt.Println("\n\n")
+	fmt.Print("\n\n\n")
 
 	// Демонстрация 2: Длинный диалог со сжатием
 	fmt.Println("🗜️  СЦЕНАРИЙ 2: Длинный диалог СО сжатием")
 	utils.PrintSeparator()
-	runWithCompression(client)
+	runWithCompression(ctx, client, summarizer)
 
-	fmt.Println("\n\n")
+	fmt.Print("\n\n\n")
 
 	// Демонстрация 3: Сравнение качества ответов
 	fmt.Println("🔍 СЦЕНАРИЙ 3: Сравнение качества ответов")
 	utils.PrintSeparator()
-	compareQuality(client)
+	compareQuality(ctx, client, summarizer)
+	return nil
 }
 
 // runWithoutCompression демонстрирует работу без сжатия
-func runWithoutCompression(client *openai.Client) {
-	ctx := context.Background()
+func runWithoutCompression(ctx context.Context, client *openai.Client) {
+	ctx, span := telemetry.Tracer().Start(ctx, "runWithoutCompression")
+	defer span.End()
 
 	// Симулируем длинный диалог (20 сообщений)
 	messages := generateLongDialog()
 
 	fmt.Printf("Всего сообщений: %d\n", len(messages))
 
-	// Подсчитываем токены
-	totalTokens := 0
-	for _, msg := range messages {
-		totalTokens += len(msg.Content) / 3 // Приблизительная оценка
-	}
-
-	fmt.Printf("Приблизительно токенов в контексте: %d\n", totalTokens)
-
 	// Отправляем запрос со всей историей
 	fullHistory := make([]openai.ChatCompletionMessage, 0)
 	for _, msg := range messages {
@@ -68,17 +97,20 @@ func runWithoutCompression(client *openai.Client) {
 		})
 	}
 
+	// Подсчитываем токены по словарю модели
+	fmt.Printf("Токенов в контексте: %d\n", tokenizer.CountMessages(dialogModel, fullHistory))
+
 	// Добавляем финальный вопрос
 	fullHistory = append(fullHistory, openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleUser,
 		Content: "Подведи итог нашего разговора: о чем мы говорили и какие решения приняли?",
 	})
 
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
+	resp, _, err := meter.ChatCompletion(ctx, client, openai.ChatCompletionRequest{
+		Model:       dialogModel,
 		Messages:    fullHistory,
 		Temperature: 0.7,
-	})
+	}, "")
 
 	if err != nil {
 		fmt.Printf("Ошибка: %v\n", err)
@@ -96,27 +128,33 @@ func runWithoutCompression(client *openai.Client) {
 	fmt.Printf("  • Токенов в запросе: %d\n", resp.Usage.PromptTokens)
 	fmt.Printf("  • Токенов в ответе:  %d\n", resp.Usage.CompletionTokens)
 	fmt.Printf("  • Всего токенов:     %d\n", resp.Usage.TotalTokens)
-	fmt.Printf("  • Стоимость:         $%.6f\n", calculateCost(resp.Usage))
+	fmt.Printf("  • Стоимость:         %s\n", formatCost(resp.Model, resp.Usage))
 }
 
 // runWithCompression демонстрирует работу со сжатием
-func runWithCompression(client *openai.Client) {
+func runWithCompression(ctx context.Context, client *openai.Client, summarizer agent.Summarizer) {
+	ctx, span := telemetry.Tracer().Start(ctx, "runWithCompression")
+	defer span.End()
+
 	// Создаем менеджер контекста
-	// Сжимаем каждые 10 сообщений, храним последние 6 "как есть"
-	cm := agent.NewContextManager(client, 10, 6)
+	// Сжимаем, когда середина истории превышает 250 токенов, храним последние 200 токенов "как есть"
+	cm := agent.NewContextManager(client, longDialogContextConfig)
+	cm.SetMetrics(metrics)
+	cm.SetSummarizer(summarizer)
 
 	// Симулируем длинный диалог
 	messages := generateLongDialog()
 
 	fmt.Printf("Всего сообщений: %d\n", len(messages))
-	fmt.Printf("Настройки: сжатие каждые 10 сообщений, последние 6 без сжатия\n")
+	fmt.Printf("Настройки: сжатие при >%d токенах в середине, последние %d токенов без сжатия, summary до %d токенов\n",
+		longDialogContextConfig.CompressThresholdTokens, longDialogContextConfig.RecentTokens, longDialogContextConfig.MaxSummaryTokens)
 
 	// Добавляем сообщения постепенно
 	for _, msg := range messages {
 		cm.AddMessage(msg.Role, msg.Content)
 
 		// Проверяем и сжимаем при необходимости
-		if err := cm.CompressIfNeeded(); err != nil {
+		if err := cm.CompressIfNeeded(ctx); err != nil {
 			fmt.Printf("Ошибка сжатия: %v\n", err)
 		}
 	}
@@ -127,12 +165,20 @@ func runWithCompression(client *openai.Client) {
 	fmt.Println("\n📊 Статистика сжатия:")
 	fmt.Printf("  • Всего сообщений:        %d\n", stats.TotalMessages)
 	fmt.Printf("  • Сжатых блоков:          %d\n", stats.CompressedBlocks)
-	fmt.Printf("  • Последних (без сжатия): %d\n", stats.RecentMessages)
+	fmt.Printf("  • Последних (без сжатия): %d (%d токенов)\n", stats.RecentMessages, stats.RecentTokens)
+	fmt.Printf("  • Токенов в summary:      %d\n", stats.SummaryTokens)
+	for _, level := range stats.Levels {
+		fmt.Printf("    ◦ Уровень %d: %d блок(ов), %d токенов, покрывают %d сообщений\n",
+			level.Level, level.Blocks, level.Tokens, level.Messages)
+	}
 	fmt.Printf("  • Токенов оригинал:       %d\n", stats.OriginalTokens)
 	fmt.Printf("  • Токенов сжато:          %d\n", stats.CompressedTokens)
 	fmt.Printf("  • Сэкономлено токенов:    %d\n", stats.TokensSaved)
 	fmt.Printf("  • Сжатие:                 %.1f%%\n", stats.CompressionPercent)
 
+	// Сохраняем состояние и восстанавливаем его в новом менеджере
+	demonstrateStatePersistence(client, cm, summarizer)
+
 	// Формируем контекст для запроса
 	contextMessages := cm.GetContextForRequest()
 
@@ -161,12 +207,11 @@ func runWithCompression(client *openai.Client) {
 		Content: "Подведи итог нашего разговора: о чем мы говорили и какие решения приняли?",
 	})
 
-	ctx := context.Background()
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
+	resp, _, err := meter.ChatCompletion(ctx, client, openai.ChatCompletionRequest{
+		Model:       dialogModel,
 		Messages:    compressedHistory,
 		Temperature: 0.7,
-	})
+	}, "")
 
 	if err != nil {
 		fmt.Printf("Ошибка: %v\n", err)
@@ -184,12 +229,47 @@ func runWithCompression(client *openai.Client) {
 	fmt.Printf("  • Токенов в запросе: %d\n", resp.Usage.PromptTokens)
 	fmt.Printf("  • Токенов в ответе:  %d\n", resp.Usage.CompletionTokens)
 	fmt.Printf("  • Всего токенов:     %d\n", resp.Usage.TotalTokens)
-	fmt.Printf("  • Стоимость:         $%.6f\n", calculateCost(resp.Usage))
+	fmt.Printf("  • Стоимость:         %s\n", formatCost(resp.Model, resp.Usage))
+}
+
+// demonstrateStatePersistence сохраняет состояние менеджера и восстанавливает его без повторной суммаризации
+func demonstrateStatePersistence(client *openai.Client, cm *agent.ContextManager, summarizer agent.Summarizer) {
+	stateFile := filepath.Join(os.TempDir(), "day9_context_state.json")
+
+	if err := cm.SaveState(stateFile); err != nil {
+		fmt.Printf("Ошибка сохранения состояния: %v\n", err)
+		return
+	}
+
+	restored := agent.NewContextManager(client, longDialogContextConfig)
+	restored.SetSummarizer(summarizer)
+	if err := restored.LoadState(stateFile); err != nil {
+		fmt.Printf("Ошибка загрузки состояния: %v\n", err)
+		return
+	}
+
+	restoredStats := restored.GetStats()
+	fmt.Printf("\n💾 Состояние сохранено в %s и восстановлено:\n", stateFile)
+	fmt.Printf("  • Сообщений:          %d\n", restoredStats.TotalMessages)
+	fmt.Printf("  • Сжатых блоков:      %d\n", restoredStats.CompressedBlocks)
+	fmt.Printf("  • Устаревших блоков:  %d (пересчет не требуется)\n", restored.StaleSummaries())
+	for i, summary := range restored.GetSummaries() {
+		fmt.Printf("  • Блок %d (уровень %d): сообщения %d-%d (%s, промпт v%s)\n",
+			i+1, summary.Level, summary.StartIndex+1, summary.EndIndex, summary.Model, summary.PromptVersion)
+	}
+
+	// Для отладки разворачиваем первый блок обратно в исходные сообщения
+	if summaries := restored.GetSummaries(); len(summaries) > 0 {
+		source := restored.ExpandSummary(summaries[0])
+		fmt.Printf("\n🔎 Блок 1 развернут в %d исходных сообщений, первое: %s\n",
+			len(source), truncate(source[0].Content, 60))
+	}
 }
 
 // compareQuality сравнивает качество ответов со сжатием и без
-func compareQuality(client *openai.Client) {
-	ctx := context.Background()
+func compareQuality(ctx context.Context, client *openai.Client, summarizer agent.Summarizer) {
+	ctx, span := telemetry.Tracer().Start(ctx, "compareQuality")
+	defer span.End()
 
 	// Создаем диалог с важной информацией в разных частях
 	messages := []agent.Message{
@@ -241,10 +321,15 @@ func compareQuality(client *openai.Client) {
 	fmt.Println("\n\n🔶 СО СЖАТИЕМ:")
 	fmt.Println(strings.Repeat("─", 80))
 
-	cm := agent.NewContextManager(client, 6, 4)
+	cm := agent.NewContextManager(client, agent.ContextConfig{
+		CompressThresholdTokens: 150,
+		RecentTokens:            100,
+		MaxSummaryTokens:        150,
+	})
+	cm.SetSummarizer(summarizer)
 	for _, msg := range messages {
 		cm.AddMessage(msg.Role, msg.Content)
-		cm.CompressIfNeeded()
+		cm.CompressIfNeeded(ctx)
 	}
 
 	stats := cm.GetStats()
@@ -290,11 +375,11 @@ func askQuestion(ctx context.Context, client *openai.Client, history []openai.Ch
 		Content: question,
 	})
 
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
+	resp, _, err := meter.ChatCompletion(ctx, client, openai.ChatCompletionRequest{
+		Model:       dialogModel,
 		Messages:    messages,
 		Temperature: 0.3,
-	})
+	}, "")
 
 	if err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
@@ -339,16 +424,18 @@ func generateLongDialog() []agent.Message {
 	}
 }
 
-// calculateCost рассчитывает стоимость запроса
-func calculateCost(usage openai.Usage) float64 {
-	// GPT-4o-mini pricing (per 1M tokens)
-	inputPrice := 0.150 / 1_000_000  // $0.150 per 1M input tokens
-	outputPrice := 0.600 / 1_000_000 // $0.600 per 1M output tokens
-
-	inputCost := float64(usage.PromptTokens) * inputPrice
-	outputCost := float64(usage.CompletionTokens) * outputPrice
+// formatCost рассчитывает стоимость запроса по ценам модели из каталога
+func formatCost(modelName string, usage openai.Usage) string {
+	model, err := models.Lookup(modelName)
+	if err != nil {
+		return fmt.Sprintf("неизвестна (%v)", err)
+	}
 
-	return inputCost + outputCost
+	cached := 0
+	if usage.PromptTokensDetails != nil {
+		cached = usage.PromptTokensDetails.CachedTokens
+	}
+	return fmt.Sprintf("$%.6f", model.Cost(usage.PromptTokens, cached, usage.CompletionTokens))
 }
 
 // truncate обрезает строку до заданной длины
@@ -0,0 +1,304 @@
+// Package debate - обсуждение вопроса группой агентов: участники отвечают,
+// читают ответы друг друга в течение нескольких раундов и пересматривают свои,
+// затем модератор сводит итоговые позиции в один ответ и отмечает разногласия.
+// Каждый участник - agent.Agent со своей историей диалога.
+package debate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Шаблоны промптов во встроенной библиотеке (можно переопределить в prompts_dir)
+const (
+	RoundPrompt     = "debate/round@1"     // Ответы других участников в раунде
+	ModeratorPrompt = "debate/moderator@1" // Системный промпт модератора
+	AggregatePrompt = "debate/aggregate@1" // Запрос итогового ответа модератору
+)
+
+// Заголовки ответа модератора
+const (
+	finalMarker   = "ИТОГОВЫЙ ОТВЕТ:"
+	dissentMarker = "РАЗНОГЛАСИЯ:"
+)
+
+// Participant участник обсуждения
+type Participant struct {
+	Name    string       // Имя в стенограмме и отчете
+	Agent   *agent.Agent // Своя история диалога и системный промпт
+	Opening string       // Первое сообщение участнику (пусто - вопрос обсуждения)
+}
+
+// Turn реплика участника или модератора
+type Turn struct {
+	Round            int           `json:"round"` // 0 - первые ответы, Rounds+1 - итог модератора
+	Participant      string        `json:"participant"`
+	Prompt           string        `json:"prompt"` // Сообщение, на которое отвечает участник
+	Content          string        `json:"content"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Cost             float64       `json:"cost_usd"`
+	Duration         time.Duration `json:"duration_ns"`
+}
+
+// Usage расходы одного участника за обсуждение
+type Usage struct {
+	Participant      string  `json:"participant"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost_usd"`
+}
+
+// Result итог обсуждения: стенограмма, ответ модератора и расходы по участникам
+type Result struct {
+	Question   string   `json:"question"`
+	Rounds     int      `json:"rounds"`
+	Moderator  string   `json:"moderator"`
+	Transcript []Turn   `json:"transcript"`
+	Final      string   `json:"final"`             // Итоговый ответ (пусто - модератор не ответил)
+	Dissent    string   `json:"dissent,omitempty"` // Разногласия по мнению модератора
+	Usage      []Usage  `json:"usage"`             // По участникам, модератор последним
+	Templates  []string `json:"templates"`         // Шаблоны раунда и итога: name@version#hash
+}
+
+// TotalTokens возвращает токены всех запросов обсуждения
+func (r *Result) TotalTokens() int {
+	total := 0
+	for _, u := range r.Usage {
+		total += u.TotalTokens
+	}
+	return total
+}
+
+// Cost возвращает стоимость всех запросов обсуждения
+func (r *Result) Cost() float64 {
+	total := 0.0
+	for _, u := range r.Usage {
+		total += u.Cost
+	}
+	return total
+}
+
+// Positions возвращает последние реплики участников (без модератора) в порядке участников
+func (r *Result) Positions() []Turn {
+	var positions []Turn
+	index := make(map[string]int)
+	for _, t := range r.Transcript {
+		if t.Participant == r.Moderator {
+			continue
+		}
+		if i, ok := index[t.Participant]; ok {
+			positions[i] = t
+			continue
+		}
+		index[t.Participant] = len(positions)
+		positions = append(positions, t)
+	}
+	return positions
+}
+
+// Debate обсуждение вопроса группой агентов
+type Debate struct {
+	participants []Participant
+	moderator    Participant
+	rounds       int
+	prompts      *prompts.Library
+
+	onTurn func(Turn) // Вызывается после каждой реплики (опционально)
+}
+
+// New создает обсуждение: rounds - число раундов после первых ответов
+// (0 - участники не читают друг друга), lib - библиотека с шаблонами раунда
+// и итога (nil - встроенная). Системный промпт модератора задает вызывающий код,
+// например по шаблону ModeratorPrompt.
+func New(participants []Participant, moderator Participant, rounds int, lib *prompts.Library) *Debate {
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	return &Debate{participants: participants, moderator: moderator, rounds: rounds, prompts: lib}
+}
+
+// SetProgress задает функцию, которая получает каждую реплику сразу после ответа
+func (d *Debate) SetProgress(fn func(Turn)) {
+	d.onTurn = fn
+}
+
+// Run проводит обсуждение. При ошибке запроса (включая превышение бюджета
+// и отмену ctx) возвращается стенограмма на момент ошибки вместе с ошибкой.
+// Имена участников и модератора должны быть разными.
+func (d *Debate) Run(ctx context.Context, question string) (*Result, error) {
+	if len(d.participants) < 2 {
+		return nil, errors.New("для обсуждения нужно хотя бы два участника")
+	}
+	if d.rounds < 0 {
+		return nil, fmt.Errorf("число раундов не может быть отрицательным: %d", d.rounds)
+	}
+	// Реплики и расходы учитываются по имени: одинаковые имена смешали бы их
+	names := map[string]bool{d.moderator.Name: true}
+	for _, p := range d.participants {
+		if names[p.Name] {
+			return nil, fmt.Errorf("имя участника %q повторяется или совпадает с именем модератора", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	ctx, span := telemetry.Tracer().Start(ctx, "debate.Run")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("debate.participants", len(d.participants)),
+		attribute.Int("debate.rounds", d.rounds),
+	)
+
+	result := &Result{Question: question, Rounds: d.rounds, Moderator: d.moderator.Name}
+	err := d.run(ctx, result)
+	result.Usage = d.usage(result.Transcript)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return result, err
+	}
+	return result, nil
+}
+
+func (d *Debate) run(ctx context.Context, result *Result) error {
+	// Первые ответы: участники еще не видят друг друга
+	for _, p := range d.participants {
+		prompt := p.Opening
+		if prompt == "" {
+			prompt = result.Question
+		}
+		if _, err := d.ask(ctx, result, p, 0, prompt); err != nil {
+			return err
+		}
+	}
+
+	// Раунды: каждый читает ответы остальных из предыдущего раунда
+	for round := 1; round <= d.rounds; round++ {
+		previous := result.Positions()
+		for i, p := range d.participants {
+			others := make([]Turn, 0, len(previous)-1)
+			for j, t := range previous {
+				if j != i {
+					others = append(others, t)
+				}
+			}
+			prompt, err := d.render(result, RoundPrompt, map[string]any{
+				"round":  round,
+				"rounds": d.rounds,
+				"others": others,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := d.ask(ctx, result, p, round, prompt); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Итог модератора по последним позициям
+	prompt, err := d.render(result, AggregatePrompt, map[string]any{
+		"question":  result.Question,
+		"rounds":    d.rounds,
+		"positions": result.Positions(),
+	})
+	if err != nil {
+		return err
+	}
+	turn, err := d.ask(ctx, result, d.moderator, d.rounds+1, prompt)
+	if err != nil {
+		return err
+	}
+	result.Final, result.Dissent = parseVerdict(turn.Content)
+	return nil
+}
+
+// ask отправляет сообщение участнику и добавляет ответ в стенограмму
+func (d *Debate) ask(ctx context.Context, result *Result, p Participant, round int, prompt string) (Turn, error) {
+	resp, err := p.Agent.AskContext(ctx, prompt)
+	if err != nil {
+		return Turn{}, fmt.Errorf("%s: %w", p.Name, err)
+	}
+
+	turn := Turn{
+		Round:            round,
+		Participant:      p.Name,
+		Prompt:           prompt,
+		Content:          resp.Content,
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+		Cost:             resp.Cost,
+		Duration:         resp.ExecutionTime,
+	}
+	result.Transcript = append(result.Transcript, turn)
+	if d.onTurn != nil {
+		d.onTurn(turn)
+	}
+	return turn, nil
+}
+
+// render строит сообщение по шаблону и запоминает шаблон в итоге
+func (d *Debate) render(result *Result, name string, vars map[string]any) (string, error) {
+	rendered, err := d.prompts.Render(name, vars)
+	if err != nil {
+		return "", err
+	}
+	ref := rendered.String()
+	for _, t := range result.Templates {
+		if t == ref {
+			return rendered.Text, nil
+		}
+	}
+	result.Templates = append(result.Templates, ref)
+	return rendered.Text, nil
+}
+
+// usage суммирует расходы по участникам в порядке участников, модератор последним
+func (d *Debate) usage(transcript []Turn) []Usage {
+	names := make([]string, 0, len(d.participants)+1)
+	for _, p := range d.participants {
+		names = append(names, p.Name)
+	}
+	names = append(names, d.moderator.Name)
+
+	byName := make(map[string]*Usage, len(names))
+	usage := make([]Usage, len(names))
+	for i, name := range names {
+		usage[i].Participant = name
+		byName[name] = &usage[i]
+	}
+	for _, t := range transcript {
+		u := byName[t.Participant]
+		u.Requests++
+		u.PromptTokens += t.PromptTokens
+		u.CompletionTokens += t.CompletionTokens
+		u.TotalTokens += t.PromptTokens + t.CompletionTokens
+		u.Cost += t.Cost
+	}
+	return usage
+}
+
+// parseVerdict делит ответ модератора на итоговый ответ и разногласия.
+// Ответ не в формате целиком считается итоговым.
+func parseVerdict(content string) (final, dissent string) {
+	final = content
+	if before, after, ok := strings.Cut(content, dissentMarker); ok {
+		final, dissent = before, strings.TrimSpace(after)
+	}
+	final = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(final), finalMarker))
+
+	switch strings.ToLower(strings.Trim(dissent, " .«»\"")) {
+	case "нет", "нет разногласий", "-":
+		dissent = ""
+	}
+	return final, dissent
+}
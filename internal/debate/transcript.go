@@ -0,0 +1,81 @@
+package debate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+)
+
+// WriteTranscript сохраняет стенограмму обсуждения: файл .json - итог целиком
+// в JSON, иначе - markdown. Ключи, случайно попавшие в реплики, маскируются.
+func (r *Result) WriteTranscript(path string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		if data, err = json.MarshalIndent(r, "", "  "); err != nil {
+			return fmt.Errorf("ошибка сериализации: %w", err)
+		}
+	} else {
+		data = []byte(r.Markdown())
+	}
+
+	if err := os.WriteFile(path, redact.Bytes(data), 0644); err != nil {
+		return fmt.Errorf("ошибка записи стенограммы: %w", err)
+	}
+	return nil
+}
+
+// Markdown возвращает стенограмму обсуждения в markdown: вопрос, реплики
+// по раундам, итог модератора и расходы по участникам
+func (r *Result) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Стенограмма обсуждения\n\n")
+	fmt.Fprintf(&b, "## Вопрос\n\n%s\n", strings.TrimSpace(r.Question))
+
+	round := -1
+	for _, t := range r.Transcript {
+		if t.Round != round {
+			round = t.Round
+			b.WriteString("\n## " + roundTitle(round, r.Rounds) + "\n")
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", t.Participant, strings.TrimSpace(t.Content))
+	}
+
+	if r.Final != "" {
+		fmt.Fprintf(&b, "\n## Итоговый ответ\n\n%s\n", r.Final)
+		dissent := r.Dissent
+		if dissent == "" {
+			dissent = "нет"
+		}
+		fmt.Fprintf(&b, "\n**Разногласия:** %s\n", dissent)
+	}
+
+	b.WriteString("\n## Расходы\n\n")
+	b.WriteString("| Участник | Запросов | Промпт | Ответ | Всего | Стоимость |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+	for _, u := range r.Usage {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | $%.6f |\n",
+			u.Participant, u.Requests, u.PromptTokens, u.CompletionTokens, u.TotalTokens, u.Cost)
+	}
+	fmt.Fprintf(&b, "| **Итого** | | | | %d | $%.6f |\n", r.TotalTokens(), r.Cost())
+
+	if len(r.Templates) > 0 {
+		fmt.Fprintf(&b, "\nШаблоны: %s\n", strings.Join(r.Templates, ", "))
+	}
+	return b.String()
+}
+
+// roundTitle заголовок раунда в стенограмме
+func roundTitle(round, rounds int) string {
+	switch {
+	case round == 0:
+		return "Первые ответы"
+	case round > rounds:
+		return "Модератор"
+	}
+	return fmt.Sprintf("Раунд %d из %d", round, rounds)
+}
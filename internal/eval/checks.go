@@ -0,0 +1,324 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Проверки без обращения к API. Конструкторы можно использовать напрямую,
+// например в Go-тестах:
+//
+//	check, _ := eval.Numeric(17, eval.NumericOptions{})
+//	score, _ := eval.EvaluateText(check, answer)
+//	if !score.Pass {
+//		t.Errorf("неверный ответ: %s", score.Detail)
+//	}
+
+// EvaluateText применяет проверку к тексту ответа без контекста запроса
+func EvaluateText(e Evaluator, response string) (Score, error) {
+	return e.Evaluate(context.Background(), Input{Response: response})
+}
+
+// ExactMatch ответ (без пробелов по краям) совпадает с одним из ожидаемых
+func ExactMatch(expected ...string) Evaluator {
+	return newMatch("exact", expected, strings.TrimSpace)
+}
+
+// NormalizedMatch ответ совпадает с одним из ожидаемых после Normalize
+func NormalizedMatch(expected ...string) Evaluator {
+	return newMatch("normalized", expected, Normalize)
+}
+
+// Normalize приводит текст к нижнему регистру, заменяет ё на е,
+// убирает знаки препинания и лишние пробелы
+func Normalize(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func newMatch(name string, expected []string, normalize func(string) string) Evaluator {
+	return check(name, func(in Input) (bool, string) {
+		response := normalize(in.Response)
+		for _, e := range expected {
+			if response == normalize(e) {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("ожидалось %s, получено %q", quoteAll(expected), truncate(response, 80))
+	})
+}
+
+// Regex ответ содержит совпадение с регулярным выражением
+func Regex(pattern string) (Evaluator, error) {
+	return newRegex("regex", pattern)
+}
+
+func newRegex(name, pattern string) (Evaluator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное регулярное выражение: %w", err)
+	}
+	return check(name, func(in Input) (bool, string) {
+		if re.MatchString(in.Response) {
+			return true, ""
+		}
+		return false, "нет совпадения с " + pattern
+	}), nil
+}
+
+// Способы выбрать число из ответа для Numeric
+const (
+	ExtractLast  = "last"  // Последнее число (итоговый ответ обычно в конце)
+	ExtractFirst = "first" // Первое число
+	ExtractAny   = "any"   // Любое из чисел
+)
+
+// NumericOptions параметры проверки числового ответа
+type NumericOptions struct {
+	Tolerance float64 // Допустимое отклонение (0 - точное совпадение)
+	Extract   string  // ExtractLast (по умолчанию), ExtractFirst или ExtractAny
+	Pattern   string  // Регулярное выражение, первая группа которого - ответ (пусто - все числа)
+}
+
+// numberPattern число с необязательным знаком и дробной частью через точку или запятую
+var numberPattern = regexp.MustCompile(`[-−]?\d+(?:[.,]\d+)?`)
+
+// Numeric извлекает число из ответа и сравнивает с ожидаемым с допуском
+func Numeric(expected float64, opts NumericOptions) (Evaluator, error) {
+	return newNumeric("numeric", expected, opts)
+}
+
+func newNumeric(name string, expected float64, opts NumericOptions) (Evaluator, error) {
+	if opts.Tolerance < 0 {
+		return nil, fmt.Errorf("отрицательный допуск %v", opts.Tolerance)
+	}
+	switch opts.Extract {
+	case "":
+		opts.Extract = ExtractLast
+	case ExtractLast, ExtractFirst, ExtractAny:
+	default:
+		return nil, fmt.Errorf("неизвестный способ извлечения %q (допустимо: %s, %s, %s)", opts.Extract, ExtractLast, ExtractFirst, ExtractAny)
+	}
+
+	var re *regexp.Regexp
+	if opts.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(opts.Pattern); err != nil {
+			return nil, fmt.Errorf("некорректное регулярное выражение: %w", err)
+		}
+	}
+
+	want := formatNumber(expected)
+	if opts.Tolerance > 0 {
+		want += " ± " + formatNumber(opts.Tolerance)
+	}
+
+	return check(name, func(in Input) (bool, string) {
+		numbers := extractNumbers(in.Response, re)
+		if len(numbers) == 0 {
+			return false, "в ответе нет числа, ожидалось " + want
+		}
+
+		candidates := numbers[len(numbers)-1:]
+		switch opts.Extract {
+		case ExtractFirst:
+			candidates = numbers[:1]
+		case ExtractAny:
+			candidates = numbers
+		}
+		for _, n := range candidates {
+			if math.Abs(n-expected) <= opts.Tolerance {
+				return true, "найдено " + formatNumber(n)
+			}
+		}
+		return false, fmt.Sprintf("найдено %s, ожидалось %s", formatNumber(candidates[len(candidates)-1]), want)
+	}), nil
+}
+
+// extractNumbers числа из текста; с re - только из совпадений (первой группы, если она есть)
+func extractNumbers(text string, re *regexp.Regexp) []float64 {
+	sources := []string{text}
+	if re != nil {
+		sources = nil
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			if len(m) > 1 {
+				sources = append(sources, m[1])
+			} else {
+				sources = append(sources, m[0])
+			}
+		}
+	}
+
+	var numbers []float64
+	for _, s := range sources {
+		for _, raw := range numberPattern.FindAllString(s, -1) {
+			raw = strings.NewReplacer("−", "-", ",", ".").Replace(raw)
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	return numbers
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// ValidJSON ответ - валидный JSON
+func ValidJSON() Evaluator {
+	return newValidJSON("json")
+}
+
+func newValidJSON(name string) Evaluator {
+	return check(name, func(in Input) (bool, string) {
+		var v any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(in.Response)), &v); err != nil {
+			return false, err.Error()
+		}
+		return true, ""
+	})
+}
+
+// JSONSchema ответ - JSON, соответствующий схеме (подмножество JSON Schema, см. compileSchema)
+func JSONSchema(schema map[string]any) (Evaluator, error) {
+	return newJSONSchema("json_schema", schema)
+}
+
+func newJSONSchema(name string, raw map[string]any) (Evaluator, error) {
+	schema, err := compileSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+	return check(name, func(in Input) (bool, string) {
+		var v any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(in.Response)), &v); err != nil {
+			return false, "не JSON: " + err.Error()
+		}
+		violations := schema.validate(v, "$")
+		if len(violations) == 0 {
+			return true, ""
+		}
+		if len(violations) > 3 {
+			violations = append(violations[:3], fmt.Sprintf("и еще %d", len(violations)-3))
+		}
+		return false, strings.Join(violations, "; ")
+	}), nil
+}
+
+// Length длина ответа в символах от min до max (0 - без ограничения)
+func Length(min, max int) (Evaluator, error) {
+	return newLimit("length", "символов", min, max, utf8.RuneCountInString)
+}
+
+// WordCount число слов в ответе от min до max (0 - без ограничения)
+func WordCount(min, max int) (Evaluator, error) {
+	return newLimit("words", "слов", min, max, wordCount)
+}
+
+func wordCount(s string) int {
+	return len(strings.Fields(s))
+}
+
+func newLimit(name, unit string, min, max int, count func(string) int) (Evaluator, error) {
+	if min < 0 || max < 0 {
+		return nil, errors.New("min и max не могут быть отрицательными")
+	}
+	if max > 0 && min > max {
+		return nil, fmt.Errorf("min (%d) больше max (%d)", min, max)
+	}
+	return check(name, func(in Input) (bool, string) {
+		n := count(in.Response)
+		return n >= min && (max == 0 || n <= max), fmt.Sprintf("%d %s", n, unit)
+	}), nil
+}
+
+// StopSequences ответ не содержит стоп-последовательностей: API должен был
+// остановить генерацию на них. Без аргументов берутся Input.Stop.
+func StopSequences(stops ...string) Evaluator {
+	return newStopSequences("stop_sequences", stops)
+}
+
+func newStopSequences(name string, stops []string) Evaluator {
+	return check(name, func(in Input) (bool, string) {
+		sequences := stops
+		if len(sequences) == 0 {
+			sequences = in.Stop
+		}
+		if len(sequences) == 0 {
+			return true, "стоп-последовательности не заданы"
+		}
+
+		var found []string
+		for _, s := range sequences {
+			if s != "" && strings.Contains(in.Response, s) {
+				found = append(found, s)
+			}
+		}
+		if len(found) > 0 {
+			return false, "в ответе есть " + quoteAll(found)
+		}
+		return true, ""
+	})
+}
+
+// Forbidden ответ не содержит запрещенных фраз (сравнение после Normalize);
+// Value - доля фраз, которых в ответе нет
+func Forbidden(phrases ...string) Evaluator {
+	return newForbidden("forbidden", phrases)
+}
+
+func newForbidden(name string, phrases []string) Evaluator {
+	return &funcEvaluator{name: name, fn: func(in Input) Score {
+		if len(phrases) == 0 {
+			return passScore(true, "")
+		}
+
+		response := " " + Normalize(in.Response) + " "
+		var found []string
+		for _, p := range phrases {
+			if strings.Contains(response, " "+Normalize(p)+" ") {
+				found = append(found, p)
+			}
+		}
+
+		score := Score{
+			Value: float64(len(phrases)-len(found)) / float64(len(phrases)),
+			Pass:  len(found) == 0,
+		}
+		if len(found) > 0 {
+			score.Detail = "найдено: " + strings.Join(found, ", ")
+		}
+		return score
+	}}
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,180 @@
+package eval_test
+
+import (
+	"testing"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+)
+
+func TestNumeric(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected float64
+		opts     eval.NumericOptions
+		response string
+		pass     bool
+	}{
+		{"целое", 17, eval.NumericOptions{}, "Ответ: 17 яблок", true},
+		{"десятичная запятая", 3.5, eval.NumericOptions{}, "Получится 3,5 кг", true},
+		{"десятичная точка", 3.5, eval.NumericOptions{}, "Получится 3.5 кг", true},
+		{"знак минус", -5, eval.NumericOptions{}, "Температура −5 градусов", true},
+		{"дефис как минус", -5, eval.NumericOptions{}, "Температура -5 градусов", true},
+		{"last по умолчанию", 17, eval.NumericOptions{}, "15 - 5 = 10, 10 + 7 = 17", true},
+		{"last не видит первое", 15, eval.NumericOptions{}, "15 - 5 = 10, 10 + 7 = 17", false},
+		{"first", 15, eval.NumericOptions{Extract: eval.ExtractFirst}, "15 - 5 = 10, 10 + 7 = 17", true},
+		{"first не видит последнее", 17, eval.NumericOptions{Extract: eval.ExtractFirst}, "15 - 5 = 10, 10 + 7 = 17", false},
+		{"any", 10, eval.NumericOptions{Extract: eval.ExtractAny}, "15 - 5 = 10, 10 + 7 = 17", true},
+		{"any без совпадений", 11, eval.NumericOptions{Extract: eval.ExtractAny}, "15 - 5 = 10, 10 + 7 = 17", false},
+		{"допуск", 3.1416, eval.NumericOptions{Tolerance: 0.01}, "Пи примерно 3.14", true},
+		{"вне допуска", 3.1416, eval.NumericOptions{Tolerance: 0.001}, "Пи примерно 3.14", false},
+		{"шаблон", 17, eval.NumericOptions{Pattern: `Ответ: (\d+)`}, "Ответ: 17, проверка: 17 - 7 = 10", true},
+		{"нет числа", 17, eval.NumericOptions{}, "Семнадцать", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := eval.Numeric(tt.expected, tt.opts)
+			if err != nil {
+				t.Fatalf("Numeric: %v", err)
+			}
+			score, err := eval.EvaluateText(check, tt.response)
+			if err != nil {
+				t.Fatalf("EvaluateText: %v", err)
+			}
+			if score.Pass != tt.pass {
+				t.Errorf("Pass = %v, ожидалось %v (%s)", score.Pass, tt.pass, score.Detail)
+			}
+		})
+	}
+}
+
+func TestNumericOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts eval.NumericOptions
+	}{
+		{"отрицательный допуск", eval.NumericOptions{Tolerance: -1}},
+		{"неизвестный способ", eval.NumericOptions{Extract: "middle"}},
+		{"некорректный шаблон", eval.NumericOptions{Pattern: "("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := eval.Numeric(1, tt.opts); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"name", "age", "tags"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"name":   map[string]any{"type": "string", "minLength": 1, "pattern": "^[А-ЯA-Z]"},
+			"age":    map[string]any{"type": "integer", "minimum": 0, "maximum": 150},
+			"status": map[string]any{"enum": []any{"active", "blocked"}},
+			"tags": map[string]any{
+				"type":     "array",
+				"minItems": 1,
+				"maxItems": 3,
+				"items":    map[string]any{"type": "string"},
+			},
+		},
+	}
+	check, err := eval.JSONSchema(schema)
+	if err != nil {
+		t.Fatalf("JSONSchema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		response string
+		pass     bool
+	}{
+		{"соответствует", `{"name": "Анна", "age": 30, "tags": ["go"], "status": "active"}`, true},
+		{"пробелы вокруг", "\n  {\"name\": \"Анна\", \"age\": 30, \"tags\": [\"go\"]}  \n", true},
+		{"не JSON", `name: Анна`, false},
+		{"нет обязательного поля", `{"name": "Анна", "tags": ["go"]}`, false},
+		{"лишнее поле", `{"name": "Анна", "age": 30, "tags": ["go"], "city": "Москва"}`, false},
+		{"дробное вместо integer", `{"name": "Анна", "age": 30.5, "tags": ["go"]}`, false},
+		{"больше maximum", `{"name": "Анна", "age": 200, "tags": ["go"]}`, false},
+		{"не по pattern", `{"name": "анна", "age": 30, "tags": ["go"]}`, false},
+		{"пустая строка", `{"name": "", "age": 30, "tags": ["go"]}`, false},
+		{"не из enum", `{"name": "Анна", "age": 30, "tags": ["go"], "status": "deleted"}`, false},
+		{"пустой массив", `{"name": "Анна", "age": 30, "tags": []}`, false},
+		{"много элементов", `{"name": "Анна", "age": 30, "tags": ["a", "b", "c", "d"]}`, false},
+		{"элемент другого типа", `{"name": "Анна", "age": 30, "tags": [1]}`, false},
+		{"массив вместо объекта", `[]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := eval.EvaluateText(check, tt.response)
+			if err != nil {
+				t.Fatalf("EvaluateText: %v", err)
+			}
+			if score.Pass != tt.pass {
+				t.Errorf("Pass = %v, ожидалось %v (%s)", score.Pass, tt.pass, score.Detail)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]any
+	}{
+		{"неподдерживаемое слово", map[string]any{"oneOf": []any{}}},
+		{"неизвестный тип", map[string]any{"type": "date"}},
+		{"пустой enum", map[string]any{"enum": []any{}}},
+		{"некорректный pattern", map[string]any{"type": "string", "pattern": "("}},
+		{"properties не объект", map[string]any{"properties": "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := eval.JSONSchema(tt.schema); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		name     string
+		phrases  []string
+		response string
+		pass     bool
+		value    float64
+	}{
+		{"фразы нет", []string{"как ИИ"}, "Вот ответ на вопрос.", true, 1},
+		{"фраза есть", []string{"как ИИ"}, "Я, как ии, не могу ответить.", false, 0},
+		{"регистр и знаки препинания", []string{"Не могу"}, "НЕ, МОГУ!", false, 0},
+		{"ё и е", []string{"еще раз"}, "Попробуйте ещё раз", false, 0},
+		{"часть слова не считается", []string{"кот"}, "Который час?", true, 1},
+		{"фраза внутри слов не считается", []string{"как и"}, "Такая история", true, 1},
+		{"в начале и в конце", []string{"привет", "пока"}, "Привет и пока", false, 0},
+		{"доля найденных", []string{"привет", "извините"}, "Привет!", false, 0.5},
+		{"без фраз", nil, "Что угодно", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := eval.EvaluateText(eval.Forbidden(tt.phrases...), tt.response)
+			if err != nil {
+				t.Fatalf("EvaluateText: %v", err)
+			}
+			if score.Pass != tt.pass {
+				t.Errorf("Pass = %v, ожидалось %v (%s)", score.Pass, tt.pass, score.Detail)
+			}
+			if score.Value != tt.value {
+				t.Errorf("Value = %v, ожидалось %v", score.Value, tt.value)
+			}
+		})
+	}
+}
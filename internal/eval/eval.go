@@ -0,0 +1,269 @@
+// Package eval - оценка ответов модели: проверки, которые эксперимент
+// применяет к каждому ответу и сохраняет рядом с результатом
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/river"
+)
+
+// Input ответ модели с контекстом запроса
+type Input struct {
+	Prompt       string
+	Response     string
+	FinishReason string
+	Stop         []string // Стоп-последовательности запроса (stop_sequences)
+}
+
+// Score результат одной проверки
+type Score struct {
+	Evaluator string  `json:"evaluator"`
+	Value     float64 `json:"value"` // 0..1
+	Pass      bool    `json:"pass"`
+	Detail    string  `json:"detail,omitempty"`
+
+	// Criteria оценки судьи по критериям рубрики (judge)
+	Criteria []CriterionScore `json:"criteria,omitempty"`
+
+	// Usage расходы на запросы проверки (judge; nil - проверка без запросов).
+	// Возвращается и вместе с ошибкой проверки.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Evaluator проверка ответа модели
+type Evaluator interface {
+	Name() string
+	Evaluate(ctx context.Context, in Input) (Score, error)
+}
+
+// Spec описание проверки в спецификации эксперимента.
+// Какие поля используются, зависит от Type.
+type Spec struct {
+	Name       string   `yaml:"name"`        // Имя в результатах (пусто - Type)
+	Type       string   `yaml:"type"`        // Тип проверки, см. Types
+	Value      string   `yaml:"value"`       // Подстрока, ожидаемый ответ или число (numeric)
+	Values     []string `yaml:"values"`      // Несколько подстрок, ответов, фраз или стоп-последовательностей
+	Pattern    string   `yaml:"pattern"`     // Регулярное выражение (regex; numeric - где искать число)
+	Min        int      `yaml:"min"`         // Минимум слов (words) или символов (length)
+	Max        int      `yaml:"max"`         // Максимум слов или символов (0 - без ограничения)
+	IgnoreCase bool     `yaml:"ignore_case"` // Сравнение без учета регистра (contains, not_contains, regex)
+
+	Tolerance float64        `yaml:"tolerance"` // Допуск (numeric)
+	Extract   string         `yaml:"extract"`   // Какое число из ответа сравнивать: last, first, any (numeric)
+	Schema    map[string]any `yaml:"schema"`    // JSON Schema ответа (json_schema)
+
+	Model     string  `yaml:"model"`     // Модель судьи (judge, пусто - из Options)
+	Criteria  Rubric  `yaml:"criteria"`  // Рубрика (judge, пусто - DefaultRubric)
+	Reference string  `yaml:"reference"` // Эталонный ответ для судьи (judge)
+	Threshold float64 `yaml:"threshold"` // Оценка 1-10 для прохождения (judge, 0 - DefaultThreshold)
+}
+
+// Options зависимости проверок, которые обращаются к API
+type Options struct {
+	Client     *client.OpenAIClient // Клиент судьи (nil - проверка judge возвращает ошибку при оценке)
+	JudgeModel string               // Модель судьи по умолчанию (пусто - модель клиента)
+	Prompts    *prompts.Library     // Библиотека с шаблонами судьи (nil - встроенная)
+}
+
+// Types поддерживаемые типы проверок
+var Types = []string{
+	"contains", "not_contains", "exact", "normalized", "regex", "numeric", "json", "json_schema",
+	"words", "length", "stop_sequences", "forbidden", "finish_reason", "river_crossing", "judge",
+}
+
+// New создает проверку по описанию. Без opts.Client проверку judge можно
+// создать (например, чтобы проверить спецификацию), но не выполнить.
+func New(spec Spec, opts Options) (Evaluator, error) {
+	name := spec.Name
+	if name == "" {
+		name = spec.Type
+	}
+
+	e, err := newEvaluator(name, spec, opts)
+	if err != nil {
+		return nil, fmt.Errorf("проверка %s: %w", name, err)
+	}
+	return e, nil
+}
+
+func newEvaluator(name string, spec Spec, opts Options) (Evaluator, error) {
+	values := spec.Values
+	if spec.Value != "" {
+		values = append([]string{spec.Value}, values...)
+	}
+
+	switch spec.Type {
+	case "contains", "not_contains":
+		if len(values) == 0 {
+			return nil, errors.New("не задано value или values")
+		}
+		return newContains(name, values, spec.IgnoreCase, spec.Type == "not_contains"), nil
+
+	case "exact", "normalized":
+		if len(values) == 0 {
+			return nil, errors.New("не задано value или values")
+		}
+		normalize := strings.TrimSpace
+		if spec.Type == "normalized" {
+			normalize = Normalize
+		}
+		return newMatch(name, values, normalize), nil
+
+	case "regex":
+		pattern := spec.Pattern
+		if spec.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		return newRegex(name, pattern)
+
+	case "numeric":
+		expected, err := strconv.ParseFloat(strings.TrimSpace(spec.Value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("value: ожидалось число, получено %q", spec.Value)
+		}
+		return newNumeric(name, expected, NumericOptions{
+			Tolerance: spec.Tolerance,
+			Extract:   spec.Extract,
+			Pattern:   spec.Pattern,
+		})
+
+	case "json":
+		return newValidJSON(name), nil
+
+	case "json_schema":
+		if len(spec.Schema) == 0 {
+			return nil, errors.New("не задана schema")
+		}
+		return newJSONSchema(name, spec.Schema)
+
+	case "words":
+		return newLimit(name, "слов", spec.Min, spec.Max, wordCount)
+
+	case "length":
+		if spec.Min == 0 && spec.Max == 0 {
+			return nil, errors.New("не задано min или max")
+		}
+		return newLimit(name, "символов", spec.Min, spec.Max, utf8.RuneCountInString)
+
+	case "stop_sequences":
+		return newStopSequences(name, values), nil
+
+	case "forbidden":
+		if len(values) == 0 {
+			return nil, errors.New("не задано value или values")
+		}
+		return newForbidden(name, values), nil
+
+	case "finish_reason":
+		if spec.Value == "" {
+			return nil, errors.New("не задано value")
+		}
+		return check(name, func(in Input) (bool, string) {
+			return in.FinishReason == spec.Value, "finish_reason " + in.FinishReason
+		}), nil
+
+	case "river_crossing":
+		return check(name, func(in Input) (bool, string) {
+			verdict := river.Verify(river.ParseMoves(in.Response))
+			return verdict.Correct(), verdict.String()
+		}), nil
+
+	case "judge":
+		threshold := spec.Threshold
+		if threshold == 0 {
+			threshold = DefaultThreshold
+		}
+		if threshold < MinGrade || threshold > MaxGrade {
+			return nil, fmt.Errorf("threshold %v вне шкалы %d-%d", threshold, MinGrade, MaxGrade)
+		}
+		model := spec.Model
+		if model == "" {
+			model = opts.JudgeModel
+		}
+		judge, err := NewJudge(opts.Client, model, spec.Criteria)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Prompts != nil {
+			judge.SetPrompts(opts.Prompts)
+		}
+		return &judgeEvaluator{name: name, judge: judge, reference: spec.Reference, threshold: threshold}, nil
+	}
+
+	return nil, fmt.Errorf("неизвестный тип %q (допустимо: %s)", spec.Type, strings.Join(Types, ", "))
+}
+
+// funcEvaluator проверка без обращения к API
+type funcEvaluator struct {
+	name string
+	fn   func(in Input) Score
+}
+
+func (e *funcEvaluator) Name() string {
+	return e.name
+}
+
+func (e *funcEvaluator) Evaluate(_ context.Context, in Input) (Score, error) {
+	score := e.fn(in)
+	score.Evaluator = e.name
+	return score, nil
+}
+
+// check создает проверку "прошел / не прошел" (Value 1 или 0)
+func check(name string, fn func(in Input) (bool, string)) Evaluator {
+	return &funcEvaluator{name: name, fn: func(in Input) Score {
+		pass, detail := fn(in)
+		return passScore(pass, detail)
+	}}
+}
+
+// newContains проверяет наличие (или отсутствие) подстрок;
+// Value - доля подстрок, для которых условие выполнено
+func newContains(name string, values []string, ignoreCase, negate bool) Evaluator {
+	return &funcEvaluator{name: name, fn: func(in Input) Score {
+		response := in.Response
+		if ignoreCase {
+			response = strings.ToLower(response)
+		}
+
+		var failed []string
+		for _, v := range values {
+			needle := v
+			if ignoreCase {
+				needle = strings.ToLower(v)
+			}
+			if strings.Contains(response, needle) == negate {
+				failed = append(failed, v)
+			}
+		}
+
+		score := Score{
+			Value: float64(len(values)-len(failed)) / float64(len(values)),
+			Pass:  len(failed) == 0,
+		}
+		if len(failed) > 0 {
+			verb := "не найдено"
+			if negate {
+				verb = "найдено"
+			}
+			score.Detail = fmt.Sprintf("%s: %s", verb, strings.Join(failed, ", "))
+		}
+		return score
+	}}
+}
+
+func passScore(pass bool, detail string) Score {
+	score := Score{Pass: pass, Detail: detail}
+	if pass {
+		score.Value = 1
+	}
+	return score
+}
@@ -0,0 +1,348 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Criterion критерий рубрики судьи
+type Criterion struct {
+	Name        string  `yaml:"name" json:"name"`
+	Description string  `yaml:"description" json:"description"`
+	Weight      float64 `yaml:"weight" json:"weight"` // Вес в итоговой оценке (0 - как 1)
+}
+
+// Rubric критерии, по которым судья оценивает ответ
+type Rubric []Criterion
+
+// DefaultRubric рубрика для проверки judge без criteria
+var DefaultRubric = Rubric{
+	{Name: "correctness", Description: "ответ верен по существу, без фактических и логических ошибок", Weight: 2},
+	{Name: "completeness", Description: "ответ полностью выполняет задание", Weight: 1},
+	{Name: "clarity", Description: "ответ понятен, хорошо структурирован и не содержит лишнего", Weight: 1},
+}
+
+// Validate проверяет, что у критериев есть уникальные имена и неотрицательные веса
+func (r Rubric) Validate() error {
+	if len(r) == 0 {
+		return errors.New("пустая рубрика")
+	}
+	seen := make(map[string]bool)
+	for i, c := range r {
+		if c.Name == "" {
+			return fmt.Errorf("criteria[%d]: не задано имя", i)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("criteria[%d]: повторяется критерий %q", i, c.Name)
+		}
+		seen[c.Name] = true
+		if c.Weight < 0 {
+			return fmt.Errorf("criteria[%d]: отрицательный вес %v", i, c.Weight)
+		}
+	}
+	return nil
+}
+
+// weight вес критерия (по умолчанию 1)
+func (c Criterion) weight() float64 {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// CriterionScore оценка ответа по одному критерию
+type CriterionScore struct {
+	Name          string `json:"name"`
+	Score         int    `json:"score"` // 1..10
+	Justification string `json:"justification"`
+}
+
+// Grade оценка ответа судьей
+type Grade struct {
+	Score         float64          `json:"score"` // Взвешенная оценка 1..10
+	Criteria      []CriterionScore `json:"criteria"`
+	Justification string           `json:"justification"`
+	Usage         Usage            `json:"usage"` // Расходы на запрос судьи
+}
+
+// Usage расходы на запросы судьи
+type Usage struct {
+	Requests    int     `json:"requests"`
+	TotalTokens int     `json:"total_tokens"`
+	Cost        float64 `json:"cost_usd"` // 0 - модели судьи нет в каталоге
+}
+
+// Add добавляет расходы other
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
+
+// String возвращает расходы одной строкой: "запросов: 2, токенов: 350, $0.000120"
+func (u Usage) String() string {
+	return fmt.Sprintf("запросов: %d, токенов: %d, $%.6f", u.Requests, u.TotalTokens, u.Cost)
+}
+
+// add учитывает ответ судьи
+func (u *Usage) add(resp *client.CompletionResponse) {
+	u.Requests++
+	u.TotalTokens += resp.TotalTokens
+	u.Cost += resp.Cost
+}
+
+// Шкала оценок судьи
+const (
+	MinGrade = 1
+	MaxGrade = 10
+)
+
+// DefaultThreshold взвешенная оценка, с которой проверка judge считается пройденной
+const DefaultThreshold = 7
+
+// Judge LLM-судья: оценивает ответы по рубрике и сравнивает пары ответов.
+// Ответ судьи запрашивается в формате JSON.
+type Judge struct {
+	client  *client.OpenAIClient
+	model   string // Пусто - модель клиента
+	rubric  Rubric
+	prompts *prompts.Library // Шаблоны judge/grade и judge/compare
+}
+
+// Шаблоны промптов судьи во встроенной библиотеке
+const (
+	gradeTemplate   = "judge/grade@1"
+	compareTemplate = "judge/compare@1"
+)
+
+// NewJudge создает судью с моделью model (пусто - модель клиента)
+// и рубрикой rubric (пусто - DefaultRubric)
+func NewJudge(c *client.OpenAIClient, model string, rubric Rubric) (*Judge, error) {
+	if len(rubric) == 0 {
+		rubric = DefaultRubric
+	}
+	if err := rubric.Validate(); err != nil {
+		return nil, err
+	}
+	return &Judge{client: c, model: model, rubric: rubric, prompts: prompts.Default()}, nil
+}
+
+// SetPrompts задает библиотеку с шаблонами судьи (по умолчанию - встроенная)
+func (j *Judge) SetPrompts(lib *prompts.Library) {
+	j.prompts = lib
+}
+
+// Rubric возвращает критерии судьи
+func (j *Judge) Rubric() Rubric {
+	return j.rubric
+}
+
+// Grade оценивает ответ на prompt по рубрике; reference - эталонный ответ (пусто - без эталона).
+// Расходы на запрос судьи возвращаются в Grade.Usage и при ошибке разбора ответа.
+func (j *Judge) Grade(ctx context.Context, prompt, response, reference string) (Grade, error) {
+	var parsed struct {
+		Criteria      []CriterionScore `json:"criteria"`
+		Justification string           `json:"justification"`
+	}
+	var spent Usage
+	vars := map[string]any{"task": prompt, "reference": reference, "response": response, "criteria": j.criteriaList()}
+	if err := j.ask(ctx, gradeTemplate, vars, &parsed, &spent); err != nil {
+		return Grade{Usage: spent}, err
+	}
+
+	byName := make(map[string]CriterionScore, len(parsed.Criteria))
+	for _, c := range parsed.Criteria {
+		if c.Score < MinGrade || c.Score > MaxGrade {
+			return Grade{Usage: spent}, fmt.Errorf("судья: оценка %d по критерию %q вне шкалы %d-%d", c.Score, c.Name, MinGrade, MaxGrade)
+		}
+		byName[c.Name] = c
+	}
+
+	grade := Grade{Justification: parsed.Justification, Usage: spent}
+	var sum, weights float64
+	for _, c := range j.rubric {
+		score, ok := byName[c.Name]
+		if !ok {
+			return Grade{Usage: spent}, fmt.Errorf("судья не оценил критерий %q", c.Name)
+		}
+		grade.Criteria = append(grade.Criteria, score)
+		sum += float64(score.Score) * c.weight()
+		weights += c.weight()
+	}
+	grade.Score = math.Round(sum/weights*100) / 100
+	return grade, nil
+}
+
+// Исход попарного сравнения
+const (
+	WinnerA   = "A"
+	WinnerB   = "B"
+	WinnerTie = "tie"
+)
+
+// PairwiseRound одно сравнение пары; Winner и оценки - в исходных обозначениях A и B
+type PairwiseRound struct {
+	Swapped       bool    `json:"swapped"` // Ответы показаны судье в обратном порядке
+	Winner        string  `json:"winner"`
+	ScoreA        float64 `json:"score_a"`
+	ScoreB        float64 `json:"score_b"`
+	Justification string  `json:"justification"`
+}
+
+// Comparison итог попарного сравнения в обоих порядках. Если порядки
+// дали разных победителей, итог - ничья: предпочтение вызвано позицией.
+type Comparison struct {
+	Winner     string          `json:"winner"`
+	ScoreA     float64         `json:"score_a"` // Средняя оценка 1..10
+	ScoreB     float64         `json:"score_b"`
+	Consistent bool            `json:"consistent"` // Оба порядка дали одного победителя
+	Rounds     []PairwiseRound `json:"rounds"`
+	Usage      Usage           `json:"usage"` // Расходы на оба запроса судьи
+}
+
+// Compare сравнивает ответы a и b на prompt. Судья видит пару дважды - в прямом
+// и обратном порядке, чтобы позиция ответа не влияла на итог. Расходы на запросы
+// судьи возвращаются в Comparison.Usage и при ошибке.
+func (j *Judge) Compare(ctx context.Context, prompt, a, b string) (Comparison, error) {
+	var cmp Comparison
+	for _, swapped := range []bool{false, true} {
+		first, second := a, b
+		if swapped {
+			first, second = b, a
+		}
+
+		var parsed struct {
+			Score1        float64 `json:"score_1"`
+			Score2        float64 `json:"score_2"`
+			Winner        string  `json:"winner"`
+			Justification string  `json:"justification"`
+		}
+		vars := map[string]any{"task": prompt, "first": first, "second": second, "criteria": j.criteriaList()}
+		if err := j.ask(ctx, compareTemplate, vars, &parsed, &cmp.Usage); err != nil {
+			return Comparison{Usage: cmp.Usage}, err
+		}
+		for _, score := range []float64{parsed.Score1, parsed.Score2} {
+			if score < MinGrade || score > MaxGrade {
+				return Comparison{Usage: cmp.Usage}, fmt.Errorf("судья: оценка %v вне шкалы %d-%d", score, MinGrade, MaxGrade)
+			}
+		}
+
+		round := PairwiseRound{
+			Swapped:       swapped,
+			ScoreA:        parsed.Score1,
+			ScoreB:        parsed.Score2,
+			Justification: parsed.Justification,
+		}
+		switch strings.TrimSpace(parsed.Winner) {
+		case "1":
+			round.Winner = WinnerA
+		case "2":
+			round.Winner = WinnerB
+		case WinnerTie:
+			round.Winner = WinnerTie
+		default:
+			return Comparison{Usage: cmp.Usage}, fmt.Errorf("судья: неизвестный победитель %q (ожидалось 1, 2 или tie)", parsed.Winner)
+		}
+		if swapped {
+			round.ScoreA, round.ScoreB = round.ScoreB, round.ScoreA
+			round.Winner = swapWinner(round.Winner)
+		}
+		cmp.Rounds = append(cmp.Rounds, round)
+	}
+
+	first, second := cmp.Rounds[0], cmp.Rounds[1]
+	cmp.ScoreA = (first.ScoreA + second.ScoreA) / 2
+	cmp.ScoreB = (first.ScoreB + second.ScoreB) / 2
+	cmp.Consistent = first.Winner == second.Winner
+	cmp.Winner = WinnerTie
+	if cmp.Consistent {
+		cmp.Winner = first.Winner
+	}
+	return cmp, nil
+}
+
+func swapWinner(w string) string {
+	switch w {
+	case WinnerA:
+		return WinnerB
+	case WinnerB:
+		return WinnerA
+	}
+	return w
+}
+
+// criteriaList критерии рубрики списком для промпта
+func (j *Judge) criteriaList() string {
+	var b strings.Builder
+	for _, c := range j.rubric {
+		fmt.Fprintf(&b, "- %s: %s (вес %v)\n", c.Name, c.Description, c.weight())
+	}
+	return b.String()
+}
+
+// ask отправляет судье промпт по шаблону ref, учитывает расходы в spent
+// и разбирает JSON-ответ в v
+func (j *Judge) ask(ctx context.Context, ref string, vars map[string]any, v any, spent *Usage) error {
+	prompt, err := j.prompts.Render(ref, vars)
+	if err != nil {
+		return fmt.Errorf("судья: %w", err)
+	}
+
+	resp, err := j.client.CreateCompletionContext(ctx, client.CompletionRequest{
+		Model:       j.model,
+		Prompt:      prompt.Text,
+		Templates:   []string{prompt.String()},
+		MaxTokens:   600,
+		Temperature: client.Temperature(0), // Оценка должна быть воспроизводимой
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	spent.add(resp)
+	if err := json.Unmarshal([]byte(resp.Content), v); err != nil {
+		return fmt.Errorf("ответ судьи не JSON: %w", err)
+	}
+	return nil
+}
+
+// judgeEvaluator проверка judge: оценка судьи, деленная на 10
+type judgeEvaluator struct {
+	name      string
+	judge     *Judge
+	reference string
+	threshold float64
+}
+
+func (e *judgeEvaluator) Name() string {
+	return e.name
+}
+
+func (e *judgeEvaluator) Evaluate(ctx context.Context, in Input) (Score, error) {
+	if e.judge.client == nil {
+		return Score{}, errors.New("судья не настроен: нет клиента API")
+	}
+	grade, err := e.judge.Grade(ctx, in.Prompt, in.Response, e.reference)
+	if err != nil {
+		return Score{Evaluator: e.name, Usage: &grade.Usage}, err
+	}
+	return Score{
+		Evaluator: e.name,
+		Value:     grade.Score / MaxGrade,
+		Pass:      grade.Score >= e.threshold,
+		Detail:    fmt.Sprintf("%.1f/10: %s", grade.Score, grade.Justification),
+		Criteria:  grade.Criteria,
+		Usage:     &grade.Usage,
+	}, nil
+}
@@ -0,0 +1,293 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema скомпилированная схема. Поддерживается подмножество JSON Schema:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum, maximum.
+type jsonSchema struct {
+	types      []string
+	enum       []any
+	properties map[string]*jsonSchema
+	required   []string
+	closed     bool        // additionalProperties: false
+	additional *jsonSchema // additionalProperties: схема
+	items      *jsonSchema
+	minItems   *int
+	maxItems   *int
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	minimum    *float64
+	maximum    *float64
+}
+
+// schemaTypes допустимые значения type
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// annotations ключевые слова, которые не влияют на проверку
+var annotations = map[string]bool{"$schema": true, "$id": true, "title": true, "description": true, "examples": true}
+
+// compileSchema проверяет схему и готовит ее к проверке ответов.
+// Неподдерживаемые ключевые слова - ошибка, чтобы ограничение не игнорировалось молча.
+func compileSchema(raw map[string]any) (*jsonSchema, error) {
+	// Схема из YAML содержит int, из JSON - float64: приводим к виду json.Unmarshal
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("схема: %w", err)
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("схема: %w", err)
+	}
+	return compileNode(normalized, "schema")
+}
+
+func compileNode(raw map[string]any, path string) (*jsonSchema, error) {
+	s := &jsonSchema{}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := raw[key]
+		at := path + "." + key
+		var err error
+		switch key {
+		case "type":
+			s.types, err = schemaTypeList(value, at)
+		case "enum":
+			list, ok := value.([]any)
+			if !ok || len(list) == 0 {
+				return nil, fmt.Errorf("%s: ожидался непустой список", at)
+			}
+			s.enum = list
+		case "const":
+			s.enum = []any{value}
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: ожидался объект", at)
+			}
+			s.properties = make(map[string]*jsonSchema, len(props))
+			for name, p := range props {
+				if s.properties[name], err = subschema(p, at+"."+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = stringList(value, at)
+		case "additionalProperties":
+			if b, ok := value.(bool); ok {
+				s.closed = !b
+			} else {
+				s.additional, err = subschema(value, at)
+			}
+		case "items":
+			s.items, err = subschema(value, at)
+		case "minItems":
+			s.minItems, err = schemaInt(value, at)
+		case "maxItems":
+			s.maxItems, err = schemaInt(value, at)
+		case "minLength":
+			s.minLength, err = schemaInt(value, at)
+		case "maxLength":
+			s.maxLength, err = schemaInt(value, at)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: ожидалась строка", at)
+			}
+			if s.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("%s: %w", at, err)
+			}
+		case "minimum":
+			s.minimum, err = schemaNumber(value, at)
+		case "maximum":
+			s.maximum, err = schemaNumber(value, at)
+		default:
+			if !annotations[key] {
+				return nil, fmt.Errorf("%s: неподдерживаемое ключевое слово", at)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func subschema(value any, path string) (*jsonSchema, error) {
+	raw, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: ожидалась схема (объект)", path)
+	}
+	return compileNode(raw, path)
+}
+
+func schemaTypeList(value any, path string) ([]string, error) {
+	var types []string
+	if t, ok := value.(string); ok {
+		types = []string{t}
+	} else {
+		var err error
+		if types, err = stringList(value, path); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range types {
+		if !slices.Contains(schemaTypes, t) {
+			return nil, fmt.Errorf("%s: неизвестный тип %q (допустимо: %s)", path, t, strings.Join(schemaTypes, ", "))
+		}
+	}
+	return types, nil
+}
+
+func stringList(value any, path string) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: ожидался список строк", path)
+	}
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: ожидался список строк", path)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func schemaInt(value any, path string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("%s: ожидалось неотрицательное целое", path)
+	}
+	i := int(n)
+	return &i, nil
+}
+
+func schemaNumber(value any, path string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: ожидалось число", path)
+	}
+	return &n, nil
+}
+
+// validate возвращает нарушения схемы; path - путь к значению вида $.types[0]
+func (s *jsonSchema) validate(v any, path string) []string {
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(v, t) }) {
+		return []string{fmt.Sprintf("%s: ожидался %s, получено %s", path, strings.Join(s.types, " или "), typeOf(v))}
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return []string{fmt.Sprintf("%s: значение не из списка допустимых", path)}
+	}
+
+	var violations []string
+	add := func(format string, args ...any) {
+		violations = append(violations, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				add("нет обязательного поля %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			at := path + "." + name
+			if prop, ok := s.properties[name]; ok {
+				violations = append(violations, prop.validate(v[name], at)...)
+			} else if s.additional != nil {
+				violations = append(violations, s.additional.validate(v[name], at)...)
+			} else if s.closed {
+				add("лишнее поле %q", name)
+			}
+		}
+
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			add("элементов %d, минимум %d", len(v), *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			add("элементов %d, максимум %d", len(v), *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				violations = append(violations, s.items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			add("длина %d, минимум %d", n, *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			add("длина %d, максимум %d", n, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			add("нет совпадения с %s", s.pattern)
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			add("%v меньше минимума %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			add("%v больше максимума %v", v, *s.maximum)
+		}
+	}
+	return violations
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	}
+	return typeOf(v) == t
+}
+
+// typeOf тип JSON-значения из json.Unmarshal
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
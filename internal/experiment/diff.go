@@ -0,0 +1,172 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+)
+
+// DiffOptions пороги сравнения запусков
+type DiffOptions struct {
+	ScoreDrop    float64 // Падение среднего значения проверки, считающееся регрессией (0.05 - на 5 п.п.)
+	ChangeRatio  float64 // Относительное изменение стоимости и времени, которое выделяется (0.2 - на 20%)
+	MinLatencyMs float64 // Изменения времени меньше этого не выделяются (шум сети)
+}
+
+// DefaultDiffOptions пороги сравнения по умолчанию
+var DefaultDiffOptions = DiffOptions{ScoreDrop: 0.05, ChangeRatio: 0.2, MinLatencyMs: 100}
+
+// ScoreChange изменение среднего значения проверки
+type ScoreChange struct {
+	Evaluator string   `json:"evaluator"`
+	Old       *float64 `json:"old,omitempty"` // nil - проверки не было
+	New       *float64 `json:"new,omitempty"`
+}
+
+// Delta возвращает изменение значения (0, если проверки нет в одном из запусков)
+func (c ScoreChange) Delta() float64 {
+	if c.Old == nil || c.New == nil {
+		return 0
+	}
+	return *c.New - *c.Old
+}
+
+// CellDiff сравнение одной ячейки (промпт, модель и параметры) в двух запусках
+type CellDiff struct {
+	Cell
+	Old *Summary `json:"old,omitempty"` // nil - ячейка появилась в новом запуске
+	New *Summary `json:"new,omitempty"` // nil - ячейки нет в новом запуске
+
+	Scores      []ScoreChange `json:"scores,omitempty"`
+	Regressions []string      `json:"regressions,omitempty"` // Ухудшения качества
+	CostChange  float64       `json:"cost_change"`           // Относительное изменение стоимости (0.1 - на 10% дороже)
+	Latency     float64       `json:"latency_change"`        // Относительное изменение среднего времени
+	CostFlag    bool          `json:"cost_flag,omitempty"`   // Изменение стоимости выше порога
+	LatencyFlag bool          `json:"latency_flag,omitempty"`
+}
+
+// Diff сравнение двух запусков эксперимента
+type Diff struct {
+	Old      Run        `json:"old"`
+	New      Run        `json:"new"`
+	SameSpec bool       `json:"same_spec"` // Хэши спецификаций совпадают
+	Cells    []CellDiff `json:"cells"`
+
+	Regressions int     `json:"regressions"` // Ячеек с ухудшением качества
+	CostChange  float64 `json:"cost_change"` // Относительное изменение общей стоимости
+}
+
+// Compare сравнивает результаты двух запусков по ячейкам без учета повторов.
+// Ячейки сопоставляются по параметрам (Cell.Key), порядок - как в новом запуске,
+// затем исчезнувшие ячейки старого.
+func Compare(oldRun, newRun Run, oldResults, newResults []Result, opts DiffOptions) Diff {
+	d := Diff{
+		Old:      oldRun,
+		New:      newRun,
+		SameSpec: oldRun.SpecHash != "" && oldRun.SpecHash == newRun.SpecHash,
+	}
+
+	oldSummary := Summarize(oldResults)
+	oldByKey := make(map[string]*Summary, len(oldSummary))
+	for i := range oldSummary {
+		oldByKey[oldSummary[i].Key()] = &oldSummary[i]
+	}
+
+	newSummary := Summarize(newResults)
+	seen := make(map[string]bool, len(newSummary))
+	for i := range newSummary {
+		s := &newSummary[i]
+		seen[s.Key()] = true
+		d.Cells = append(d.Cells, compareCell(oldByKey[s.Key()], s, opts))
+	}
+	for i := range oldSummary {
+		if s := &oldSummary[i]; !seen[s.Key()] {
+			d.Cells = append(d.Cells, compareCell(s, nil, opts))
+		}
+	}
+
+	for _, c := range d.Cells {
+		if len(c.Regressions) > 0 {
+			d.Regressions++
+		}
+	}
+	d.CostChange = relativeChange(totalCost(oldResults), totalCost(newResults))
+	return d
+}
+
+// compareCell сравнивает сводки ячейки; old или new может быть nil
+func compareCell(old, new *Summary, opts DiffOptions) CellDiff {
+	c := CellDiff{Old: old, New: new}
+	if new != nil {
+		c.Cell = new.Cell
+	} else {
+		c.Cell = old.Cell
+	}
+	if old == nil || new == nil {
+		return c
+	}
+
+	c.Scores = compareScores(old, new)
+	if oldRate, newRate := passRate(old), passRate(new); newRate < oldRate-opts.ScoreDrop {
+		c.Regressions = append(c.Regressions, fmt.Sprintf("успешных %.0f%% → %.0f%%", oldRate*100, newRate*100))
+	}
+	if oldRate, newRate := errorRate(old), errorRate(new); newRate > oldRate {
+		c.Regressions = append(c.Regressions, fmt.Sprintf("ошибок %.0f%% → %.0f%%", oldRate*100, newRate*100))
+	}
+	for _, s := range c.Scores {
+		switch {
+		case s.Old != nil && s.New == nil:
+			c.Regressions = append(c.Regressions, fmt.Sprintf("нет проверки %s", s.Evaluator))
+		case s.Delta() < -opts.ScoreDrop:
+			c.Regressions = append(c.Regressions, fmt.Sprintf("%s %.2f → %.2f", s.Evaluator, *s.Old, *s.New))
+		}
+	}
+
+	c.CostChange = relativeChange(old.TotalCost/float64(old.Runs), new.TotalCost/float64(new.Runs))
+	c.CostFlag = math.Abs(c.CostChange) > opts.ChangeRatio
+	c.Latency = relativeChange(old.AvgLatencyMs, new.AvgLatencyMs)
+	c.LatencyFlag = math.Abs(c.Latency) > opts.ChangeRatio &&
+		math.Abs(new.AvgLatencyMs-old.AvgLatencyMs) >= opts.MinLatencyMs
+	return c
+}
+
+// compareScores сопоставляет средние значения проверок по имени
+func compareScores(old, new *Summary) []ScoreChange {
+	var changes []ScoreChange
+	index := make(map[string]int)
+	for _, s := range new.Scores {
+		index[s.Evaluator] = len(changes)
+		changes = append(changes, ScoreChange{Evaluator: s.Evaluator, New: &s.Value})
+	}
+	for _, s := range old.Scores {
+		if i, ok := index[s.Evaluator]; ok {
+			changes[i].Old = &s.Value
+			continue
+		}
+		changes = append(changes, ScoreChange{Evaluator: s.Evaluator, Old: &s.Value})
+	}
+	return changes
+}
+
+func passRate(s *Summary) float64 {
+	return float64(s.Passed) / float64(s.Runs)
+}
+
+func errorRate(s *Summary) float64 {
+	return float64(s.Errors) / float64(s.Runs)
+}
+
+func totalCost(results []Result) float64 {
+	var cost float64
+	for _, r := range results {
+		cost += r.Cost
+	}
+	return cost
+}
+
+// relativeChange относительное изменение new к old (0 - old равно 0)
+func relativeChange(old, new float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (new - old) / old
+}
@@ -0,0 +1,136 @@
+package experiment
+
+import (
+	"fmt"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+)
+
+// Defaults значения для пустых осей сетки (обычно из конфигурации)
+type Defaults struct {
+	Model       string
+	Temperature float32
+	MaxTokens   int
+}
+
+// Cell одна ячейка сетки: полный набор параметров запроса
+type Cell struct {
+	Index          int      `json:"index"`
+	Prompt         string   `json:"prompt"`
+	PromptText     string   `json:"prompt_text"`
+	PromptTemplate string   `json:"prompt_template,omitempty"` // Шаблон промпта: name@version#hash
+	System         string   `json:"system,omitempty"`
+	SystemText     string   `json:"system_text,omitempty"`
+	SystemTemplate string   `json:"system_template,omitempty"` // Шаблон системного промпта
+	History        string   `json:"history,omitempty"`
+	Compress       bool     `json:"compress,omitempty"`
+	Model          string   `json:"model"`
+	Temperature    float32  `json:"temperature"`
+	MaxTokens      int      `json:"max_tokens"`
+	Stop           []string `json:"stop,omitempty"`
+	ResponseFormat string   `json:"response_format,omitempty"`
+	Repetition     int      `json:"repetition"` // Номер повтора с 1
+
+	history    *History
+	evaluators []eval.Spec
+}
+
+// Templates возвращает шаблоны промптов ячейки: name@version#hash
+func (c Cell) Templates() []string {
+	var templates []string
+	for _, t := range []string{c.SystemTemplate, c.PromptTemplate} {
+		if t != "" {
+			templates = append(templates, t)
+		}
+	}
+	return templates
+}
+
+// Key возвращает ключ параметров ячейки без номера повтора: повторы одной
+// ячейки имеют одинаковый ключ (stop и response_format задаются промптом)
+func (c Cell) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%.2f|%d", c.Prompt, c.System, c.History, c.Model, c.Temperature, c.MaxTokens)
+}
+
+// Expand раскрывает спецификацию в декартово произведение: промпты x системные
+// промпты x истории x модели x температуры x max_tokens x повторы
+func (s *Spec) Expand(defaults Defaults) []Cell {
+	systems := s.SystemPrompts
+	if len(systems) == 0 {
+		systems = []Text{{}}
+	}
+	histories := make([]*History, 0, len(s.Histories))
+	for i := range s.Histories {
+		histories = append(histories, &s.Histories[i])
+	}
+	if len(histories) == 0 {
+		histories = []*History{nil}
+	}
+	models := orDefault(s.Models, defaults.Model)
+	repetitions := max(s.Repetitions, 1)
+
+	var cells []Cell
+	for _, p := range s.Prompts {
+		temperatures := orDefault(firstNonEmpty(p.Temperatures, s.Temperatures), defaults.Temperature)
+		maxTokens := orDefault(firstNonEmpty(p.MaxTokens, s.MaxTokens), defaults.MaxTokens)
+		stop := firstNonEmpty(p.Stop, s.Stop)
+		format := p.ResponseFormat
+		if format == "" {
+			format = s.ResponseFormat
+		}
+		evaluators := append(append([]eval.Spec{}, s.Evaluators...), p.Evaluators...)
+
+		for _, system := range systems {
+			for _, history := range histories {
+				for _, model := range models {
+					for _, temperature := range temperatures {
+						for _, tokens := range maxTokens {
+							for rep := 1; rep <= repetitions; rep++ {
+								cell := Cell{
+									Index:          len(cells),
+									Prompt:         p.Name,
+									PromptText:     p.Text.Text,
+									PromptTemplate: p.rendered.String(),
+									System:         system.Name,
+									SystemText:     system.Text,
+									SystemTemplate: system.rendered.String(),
+									Model:          model,
+									Temperature:    temperature,
+									MaxTokens:      tokens,
+									Stop:           stop,
+									ResponseFormat: format,
+									Repetition:     rep,
+									history:        history,
+									evaluators:     evaluators,
+								}
+								if history != nil {
+									cell.History = history.Name
+									cell.Compress = history.Compress != nil
+								}
+								cells = append(cells, cell)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// orDefault возвращает values или список из одного значения по умолчанию
+func orDefault[T any](values []T, def T) []T {
+	if len(values) == 0 {
+		return []T{def}
+	}
+	return values
+}
+
+func firstNonEmpty[T any](values ...[]T) []T {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
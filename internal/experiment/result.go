@@ -0,0 +1,180 @@
+package experiment
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+)
+
+// Result ответ модели для одной ячейки вместе с ее параметрами
+type Result struct {
+	Experiment string `json:"experiment"`
+	Cell
+
+	Response         string       `json:"response"`
+	ResponseModel    string       `json:"response_model,omitempty"` // Модель, которую вернул API
+	FinishReason     string       `json:"finish_reason,omitempty"`
+	PromptTokens     int          `json:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens"`
+	TotalTokens      int          `json:"total_tokens"`             // Вместе с токенами судьи
+	HistoryTokens    int          `json:"history_tokens,omitempty"` // Токенов истории в запросе (после сжатия)
+	Cost             float64      `json:"cost_usd"`                 // Вместе с судьей; 0 - модели нет в каталоге
+	JudgeTokens      int          `json:"judge_tokens,omitempty"`   // Из них на проверки judge
+	JudgeCost        float64      `json:"judge_cost_usd,omitempty"`
+	LatencyMs        int64        `json:"latency_ms"`
+	Scores           []eval.Score `json:"scores,omitempty"`
+	Error            string       `json:"error,omitempty"`
+	Time             time.Time    `json:"time"`
+}
+
+// Passed возвращает true, если запрос выполнен и все проверки пройдены
+func (r Result) Passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, s := range r.Scores {
+		if !s.Pass {
+			return false
+		}
+	}
+	return true
+}
+
+// ResultWriter дописывает результаты в файл JSONL по одному на строку,
+// поэтому прерванный эксперимент сохраняет уже полученные ответы
+type ResultWriter struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// CreateResultWriter создает файл результатов (и каталог для него)
+func CreateResultWriter(path string) (*ResultWriter, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("ошибка создания каталога результатов: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания файла результатов: %w", err)
+	}
+	return &ResultWriter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Write записывает один результат
+func (w *ResultWriter) Write(result Result) error {
+	if err := w.enc.Encode(result); err != nil {
+		return fmt.Errorf("ошибка записи результата: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает файл результатов
+func (w *ResultWriter) Close() error {
+	return w.file.Close()
+}
+
+// ReadResults читает результаты из файла JSONL
+func ReadResults(path string) ([]Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения результатов: %w", err)
+	}
+	defer file.Close()
+
+	var results []Result
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		results = append(results, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения результатов: %w", err)
+	}
+	return results, nil
+}
+
+// Summary сводка по повторам одной ячейки
+type Summary struct {
+	Cell                        // Параметры первого повтора
+	Runs           int          `json:"runs"`
+	Errors         int          `json:"errors"`
+	Passed         int          `json:"passed"` // Повторов, прошедших все проверки
+	AvgTotalTokens float64      `json:"avg_total_tokens"`
+	AvgCompletion  float64      `json:"avg_completion_tokens"`
+	AvgLatencyMs   float64      `json:"avg_latency_ms"`
+	TotalCost      float64      `json:"total_cost_usd"`
+	Scores         []eval.Score `json:"scores,omitempty"` // Средние значения проверок
+}
+
+// Summarize сводит результаты по ячейкам без учета повторов в порядке первого появления
+func Summarize(results []Result) []Summary {
+	var summaries []Summary
+	index := make(map[string]int)
+	scoreSums := make(map[string]map[string]float64)
+	scoreCounts := make(map[string]map[string]int)
+
+	for _, r := range results {
+		key := r.Key()
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{Cell: r.Cell})
+			scoreSums[key] = make(map[string]float64)
+			scoreCounts[key] = make(map[string]int)
+		}
+
+		s := &summaries[i]
+		s.Runs++
+		s.TotalCost += r.Cost
+		if r.Error != "" {
+			s.Errors++
+			continue
+		}
+		if r.Passed() {
+			s.Passed++
+		}
+		s.AvgTotalTokens += float64(r.TotalTokens)
+		s.AvgCompletion += float64(r.CompletionTokens)
+		s.AvgLatencyMs += float64(r.LatencyMs)
+
+		for _, score := range r.Scores {
+			if scoreCounts[key][score.Evaluator] == 0 {
+				s.Scores = append(s.Scores, eval.Score{Evaluator: score.Evaluator})
+			}
+			scoreSums[key][score.Evaluator] += score.Value
+			scoreCounts[key][score.Evaluator]++
+		}
+	}
+
+	for i := range summaries {
+		s := &summaries[i]
+		key := s.Key()
+		if ok := s.Runs - s.Errors; ok > 0 {
+			s.AvgTotalTokens /= float64(ok)
+			s.AvgCompletion /= float64(ok)
+			s.AvgLatencyMs /= float64(ok)
+		}
+		for j := range s.Scores {
+			name := s.Scores[j].Evaluator
+			s.Scores[j].Value = scoreSums[key][name] / float64(scoreCounts[key][name])
+			s.Scores[j].Pass = s.Scores[j].Value == 1
+		}
+		s.Repetition = 0
+	}
+	return summaries
+}
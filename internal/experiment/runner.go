@@ -0,0 +1,222 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Runner выполняет ячейки эксперимента через клиент API
+type Runner struct {
+	client     *client.OpenAIClient
+	judgeModel string           // Модель судьи для проверок judge (пусто - модель клиента)
+	prompts    *prompts.Library // Шаблоны судьи (nil - встроенные)
+	summarizer agent.Summarizer // Для историй со сжатием (nil - экстрактивный)
+	onResult   func(Result)     // Вызывается после каждой ячейки (опционально)
+
+	// Сжатые истории: сжатие выполняется один раз на историю
+	compressed map[*History][]openai.ChatCompletionMessage
+}
+
+// NewRunner создает исполнитель эксперимента
+func NewRunner(c *client.OpenAIClient) *Runner {
+	return &Runner{
+		client:     c,
+		compressed: make(map[*History][]openai.ChatCompletionMessage),
+	}
+}
+
+// SetSummarizer задает суммаризатор для историй со сжатием
+func (r *Runner) SetSummarizer(summarizer agent.Summarizer) {
+	r.summarizer = summarizer
+}
+
+// SetJudgeModel задает модель судьи для проверок judge без своей модели
+func (r *Runner) SetJudgeModel(model string) {
+	r.judgeModel = model
+}
+
+// SetPrompts задает библиотеку с шаблонами промптов судьи
+func (r *Runner) SetPrompts(lib *prompts.Library) {
+	r.prompts = lib
+}
+
+// SetProgress задает функцию, которая получает каждый результат сразу после запроса
+func (r *Runner) SetProgress(fn func(Result)) {
+	r.onResult = fn
+}
+
+// Run выполняет ячейки по порядку. Ошибка запроса сохраняется в результате
+// ячейки, и эксперимент продолжается; превышение бюджета и отмена ctx
+// останавливают его и возвращают уже полученные результаты вместе с ошибкой.
+func (r *Runner) Run(ctx context.Context, spec *Spec, cells []Cell) ([]Result, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "experiment.Run")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("experiment.name", spec.Name),
+		attribute.Int("experiment.cells", len(cells)),
+	)
+
+	results := make([]Result, 0, len(cells))
+	for _, cell := range cells {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result, err := r.runCell(ctx, spec.Name, cell)
+		if err != nil {
+			telemetry.RecordError(span, err)
+			return results, err
+		}
+
+		results = append(results, result)
+		if r.onResult != nil {
+			r.onResult(result)
+		}
+	}
+	return results, nil
+}
+
+// runCell выполняет один запрос и проверки ответа. Ошибка возвращается
+// только для превышения бюджета и отмены ctx.
+func (r *Runner) runCell(ctx context.Context, name string, cell Cell) (Result, error) {
+	result := Result{
+		Experiment: name,
+		Cell:       cell,
+		Time:       time.Now(),
+	}
+
+	req := client.CompletionRequest{
+		Model:       cell.Model,
+		System:      cell.SystemText,
+		Prompt:      cell.PromptText,
+		MaxTokens:   cell.MaxTokens,
+		Temperature: client.Temperature(cell.Temperature),
+		Stop:        cell.Stop,
+		Templates:   cell.Templates(),
+	}
+	if cell.ResponseFormat != "" {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatType(cell.ResponseFormat),
+		}
+	}
+
+	if cell.history != nil {
+		history, err := r.history(ctx, cell.history)
+		if err != nil {
+			return result, err
+		}
+		req.History = history
+		result.HistoryTokens = tokenizer.CountMessages(cell.Model, history)
+	}
+
+	start := time.Now()
+	resp, err := r.client.CreateCompletionContext(ctx, req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		if usage.IsFatal(ctx, err) {
+			return result, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.Response = resp.Content
+	result.ResponseModel = resp.Model
+	result.FinishReason = resp.FinishReason
+	result.PromptTokens = resp.PromptTokens
+	result.CompletionTokens = resp.CompletionTokens
+	result.TotalTokens = resp.TotalTokens
+	if model, err := models.Lookup(cell.Model); err == nil {
+		result.Cost = model.Cost(resp.PromptTokens, resp.CachedTokens, resp.CompletionTokens)
+	}
+
+	result.Scores, err = r.evaluate(ctx, cell, resp, &result)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// history возвращает сообщения истории, при необходимости сжатые
+func (r *Runner) history(ctx context.Context, h *History) ([]openai.ChatCompletionMessage, error) {
+	if h.Compress == nil {
+		return chatMessages(h.Messages), nil
+	}
+	if messages, ok := r.compressed[h]; ok {
+		return messages, nil
+	}
+
+	cm := agent.NewContextManager(nil, *h.Compress)
+	if r.summarizer != nil {
+		cm.SetSummarizer(r.summarizer)
+	}
+	for _, m := range h.Messages {
+		cm.AddMessage(m.Role, m.Content)
+		if err := cm.CompressIfNeeded(ctx); err != nil {
+			return nil, fmt.Errorf("сжатие истории %s: %w", h.Name, err)
+		}
+	}
+
+	var messages []Message
+	for _, m := range cm.GetContextForRequest() {
+		messages = append(messages, Message{Role: m.Role, Content: m.Content})
+	}
+	r.compressed[h] = chatMessages(messages)
+	return r.compressed[h], nil
+}
+
+func chatMessages(messages []Message) []openai.ChatCompletionMessage {
+	result := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	return result
+}
+
+// evaluate применяет к ответу проверки ячейки и добавляет расходы судьи к result.
+// Ошибка судьи сохраняется в оценке, превышение бюджета и отмена ctx
+// останавливают эксперимент.
+func (r *Runner) evaluate(ctx context.Context, cell Cell, resp *client.CompletionResponse, result *Result) ([]eval.Score, error) {
+	in := eval.Input{
+		Prompt:       cell.PromptText,
+		Response:     resp.Content,
+		FinishReason: resp.FinishReason,
+		Stop:         cell.Stop,
+	}
+
+	opts := eval.Options{Client: r.client, JudgeModel: r.judgeModel, Prompts: r.prompts}
+	scores := make([]eval.Score, 0, len(cell.evaluators))
+	for _, spec := range cell.evaluators {
+		evaluator, err := eval.New(spec, opts)
+		if err != nil {
+			return nil, err
+		}
+		score, err := evaluator.Evaluate(ctx, in)
+		if score.Usage != nil {
+			result.JudgeTokens += score.Usage.TotalTokens
+			result.JudgeCost += score.Usage.Cost
+			result.TotalTokens += score.Usage.TotalTokens
+			result.Cost += score.Usage.Cost
+		}
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return nil, fmt.Errorf("проверка %s: %w", evaluator.Name(), err)
+			}
+			score = eval.Score{Evaluator: evaluator.Name(), Detail: "ошибка: " + err.Error(), Usage: score.Usage}
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
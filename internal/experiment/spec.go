@@ -0,0 +1,317 @@
+// Package experiment - декларативные эксперименты: спецификация в YAML задает
+// промпты и сетку параметров, движок выполняет декартово произведение
+// и сохраняет каждый ответ вместе с полным набором параметров
+package experiment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/agent"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec спецификация эксперимента.
+// Пустая ось сетки (models, temperatures, max_tokens) берется из конфигурации.
+type Spec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	Prompts       []Prompt  `yaml:"prompts"`        // Промпты пользователя
+	SystemPrompts []Text    `yaml:"system_prompts"` // Системные промпты (пустой text - без системного сообщения)
+	Histories     []History `yaml:"histories"`      // Диалоги перед промптом
+
+	Models         []string  `yaml:"models"`
+	Temperatures   []float32 `yaml:"temperatures"`
+	MaxTokens      []int     `yaml:"max_tokens"`
+	Stop           []string  `yaml:"stop"`
+	ResponseFormat string    `yaml:"response_format"` // "text" или "json_object"
+
+	Repetitions int         `yaml:"repetitions"` // Повторов каждой ячейки (0 - один)
+	Evaluators  []eval.Spec `yaml:"evaluators"`  // Проверки для всех ответов
+
+	path string // Файл спецификации
+}
+
+// Text именованный текст: задается прямо в спецификации, файлом
+// или шаблоном из библиотеки промптов
+type Text struct {
+	Name string `yaml:"name"`
+	Text string `yaml:"text"`
+	File string `yaml:"file"` // Путь относительно файла спецификации
+
+	Template string            `yaml:"template"` // Шаблон промпта name@version
+	Vars     map[string]string `yaml:"vars"`     // Переменные шаблона
+
+	rendered prompts.Rendered // Шаблон, по которому построен Text
+}
+
+// Prompt промпт пользователя. Заданные здесь параметры заменяют
+// одноименные оси сетки для этого промпта, проверки добавляются к общим.
+type Prompt struct {
+	Text `yaml:",inline"`
+
+	Temperatures   []float32   `yaml:"temperatures"`
+	MaxTokens      []int       `yaml:"max_tokens"`
+	Stop           []string    `yaml:"stop"`
+	ResponseFormat string      `yaml:"response_format"`
+	Evaluators     []eval.Spec `yaml:"evaluators"`
+}
+
+// History диалог, который отправляется перед промптом
+type History struct {
+	Name     string    `yaml:"name"`
+	File     string    `yaml:"file"` // YAML со списком сообщений
+	Messages []Message `yaml:"messages"`
+
+	// Compress сжимать историю через summary перед запросом (nil - без сжатия)
+	Compress *agent.ContextConfig `yaml:"compress"`
+}
+
+// Message сообщение диалога
+type Message struct {
+	Role    string `yaml:"role" json:"role"`
+	Content string `yaml:"content" json:"content"`
+}
+
+// responseFormats допустимые значения response_format
+var responseFormats = []string{"text", "json_object"}
+
+// LoadSpec читает спецификацию, подставляет содержимое файлов и шаблонов
+// промптов (из lib, nil - встроенная библиотека) и историй и проверяет ее
+func LoadSpec(path string, lib *prompts.Library) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения спецификации: %w", err)
+	}
+
+	// Неизвестные ключи и неверные типы - с номером строки
+	var spec Spec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	spec.path = path
+
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	if err := spec.readFiles(lib); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Path возвращает путь к файлу спецификации (пусто - не из файла)
+func (s *Spec) Path() string {
+	return s.path
+}
+
+// resolve возвращает путь относительно каталога спецификации
+func (s *Spec) resolve(file string) string {
+	if s.path == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(filepath.Dir(s.path), file)
+}
+
+// readFiles читает тексты промптов и истории, заданные файлами и шаблонами
+func (s *Spec) readFiles(lib *prompts.Library) error {
+	for i := range s.Prompts {
+		if err := s.readText(lib, &s.Prompts[i].Text, fmt.Sprintf("prompts[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for i := range s.SystemPrompts {
+		if err := s.readText(lib, &s.SystemPrompts[i], fmt.Sprintf("system_prompts[%d]", i)); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.Histories {
+		h := &s.Histories[i]
+		if h.File == "" {
+			continue
+		}
+		if len(h.Messages) > 0 {
+			return fmt.Errorf("histories[%d]: нужно задать file или messages, а не оба", i)
+		}
+
+		data, err := os.ReadFile(s.resolve(h.File))
+		if err != nil {
+			return fmt.Errorf("histories[%d]: %w", i, err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&h.Messages); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("histories[%d]: %s: %w", i, h.File, err)
+		}
+	}
+	return nil
+}
+
+func (s *Spec) readText(lib *prompts.Library, t *Text, key string) error {
+	if t.Template != "" {
+		return t.render(lib, key)
+	}
+	if len(t.Vars) > 0 {
+		return fmt.Errorf("%s: vars задаются только вместе с template", key)
+	}
+	if t.File == "" {
+		return nil
+	}
+	if t.Text != "" {
+		return fmt.Errorf("%s: нужно задать text или file, а не оба", key)
+	}
+
+	data, err := os.ReadFile(s.resolve(t.File))
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	t.Text = string(data)
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(t.File), filepath.Ext(t.File))
+	}
+	return nil
+}
+
+// render строит текст по шаблону; имя по умолчанию - имя шаблона
+func (t *Text) render(lib *prompts.Library, key string) error {
+	if t.Text != "" || t.File != "" {
+		return fmt.Errorf("%s: нужно задать одно из text, file или template", key)
+	}
+
+	vars := make(map[string]any, len(t.Vars))
+	for k, v := range t.Vars {
+		vars[k] = v
+	}
+	rendered, err := lib.Render(t.Template, vars)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	t.Text, t.rendered = rendered.Text, rendered
+	if t.Name == "" {
+		t.Name = rendered.Name
+	}
+	return nil
+}
+
+// Validate проверяет спецификацию
+func (s *Spec) Validate() error {
+	if len(s.Prompts) == 0 {
+		return errors.New("не задано ни одного промпта (prompts)")
+	}
+	if s.Repetitions < 0 {
+		return fmt.Errorf("repetitions не может быть отрицательным, получено %d", s.Repetitions)
+	}
+
+	names := make(map[string]bool)
+	for i, p := range s.Prompts {
+		key := fmt.Sprintf("prompts[%d]", i)
+		if p.Name == "" {
+			return fmt.Errorf("%s: не задано name", key)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("%s: повторяется имя %q", key, p.Name)
+		}
+		names[p.Name] = true
+
+		if strings.TrimSpace(p.Text.Text) == "" {
+			return fmt.Errorf("%s: пустой промпт (нужно задать text, file или template)", key)
+		}
+		if err := checkParams(key, p.Temperatures, p.MaxTokens, p.ResponseFormat, p.Evaluators); err != nil {
+			return err
+		}
+	}
+
+	if err := uniqueNames("system_prompts", len(s.SystemPrompts), func(i int) string { return s.SystemPrompts[i].Name }); err != nil {
+		return err
+	}
+	if err := uniqueNames("histories", len(s.Histories), func(i int) string { return s.Histories[i].Name }); err != nil {
+		return err
+	}
+
+	for i, h := range s.Histories {
+		key := fmt.Sprintf("histories[%d]", i)
+		if len(h.Messages) == 0 {
+			return fmt.Errorf("%s: пустая история (нужно задать file или messages)", key)
+		}
+		for j, m := range h.Messages {
+			if m.Role != "user" && m.Role != "assistant" && m.Role != "system" {
+				return fmt.Errorf("%s: messages[%d]: неизвестная роль %q (допустимо: user, assistant, system)", key, j, m.Role)
+			}
+		}
+		if h.Compress != nil {
+			if err := h.Compress.Validate(); err != nil {
+				return fmt.Errorf("%s: compress: %w", key, err)
+			}
+		}
+	}
+
+	for i, m := range s.Models {
+		if m == "" {
+			return fmt.Errorf("models[%d]: пустое имя модели", i)
+		}
+	}
+
+	return checkParams("", s.Temperatures, s.MaxTokens, s.ResponseFormat, s.Evaluators)
+}
+
+// checkParams проверяет оси сетки и проверки (общие или промпта)
+func checkParams(key string, temperatures []float32, maxTokens []int, format string, evaluators []eval.Spec) error {
+	prefix := ""
+	if key != "" {
+		prefix = key + ": "
+	}
+
+	for _, t := range temperatures {
+		if t < 0 || t > 2 {
+			return fmt.Errorf("%stemperatures: значение %.2f вне диапазона 0-2", prefix, t)
+		}
+	}
+	for _, n := range maxTokens {
+		if n < 0 {
+			return fmt.Errorf("%smax_tokens: отрицательное значение %d", prefix, n)
+		}
+	}
+	if format != "" && format != responseFormats[0] && format != responseFormats[1] {
+		return fmt.Errorf("%sresponse_format: неизвестный формат %q (допустимо: %s)", prefix, format, strings.Join(responseFormats, ", "))
+	}
+	for i, e := range evaluators {
+		if _, err := eval.New(e, eval.Options{}); err != nil {
+			return fmt.Errorf("%sevaluators[%d]: %w", prefix, i, err)
+		}
+	}
+	return nil
+}
+
+// uniqueNames проверяет, что у элементов списка есть имена и они не повторяются
+func uniqueNames(key string, n int, name func(i int) string) error {
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		if name(i) == "" {
+			return fmt.Errorf("%s[%d]: не задано name", key, i)
+		}
+		if seen[name(i)] {
+			return fmt.Errorf("%s[%d]: повторяется имя %q", key, i, name(i))
+		}
+		seen[name(i)] = true
+	}
+	return nil
+}
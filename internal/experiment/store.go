@@ -0,0 +1,289 @@
+package experiment
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// DefaultStoreDir каталог хранилища запусков по умолчанию
+const DefaultStoreDir = "results"
+
+// indexFile журнал запусков в каталоге хранилища
+const indexFile = "runs.jsonl"
+
+// Run запись о запуске эксперимента: откуда он взялся (спецификация, коммит)
+// и итоги. Ответы лежат в файле Results, по одному Result на строку.
+type Run struct {
+	ID         string    `json:"id"`
+	Experiment string    `json:"experiment"`
+	Spec       string    `json:"spec,omitempty"` // Путь к спецификации
+	SpecHash   string    `json:"spec_hash,omitempty"`
+	GitCommit  string    `json:"git_commit,omitempty"`
+	GitDirty   bool      `json:"git_dirty,omitempty"` // Были незакоммиченные изменения
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Results    string    `json:"results"` // Файл результатов (относительно хранилища, если внутри него)
+
+	Cells       int     `json:"cells"`     // Ячеек в плане
+	Completed   int     `json:"completed"` // Получено результатов
+	Errors      int     `json:"errors"`
+	Passed      int     `json:"passed"`
+	TotalTokens int     `json:"total_tokens"`
+	Cost        float64 `json:"cost_usd"`
+	Error       string  `json:"error,omitempty"` // Причина остановки
+
+	Summary []Summary `json:"summary,omitempty"`
+}
+
+// NewRun создает запись о запуске спецификации, начатом в started
+func NewRun(spec *Spec, started time.Time) Run {
+	return Run{
+		ID:         RunID(spec.Name, started),
+		Experiment: spec.Name,
+		Spec:       spec.Path(),
+		SpecHash:   spec.Hash(),
+		Started:    started,
+	}
+}
+
+// RunID идентификатор запуска: <имя эксперимента>-<время>-<случайный суффикс>.
+// Суффикс различает запуски, начатые в одну секунду.
+func RunID(name string, started time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix) // crypto/rand.Read не возвращает ошибку
+	return fmt.Sprintf("%s-%s-%s", name, started.Format("20060102-150405"), hex.EncodeToString(suffix))
+}
+
+// Finish заполняет итоги запуска по результатам; err - причина остановки (nil - завершен)
+func (r *Run) Finish(cells int, results []Result, err error) {
+	r.Finished = time.Now()
+	r.Cells = cells
+	r.Completed = len(results)
+	r.Errors, r.Passed, r.TotalTokens, r.Cost = 0, 0, 0, 0
+	for _, res := range results {
+		if res.Error != "" {
+			r.Errors++
+		} else if res.Passed() {
+			r.Passed++
+		}
+		r.TotalTokens += res.TotalTokens
+		r.Cost += res.Cost
+	}
+	r.Summary = Summarize(results)
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Hash возвращает хэш содержимого спецификации вместе с прочитанными файлами
+// промптов и историй: одинаковый хэш - запуски сравнимы
+func (s *Spec) Hash() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// GitRevision возвращает текущий коммит и наличие незакоммиченных изменений.
+// Вне репозитория - ревизия, с которой собрана программа (пусто - неизвестна).
+func GitRevision(ctx context.Context) (commit string, dirty bool) {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err == nil {
+		commit = strings.TrimSpace(string(out))
+		status, err := exec.CommandContext(ctx, "git", "status", "--porcelain", "--untracked-files=no").Output()
+		return commit, err == nil && len(strings.TrimSpace(string(status))) > 0
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", false
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	return commit, dirty
+}
+
+// Store хранилище запусков: файлы результатов и журнал запусков runs.jsonl
+type Store struct {
+	dir string
+}
+
+// NewStore создает хранилище в каталоге dir (пусто - DefaultStoreDir)
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultStoreDir
+	}
+	return &Store{dir: dir}
+}
+
+// Dir возвращает каталог хранилища
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// ResultPath возвращает путь к файлу результатов запуска в хранилище
+func (s *Store) ResultPath(id string) string {
+	return filepath.Join(s.dir, id+".jsonl")
+}
+
+// Save дописывает запись о запуске в журнал. Путь к результатам внутри
+// хранилища сохраняется относительным, чтобы каталог можно было переносить.
+func (s *Store) Save(run Run) error {
+	if path, err := filepath.Abs(run.Results); err == nil {
+		run.Results = path
+		if dir, err := filepath.Abs(s.dir); err == nil {
+			if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+				run.Results = rel
+			}
+		}
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("ошибка создания каталога результатов: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(s.dir, indexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала запусков: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(run); err != nil {
+		return fmt.Errorf("ошибка записи журнала запусков: %w", err)
+	}
+	return nil
+}
+
+// Runs возвращает запуски в порядке записи (нет журнала - пустой список)
+func (s *Store) Runs() ([]Run, error) {
+	path := filepath.Join(s.dir, indexFile)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала запусков: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Run
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		runs = append(runs, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала запусков: %w", err)
+	}
+	return runs, nil
+}
+
+// Find находит запуск по идентификатору или его уникальному началу.
+// Файл результатов вне журнала (старые запуски, -out) тоже подходит:
+// запись о нем восстанавливается по самим результатам.
+func (s *Store) Find(ref string) (Run, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return Run{}, err
+	}
+
+	var matches []Run
+	for _, r := range runs {
+		if r.ID == ref {
+			return r, nil
+		}
+		if strings.HasPrefix(r.ID, ref) {
+			matches = append(matches, r)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		ids := make([]string, 0, len(matches))
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+		return Run{}, fmt.Errorf("запуск %q неоднозначен: %s", ref, strings.Join(ids, ", "))
+	}
+
+	if _, err := os.Stat(ref); err != nil {
+		return Run{}, fmt.Errorf("запуск %q не найден в %s", ref, filepath.Join(s.dir, indexFile))
+	}
+	results, err := ReadResults(ref)
+	if err != nil {
+		return Run{}, err
+	}
+	if len(results) == 0 {
+		return Run{}, fmt.Errorf("%s: нет результатов", ref)
+	}
+	path, err := filepath.Abs(ref)
+	if err != nil {
+		return Run{}, err
+	}
+	run := Run{
+		ID:         strings.TrimSuffix(filepath.Base(ref), filepath.Ext(ref)),
+		Experiment: results[0].Experiment,
+		Started:    results[0].Time,
+		Results:    path,
+	}
+	run.Finish(len(results), results, nil)
+	run.Finished = results[len(results)-1].Time
+	return run, nil
+}
+
+// Previous возвращает запуск того же эксперимента, записанный перед run
+func (s *Store) Previous(run Run) (Run, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return Run{}, err
+	}
+
+	var prev *Run
+	for i := range runs {
+		if runs[i].ID == run.ID {
+			break
+		}
+		if runs[i].Experiment == run.Experiment {
+			prev = &runs[i]
+		}
+	}
+	if prev == nil {
+		return Run{}, fmt.Errorf("нет запуска %s раньше %s", run.Experiment, run.ID)
+	}
+	return *prev, nil
+}
+
+// Results читает ответы запуска
+func (s *Store) Results(run Run) ([]Result, error) {
+	path := run.Results
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.dir, path)
+	}
+	return ReadResults(path)
+}
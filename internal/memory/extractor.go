@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Extractor извлекает факты из одного хода диалога
+type Extractor interface {
+	Extract(ctx context.Context, userMessage, assistantMessage string) ([]Fact, error)
+}
+
+// extractionTemplate шаблон промпта извлечения фактов. Версия не закреплена:
+// новая версия в prompts_dir заменяет встроенную.
+const extractionTemplate = "memory/extract"
+
+// LLMExtractor извлекает факты с помощью языковой модели в JSON режиме
+type LLMExtractor struct {
+	client  *openai.Client
+	model   string
+	prompts *prompts.Library // Библиотека с шаблоном memory/extract
+	meter   *telemetry.Meter // Бюджет и журнал использования (опционально)
+
+	// MinConfidence факты с меньшей уверенностью отбрасываются
+	MinConfidence float64
+}
+
+// NewLLMExtractor создает LLM-экстрактор фактов
+func NewLLMExtractor(client *openai.Client, model string) *LLMExtractor {
+	if model == "" {
+		model = openai.GPT4oMini
+	}
+	return &LLMExtractor{
+		client:        client,
+		model:         model,
+		prompts:       prompts.Default(),
+		MinConfidence: 0.5,
+	}
+}
+
+// SetMeter подключает учет запросов: запросы извлечения проверяются бюджетом
+// и пишутся в журнал использования
+func (e *LLMExtractor) SetMeter(meter *telemetry.Meter) {
+	e.meter = meter
+}
+
+// SetPrompts задает библиотеку с шаблоном извлечения (по умолчанию - встроенная)
+func (e *LLMExtractor) SetPrompts(lib *prompts.Library) {
+	e.prompts = lib
+}
+
+// Extract извлекает факты из хода диалога
+func (e *LLMExtractor) Extract(ctx context.Context, userMessage, assistantMessage string) ([]Fact, error) {
+	prompt, err := e.prompts.Render(extractionTemplate, map[string]any{"user": userMessage, "assistant": assistantMessage})
+	if err != nil {
+		return nil, err
+	}
+	resp, _, err := e.meter.ChatCompletion(ctx, e.client, openai.ChatCompletionRequest{
+		Model: e.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt.Text,
+			},
+		},
+		Temperature: 0.1,
+		MaxTokens:   400,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	}, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка извлечения фактов: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("получен пустой ответ при извлечении фактов")
+	}
+
+	var parsed struct {
+		Facts []struct {
+			Subject    string  `json:"subject"`
+			Attribute  string  `json:"attribute"`
+			Value      string  `json:"value"`
+			Confidence float64 `json:"confidence"`
+		} `json:"facts"`
+	}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &parsed); err != nil {
+		return nil, fmt.Errorf("некорректный JSON с фактами: %w", err)
+	}
+
+	now := time.Now()
+	facts := make([]Fact, 0, len(parsed.Facts))
+	for _, f := range parsed.Facts {
+		subject := strings.TrimSpace(f.Subject)
+		attribute := strings.TrimSpace(f.Attribute)
+		value := strings.TrimSpace(f.Value)
+		if subject == "" || attribute == "" || value == "" || f.Confidence < e.MinConfidence {
+			continue
+		}
+
+		facts = append(facts, Fact{
+			Subject:    subject,
+			Attribute:  attribute,
+			Value:      value,
+			Source:     userMessage,
+			Confidence: f.Confidence,
+			UpdatedAt:  now,
+		})
+	}
+
+	return facts, nil
+}
@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+)
+
+// Fact структурированный факт, извлеченный из диалога
+type Fact struct {
+	ID             int       `json:"id"`
+	Subject        string    `json:"subject"`                   // О ком/чем факт ("пользователь", "команда", "проект")
+	Attribute      string    `json:"attribute"`                 // Свойство ("имя", "место работы")
+	Value          string    `json:"value"`                     // Значение ("Алексей", "TechCorp")
+	Source         string    `json:"source"`                    // Сообщение, из которого извлечен факт
+	Confidence     float64   `json:"confidence"`                // Уверенность 0..1
+	CreatedAt      time.Time `json:"created_at"`                // Когда факт впервые появился
+	UpdatedAt      time.Time `json:"updated_at"`                // Когда факт последний раз подтвержден или изменен
+	PreviousValues []string  `json:"previous_values,omitempty"` // Значения, вытесненные более новыми
+}
+
+// String возвращает факт в виде "субъект / свойство: значение"
+func (f Fact) String() string {
+	return fmt.Sprintf("%s / %s: %s", f.Subject, f.Attribute, f.Value)
+}
+
+// key ключ для дедупликации: один факт на пару субъект+свойство
+func (f Fact) key() string {
+	return normalize(f.Subject) + "\x00" + normalize(f.Attribute)
+}
+
+// Store хранилище фактов с дедупликацией и разрешением противоречий
+type Store struct {
+	facts  []Fact
+	nextID int
+}
+
+// NewStore создает пустое хранилище фактов
+func NewStore() *Store {
+	return &Store{
+		facts:  make([]Fact, 0),
+		nextID: 1,
+	}
+}
+
+// AddResult что произошло с фактом при добавлении
+type AddResult int
+
+const (
+	FactAdded     AddResult = iota // Новый факт
+	FactConfirmed                  // Тот же факт уже был - обновлены уверенность и время
+	FactReplaced                   // Противоречие: новое значение вытеснило старое
+)
+
+// Add добавляет факт. Факт с тем же субъектом и свойством не дублируется:
+// то же значение подтверждает существующий факт, другое значение заменяет
+// его (побеждает более новый), а старое значение сохраняется в PreviousValues.
+func (s *Store) Add(fact Fact) AddResult {
+	now := time.Now()
+	if fact.UpdatedAt.IsZero() {
+		fact.UpdatedAt = now
+	}
+
+	for i := range s.facts {
+		existing := &s.facts[i]
+		if existing.key() != fact.key() {
+			continue
+		}
+
+		if normalize(existing.Value) == normalize(fact.Value) {
+			if fact.Confidence > existing.Confidence {
+				existing.Confidence = fact.Confidence
+			}
+			if fact.UpdatedAt.After(existing.UpdatedAt) {
+				existing.UpdatedAt = fact.UpdatedAt
+			}
+			return FactConfirmed
+		}
+
+		// Противоречие разрешается в пользу более свежего факта
+		if fact.UpdatedAt.Before(existing.UpdatedAt) {
+			return FactConfirmed
+		}
+		existing.PreviousValues = append(existing.PreviousValues, existing.Value)
+		existing.Value = fact.Value
+		existing.Source = fact.Source
+		existing.Confidence = fact.Confidence
+		existing.UpdatedAt = fact.UpdatedAt
+		return FactReplaced
+	}
+
+	fact.ID = s.nextID
+	s.nextID++
+	if fact.CreatedAt.IsZero() {
+		fact.CreatedAt = fact.UpdatedAt
+	}
+	s.facts = append(s.facts, fact)
+	return FactAdded
+}
+
+// List возвращает все факты в порядке добавления
+func (s *Store) List() []Fact {
+	return s.facts
+}
+
+// Len возвращает количество фактов
+func (s *Store) Len() int {
+	return len(s.facts)
+}
+
+// Update вручную меняет значение факта (ручная правка считается полностью достоверной)
+func (s *Store) Update(id int, value string) error {
+	for i := range s.facts {
+		if s.facts[i].ID != id {
+			continue
+		}
+		if s.facts[i].Value != value {
+			s.facts[i].PreviousValues = append(s.facts[i].PreviousValues, s.facts[i].Value)
+		}
+		s.facts[i].Value = value
+		s.facts[i].Source = "ручная правка"
+		s.facts[i].Confidence = 1
+		s.facts[i].UpdatedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("факт #%d не найден", id)
+}
+
+// Forget удаляет факт
+func (s *Store) Forget(id int) error {
+	for i := range s.facts {
+		if s.facts[i].ID == id {
+			s.facts = append(s.facts[:i], s.facts[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("факт #%d не найден", id)
+}
+
+// Clear удаляет все факты
+func (s *Store) Clear() {
+	s.facts = make([]Fact, 0)
+}
+
+// Relevant возвращает до limit фактов, наиболее подходящих к запросу:
+// сначала по числу общих слов с запросом, затем по уверенности и свежести
+func (s *Store) Relevant(query string, limit int) []Fact {
+	queryWords := make(map[string]bool)
+	for _, word := range words(query) {
+		queryWords[word] = true
+	}
+
+	type scored struct {
+		fact    Fact
+		overlap int
+	}
+	ranked := make([]scored, 0, len(s.facts))
+	for _, fact := range s.facts {
+		overlap := 0
+		for _, word := range words(fact.Subject + " " + fact.Attribute + " " + fact.Value) {
+			if queryWords[word] {
+				overlap++
+			}
+		}
+		ranked = append(ranked, scored{fact: fact, overlap: overlap})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].overlap != ranked[j].overlap {
+			return ranked[i].overlap > ranked[j].overlap
+		}
+		if ranked[i].fact.Confidence != ranked[j].fact.Confidence {
+			return ranked[i].fact.Confidence > ranked[j].fact.Confidence
+		}
+		return ranked[i].fact.UpdatedAt.After(ranked[j].fact.UpdatedAt)
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	facts := make([]Fact, 0, len(ranked))
+	for _, r := range ranked {
+		facts = append(facts, r.fact)
+	}
+	return facts
+}
+
+// FormatForPrompt форматирует факты для системного промпта
+func FormatForPrompt(facts []Fact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Известные факты из предыдущих разговоров (используй их в ответах):\n")
+	for _, fact := range facts {
+		fmt.Fprintf(&b, "- %s\n", fact)
+	}
+	return b.String()
+}
+
+// storeFile формат файла хранилища фактов
+type storeFile struct {
+	Facts   []Fact    `json:"facts"`
+	NextID  int       `json:"next_id"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Save сохраняет факты в JSON файл
+func (s *Store) Save(filename string) error {
+	jsonData, err := json.MarshalIndent(storeFile{
+		Facts:   s.facts,
+		NextID:  s.nextID,
+		SavedAt: time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	if err := os.WriteFile(filename, redact.Bytes(jsonData), 0644); err != nil {
+		return fmt.Errorf("ошибка записи в файл: %w", err)
+	}
+
+	return nil
+}
+
+// Load загружает факты из JSON файла (отсутствие файла не считается ошибкой)
+func (s *Store) Load(filename string) error {
+	jsonData, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+
+	var data storeFile
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return fmt.Errorf("ошибка десериализации: %w", err)
+	}
+
+	s.facts = data.Facts
+	if s.facts == nil {
+		s.facts = make([]Fact, 0)
+	}
+
+	// next_id не может быть меньше уже выданных идентификаторов
+	s.nextID = data.NextID
+	for _, fact := range s.facts {
+		if fact.ID >= s.nextID {
+			s.nextID = fact.ID + 1
+		}
+	}
+
+	return nil
+}
+
+// normalize приводит строку к виду для сравнения
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// words разбивает текст на слова длиннее двух символов в нижнем регистре
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) > 2 {
+			result = append(result, field)
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package memory
+
+import "context"
+
+// DefaultPromptFacts сколько фактов по умолчанию добавляется в системный промпт
+const DefaultPromptFacts = 10
+
+// Memory долговременная память фактов: хранилище плюс экстрактор
+type Memory struct {
+	Store     *Store
+	extractor Extractor
+
+	// PromptFacts сколько релевантных фактов добавлять в системный промпт
+	PromptFacts int
+}
+
+// New создает память фактов с указанным экстрактором
+func New(extractor Extractor) *Memory {
+	return &Memory{
+		Store:       NewStore(),
+		extractor:   extractor,
+		PromptFacts: DefaultPromptFacts,
+	}
+}
+
+// TurnResult итог обработки хода диалога
+type TurnResult struct {
+	Added     int // Новых фактов
+	Confirmed int // Подтвержденных фактов
+	Replaced  int // Фактов, чье значение изменилось
+}
+
+// Learned возвращает количество новых и измененных фактов
+func (r TurnResult) Learned() int {
+	return r.Added + r.Replaced
+}
+
+// ProcessTurn извлекает факты из хода диалога и сохраняет их в хранилище
+func (m *Memory) ProcessTurn(ctx context.Context, userMessage, assistantMessage string) (TurnResult, error) {
+	var result TurnResult
+
+	facts, err := m.extractor.Extract(ctx, userMessage, assistantMessage)
+	if err != nil {
+		return result, err
+	}
+
+	for _, fact := range facts {
+		switch m.Store.Add(fact) {
+		case FactAdded:
+			result.Added++
+		case FactConfirmed:
+			result.Confirmed++
+		case FactReplaced:
+			result.Replaced++
+		}
+	}
+
+	return result, nil
+}
+
+// PromptFor возвращает блок системного промпта с фактами, релевантными запросу
+func (m *Memory) PromptFor(query string) string {
+	return FormatForPrompt(m.Store.Relevant(query, m.PromptFacts))
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultEmbeddingModel модель эмбеддингов по умолчанию
+const DefaultEmbeddingModel = string(openai.SmallEmbedding3)
+
+// Embedder векторное представление текстов для сравнения по смыслу
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder эмбеддинги через OpenAI-совместимый API
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+	meter  *telemetry.Meter // Бюджет и журнал использования (опционально)
+}
+
+// NewOpenAIEmbedder создает Embedder с моделью model (пусто - DefaultEmbeddingModel)
+func NewOpenAIEmbedder(client *openai.Client, model string) *OpenAIEmbedder {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{client: client, model: model}
+}
+
+// SetMeter подключает учет запросов: запросы эмбеддингов проверяются бюджетом
+// и пишутся в журнал использования
+func (e *OpenAIEmbedder) SetMeter(meter *telemetry.Meter) {
+	e.meter = meter
+}
+
+// Embed возвращает эмбеддинги текстов в том же порядке одним запросом
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "metrics.Embed")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("embedding.model", e.model),
+		attribute.Int("embedding.texts", len(texts)),
+	)
+
+	resp, err := e.meter.CreateEmbeddings(ctx, e.client, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, fmt.Errorf("запрос эмбеддингов: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("эмбеддинг с неверным индексом %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("нет эмбеддинга для текста %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// Cosine косинусное сходство векторов (0 - для нулевого вектора или разной длины)
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// EmbeddingSimilarity косинусное сходство эмбеддингов для каждой пары текстов
+func EmbeddingSimilarity(ctx context.Context, embedder Embedder, texts []string) ([]float64, error) {
+	if len(texts) < 2 {
+		return nil, nil
+	}
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	return pairwise(vectors, Cosine), nil
+}
@@ -0,0 +1,32 @@
+package metrics
+
+// TypeTokenRatio доля уникальных слов в тексте (0 - пустой текст)
+func TypeTokenRatio(text string) float64 {
+	words := Words(text)
+	if len(words) == 0 {
+		return 0
+	}
+	return float64(len(unique(words))) / float64(len(words))
+}
+
+// DistinctN доля уникальных n-грамм среди всех n-грамм текстов: 1 - ни одна
+// n-грамма не повторяется, около 0 - тексты повторяют одни и те же фразы.
+// Для одного текста - разнообразие внутри ответа, для нескольких - между ответами.
+func DistinctN(texts []string, n int) float64 {
+	var all []string
+	for _, t := range texts {
+		all = append(all, ngrams(Words(t), n)...)
+	}
+	if len(all) == 0 {
+		return 0
+	}
+	return float64(len(unique(all))) / float64(len(all))
+}
+
+func unique(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
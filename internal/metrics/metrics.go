@@ -0,0 +1,95 @@
+// Package metrics - метрики разнообразия и сходства ответов модели: насколько
+// разными получаются повторные ответы на один промпт (например, при разной температуре)
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Stats описательная статистика выборки
+type Stats struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"` // Выборочное стандартное отклонение (0 при N < 2)
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Median float64 `json:"median"`
+}
+
+// Describe считает статистику значений (пустой список - нулевая Stats)
+func Describe(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	s := Stats{N: len(values), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(len(values))
+
+	if len(values) > 1 {
+		var sum float64
+		for _, v := range values {
+			sum += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sum / float64(len(values)-1))
+	}
+
+	mid := len(sorted) / 2
+	s.Median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		s.Median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return s
+}
+
+// String среднее и стандартное отклонение, например "0.52 ± 0.03"
+func (s Stats) String() string {
+	return s.Format(2)
+}
+
+// Format как String с заданным числом знаков после запятой
+func (s Stats) Format(precision int) string {
+	if s.N == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.*f ± %.*f", precision, s.Mean, precision, s.StdDev)
+}
+
+// Words разбивает текст на слова в нижнем регистре (буквы и цифры)
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ngrams n-граммы слов, соединенные пробелом
+func ngrams(words []string, n int) []string {
+	if n <= 0 || len(words) < n {
+		return nil
+	}
+	result := make([]string, 0, len(words)-n+1)
+	for i := 0; i+n <= len(words); i++ {
+		result = append(result, strings.Join(words[i:i+n], " "))
+	}
+	return result
+}
+
+// pairwise применяет fn к каждой паре текстов (i < j) и возвращает значения
+func pairwise[T any](items []T, fn func(a, b T) float64) []float64 {
+	var values []float64
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			values = append(values, fn(items[i], items[j]))
+		}
+	}
+	return values
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import "math"
+
+// maxBLEUOrder BLEU учитывает n-граммы до 4 слов
+const maxBLEUOrder = 4
+
+// bleuEpsilon заменяет нулевое совпадение n-грамм, чтобы короткие ответы
+// не получали BLEU 0 целиком (сглаживание Chen & Cherry, метод 1)
+const bleuEpsilon = 0.1
+
+// BLEU сходство candidate с references по совпадению n-грамм (1..4) со штрафом
+// за краткость: 1 - candidate повторяет ссылку, 0 - общих слов нет
+func BLEU(candidate string, references []string) float64 {
+	cand := Words(candidate)
+	if len(cand) == 0 || len(references) == 0 {
+		return 0
+	}
+
+	refs := make([][]string, 0, len(references))
+	for _, r := range references {
+		refs = append(refs, Words(r))
+	}
+
+	var logSum float64
+	for n := 1; n <= maxBLEUOrder; n++ {
+		grams := ngrams(cand, n)
+		if len(grams) == 0 {
+			// Ответ короче n слов: порядок считается совпавшим (log 1 = 0)
+			continue
+		}
+
+		// Совпадения ограничены максимальным числом вхождений в одну из ссылок
+		maxRef := make(map[string]int)
+		for _, ref := range refs {
+			for gram, count := range counts(ngrams(ref, n)) {
+				maxRef[gram] = max(maxRef[gram], count)
+			}
+		}
+		matched := 0
+		for gram, count := range counts(grams) {
+			matched += min(count, maxRef[gram])
+		}
+
+		precision := float64(matched) / float64(len(grams))
+		if matched == 0 {
+			precision = bleuEpsilon / float64(len(grams))
+		}
+		logSum += math.Log(precision)
+	}
+
+	return brevityPenalty(len(cand), refs) * math.Exp(logSum/maxBLEUOrder)
+}
+
+// brevityPenalty штраф за ответ короче ближайшей по длине ссылки
+func brevityPenalty(length int, refs [][]string) float64 {
+	closest := len(refs[0])
+	for _, r := range refs[1:] {
+		if d, best := abs(len(r)-length), abs(closest-length); d < best || d == best && len(r) < closest {
+			closest = len(r)
+		}
+	}
+	if length >= closest || length == 0 {
+		return 1
+	}
+	return math.Exp(1 - float64(closest)/float64(length))
+}
+
+// ROUGEL F-мера по наибольшей общей подпоследовательности слов двух текстов
+func ROUGEL(a, b string) float64 {
+	wa, wb := Words(a), Words(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	lcs := float64(lcsLength(wa, wb))
+	if lcs == 0 {
+		return 0
+	}
+	precision := lcs / float64(len(wa))
+	recall := lcs / float64(len(wb))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// SelfBLEU BLEU каждого текста относительно остальных: чем выше, тем больше
+// ответы похожи друг на друга (меньше разнообразие). Нужно минимум два текста.
+func SelfBLEU(texts []string) []float64 {
+	if len(texts) < 2 {
+		return nil
+	}
+	values := make([]float64, 0, len(texts))
+	for i, t := range texts {
+		others := make([]string, 0, len(texts)-1)
+		others = append(others, texts[:i]...)
+		others = append(others, texts[i+1:]...)
+		values = append(values, BLEU(t, others))
+	}
+	return values
+}
+
+// SelfROUGE ROUGE-L для каждой пары текстов
+func SelfROUGE(texts []string) []float64 {
+	return pairwise(texts, ROUGEL)
+}
+
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func counts(items []string) map[string]int {
+	result := make(map[string]int, len(items))
+	for _, item := range items {
+		result[item]++
+	}
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
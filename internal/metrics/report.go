@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+)
+
+// Report метрики набора ответов на один промпт с одинаковыми параметрами.
+// Статистики по ответам (Length, TTR, Distinct1/2, SelfBLEU) считаются для
+// каждого ответа, попарные (SelfROUGE, Embedding) - для каждой пары ответов.
+type Report struct {
+	Samples int `json:"samples"`
+
+	Length    Stats `json:"length_words"` // Длина ответа в словах
+	TTR       Stats `json:"ttr"`          // Доля уникальных слов в ответе
+	Distinct1 Stats `json:"distinct_1"`   // Уникальные слова внутри ответа
+	Distinct2 Stats `json:"distinct_2"`   // Уникальные биграммы внутри ответа
+
+	// Разнообразие между ответами: доля уникальных n-грамм во всех ответах вместе
+	SetDistinct1 float64 `json:"set_distinct_1"`
+	SetDistinct2 float64 `json:"set_distinct_2"`
+
+	SelfBLEU  Stats  `json:"self_bleu"`                      // Сходство ответа с остальными (выше - однообразнее)
+	SelfROUGE Stats  `json:"self_rouge_l"`                   // ROUGE-L пар ответов
+	Embedding *Stats `json:"embedding_similarity,omitempty"` // Косинусное сходство пар по смыслу
+}
+
+// Analyze считает метрики ответов. Эмбеддинги запрашиваются только с embedder
+// (nil - без сходства по смыслу); ошибка возвращается вместе с остальными метриками.
+func Analyze(ctx context.Context, texts []string, embedder Embedder) (Report, error) {
+	var nonEmpty []string
+	for _, t := range texts {
+		if strings.TrimSpace(t) != "" {
+			nonEmpty = append(nonEmpty, t)
+		}
+	}
+
+	r := Report{Samples: len(nonEmpty)}
+	if len(nonEmpty) == 0 {
+		return r, nil
+	}
+
+	var length, ttr, d1, d2 []float64
+	for _, t := range nonEmpty {
+		length = append(length, float64(len(Words(t))))
+		ttr = append(ttr, TypeTokenRatio(t))
+		d1 = append(d1, DistinctN([]string{t}, 1))
+		d2 = append(d2, DistinctN([]string{t}, 2))
+	}
+	r.Length = Describe(length)
+	r.TTR = Describe(ttr)
+	r.Distinct1 = Describe(d1)
+	r.Distinct2 = Describe(d2)
+	r.SetDistinct1 = DistinctN(nonEmpty, 1)
+	r.SetDistinct2 = DistinctN(nonEmpty, 2)
+	r.SelfBLEU = Describe(SelfBLEU(nonEmpty))
+	r.SelfROUGE = Describe(SelfROUGE(nonEmpty))
+
+	if embedder == nil || len(nonEmpty) < 2 {
+		return r, nil
+	}
+	similarity, err := EmbeddingSimilarity(ctx, embedder, nonEmpty)
+	if err != nil {
+		return r, err
+	}
+	stats := Describe(similarity)
+	r.Embedding = &stats
+	return r, nil
+}
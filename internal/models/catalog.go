@@ -0,0 +1,204 @@
+package models
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog.yaml
+var embeddedCatalog []byte
+
+// ErrUnknownModel модели нет в каталоге
+var ErrUnknownModel = errors.New("неизвестная модель")
+
+// Capabilities возможности модели
+type Capabilities struct {
+	Vision     bool `yaml:"vision" json:"vision"`           // Принимает изображения
+	Tools      bool `yaml:"tools" json:"tools"`             // Вызов функций (tools)
+	JSONSchema bool `yaml:"json_schema" json:"json_schema"` // Structured outputs по JSON схеме
+}
+
+// Model описание модели: лимиты, цены (в долларах за 1M токенов) и возможности
+type Model struct {
+	Name             string       `yaml:"name" json:"name"`
+	Tier             string       `yaml:"tier" json:"tier"`                             // "weak", "medium", "strong"
+	ContextWindow    int          `yaml:"context_window" json:"context_window"`         // Лимит контекста в токенах
+	MaxOutput        int          `yaml:"max_output" json:"max_output"`                 // Максимальный размер ответа
+	InputPrice       float64      `yaml:"input_price" json:"input_price"`               // Цена входных токенов
+	CachedInputPrice float64      `yaml:"cached_input_price" json:"cached_input_price"` // Цена входных токенов из кэша (0 - как input)
+	OutputPrice      float64      `yaml:"output_price" json:"output_price"`             // Цена выходных токенов
+	Capabilities     Capabilities `yaml:"capabilities" json:"capabilities"`             // Возможности модели
+	Aliases          []string     `yaml:"aliases,omitempty" json:"aliases,omitempty"`   // Другие имена модели
+}
+
+// CachedPrice возвращает цену входных токенов из кэша
+func (m Model) CachedPrice() float64 {
+	if m.CachedInputPrice > 0 {
+		return m.CachedInputPrice
+	}
+	return m.InputPrice
+}
+
+// Cost возвращает стоимость запроса в долларах
+// (токены запроса, взятые из кэша, оплачиваются по цене кэша)
+func (m Model) Cost(promptTokens, cachedTokens, completionTokens int) float64 {
+	uncached := promptTokens - cachedTokens
+	return (float64(uncached)*m.InputPrice +
+		float64(cachedTokens)*m.CachedPrice() +
+		float64(completionTokens)*m.OutputPrice) / 1_000_000
+}
+
+// validate проверяет обязательные поля
+func (m Model) validate() error {
+	switch {
+	case m.Name == "":
+		return fmt.Errorf("у модели не указано имя")
+	case m.ContextWindow <= 0:
+		return fmt.Errorf("модель %s: context_window должен быть положительным", m.Name)
+	case m.MaxOutput <= 0:
+		return fmt.Errorf("модель %s: max_output должен быть положительным", m.Name)
+	case m.InputPrice < 0 || m.OutputPrice < 0 || m.CachedInputPrice < 0:
+		return fmt.Errorf("модель %s: цены не могут быть отрицательными", m.Name)
+	}
+	return nil
+}
+
+// Catalog каталог моделей
+type Catalog struct {
+	models  map[string]Model
+	aliases map[string]string
+}
+
+// catalogFile формат файла каталога
+type catalogFile struct {
+	Models []yaml.Node `yaml:"models"`
+}
+
+// NewCatalog создает пустой каталог
+func NewCatalog() *Catalog {
+	return &Catalog{
+		models:  make(map[string]Model),
+		aliases: make(map[string]string),
+	}
+}
+
+// Embedded возвращает каталог, встроенный в бинарник
+func Embedded() (*Catalog, error) {
+	catalog := NewCatalog()
+	if err := catalog.Merge(embeddedCatalog); err != nil {
+		return nil, fmt.Errorf("встроенный каталог: %w", err)
+	}
+	return catalog, nil
+}
+
+// Merge добавляет модели из YAML или JSON (JSON - подмножество YAML).
+// Поля, не указанные для уже известной модели, сохраняют прежние значения.
+func (c *Catalog) Merge(data []byte) error {
+	var file catalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("ошибка разбора каталога: %w", err)
+	}
+
+	for i := range file.Models {
+		node := &file.Models[i]
+
+		var named struct {
+			Name string `yaml:"name"`
+		}
+		if err := node.Decode(&named); err != nil {
+			return fmt.Errorf("модель %d: %w", i+1, err)
+		}
+
+		model := c.models[named.Name]
+		if err := node.Decode(&model); err != nil {
+			return fmt.Errorf("модель %d: %w", i+1, err)
+		}
+		if err := model.validate(); err != nil {
+			return err
+		}
+
+		c.models[model.Name] = model
+		for _, alias := range model.Aliases {
+			c.aliases[alias] = model.Name
+		}
+	}
+
+	return nil
+}
+
+// LoadFile добавляет модели из YAML или JSON файла
+func (c *Catalog) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения каталога моделей: %w", err)
+	}
+	if err := c.Merge(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// snapshotSuffix суффикс датированной версии модели (gpt-4o-2024-08-06, gpt-4-0613)
+var snapshotSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}|\d{4})$`)
+
+// Lookup возвращает описание модели по имени, псевдониму или датированной версии
+func (c *Catalog) Lookup(name string) (Model, error) {
+	for _, candidate := range []string{name, snapshotSuffix.ReplaceAllString(name, "")} {
+		if model, ok := c.models[candidate]; ok {
+			return model, nil
+		}
+		if canonical, ok := c.aliases[candidate]; ok {
+			return c.models[canonical], nil
+		}
+	}
+	return Model{}, fmt.Errorf("%w: %q (добавьте ее в каталог MODEL_CATALOG)", ErrUnknownModel, name)
+}
+
+// Models возвращает все модели, отсортированные по имени
+func (c *Catalog) Models() []Model {
+	models := make([]Model, 0, len(c.models))
+	for _, model := range c.models {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+	return models
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+	defaultErr     error
+)
+
+// Default возвращает каталог по умолчанию: встроенный плюс файл из MODEL_CATALOG
+// (если переменная задана). Каталог загружается один раз.
+func Default() (*Catalog, error) {
+	defaultOnce.Do(func() {
+		defaultCatalog, defaultErr = Embedded()
+		if defaultErr != nil {
+			return
+		}
+		if path := os.Getenv("MODEL_CATALOG"); path != "" {
+			defaultErr = defaultCatalog.LoadFile(path)
+		}
+	})
+	return defaultCatalog, defaultErr
+}
+
+// Lookup ищет модель в каталоге по умолчанию
+func Lookup(name string) (Model, error) {
+	catalog, err := Default()
+	if err != nil {
+		return Model{}, err
+	}
+	return catalog.Lookup(name)
+}
@@ -0,0 +1,346 @@
+// Package optimize - автоматическая оптимизация системного промпта: мета-промпт
+// предлагает переписанные варианты, каждый вариант проверяется на размеченном
+// наборе примеров (промпты и проверки спецификации эксперимента), лучший
+// становится основой следующей итерации. Оптимизация останавливается по
+// числу итераций, отсутствию улучшений или бюджету.
+package optimize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultMetaPrompt шаблон мета-промпта во встроенной библиотеке
+const DefaultMetaPrompt = "optimize/rewrite@1"
+
+// Причины остановки
+const (
+	StopIterations = "iterations" // Выполнены все итерации
+	StopPlateau    = "plateau"    // Несколько итераций без улучшения
+	StopPerfect    = "perfect"    // Все проверки пройдены, улучшать нечего
+	StopBudget     = "budget"     // Исчерпан лимит расходов
+	StopCanceled   = "canceled"   // Прервано (Ctrl+C)
+)
+
+// Options параметры оптимизации
+type Options struct {
+	Candidates     int     // Вариантов на итерацию
+	Iterations     int     // Максимум итераций
+	Patience       int     // Итераций без улучшения до остановки
+	MinImprovement float64 // Прирост оценки, который считается улучшением
+	MaxCost        float64 // Лимит расходов на оптимизацию в долларах (0 - только бюджет клиента)
+
+	Model       string  // Модель мета-промпта (пусто - модель клиента)
+	Temperature float32 // Температура мета-промпта: выше - разнообразнее варианты
+	MetaPrompt  string  // Шаблон мета-промпта name@version (пусто - DefaultMetaPrompt)
+}
+
+// DefaultOptions параметры по умолчанию
+func DefaultOptions() Options {
+	return Options{
+		Candidates:     3,
+		Iterations:     5,
+		Patience:       2,
+		MinImprovement: 0.01,
+		Temperature:    0.9,
+	}
+}
+
+// Candidate вариант промпта и его оценка на наборе примеров
+type Candidate struct {
+	Iteration int     `json:"iteration"` // 0 - исходный промпт
+	Prompt    string  `json:"prompt"`
+	Score     float64 `json:"score"`     // Среднее значение проверок по всем ответам, 0..1
+	PassRate  float64 `json:"pass_rate"` // Доля ответов, прошедших все проверки
+	Answers   int     `json:"answers"`
+	Errors    int     `json:"errors,omitempty"` // Ответов с ошибкой запроса (оценка 0)
+	Tokens    int     `json:"tokens"`
+	Cost      float64 `json:"cost_usd"`
+	Duplicate bool    `json:"duplicate,omitempty"` // Повтор уже проверенного варианта, оценка взята из него
+	Failed    string  `json:"failed,omitempty"`    // Вариант не получен или не оценен; лучшим не становится
+
+	results []experiment.Result
+}
+
+// Iteration итерация оптимизации
+type Iteration struct {
+	Number     int         `json:"iteration"`
+	Candidates []Candidate `json:"candidates"`
+	BestScore  float64     `json:"best_score"` // Лучшая оценка после итерации
+	Improved   bool        `json:"improved"`
+}
+
+// Result итог оптимизации: лучший промпт и история оценок
+type Result struct {
+	Experiment string      `json:"experiment"`
+	MetaPrompt string      `json:"meta_prompt"` // name@version#hash
+	Seed       Candidate   `json:"seed"`
+	Best       Candidate   `json:"best"`
+	History    []Iteration `json:"history"`
+	Stop       string      `json:"stop"`
+	Tokens     int         `json:"tokens"` // Все запросы: ответы, проверки и мета-промпт
+	Cost       float64     `json:"cost_usd"`
+}
+
+// Improvement возвращает прирост оценки лучшего промпта относительно исходного
+func (r *Result) Improvement() float64 {
+	return r.Best.Score - r.Seed.Score
+}
+
+// Optimizer оптимизирует системный промпт спецификации эксперимента
+type Optimizer struct {
+	client  *client.OpenAIClient
+	runner  *experiment.Runner
+	prompts *prompts.Library
+	opts    Options
+
+	onCandidate func(Candidate) // Вызывается после оценки каждого варианта (опционально)
+}
+
+// New создает оптимизатор: runner выполняет примеры, client - мета-промпт,
+// lib - библиотека с шаблоном мета-промпта (nil - встроенная)
+func New(c *client.OpenAIClient, runner *experiment.Runner, lib *prompts.Library, opts Options) *Optimizer {
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	if opts.MetaPrompt == "" {
+		opts.MetaPrompt = DefaultMetaPrompt
+	}
+	return &Optimizer{client: c, runner: runner, prompts: lib, opts: opts}
+}
+
+// SetProgress задает функцию, которая получает каждый вариант сразу после оценки
+func (o *Optimizer) SetProgress(fn func(Candidate)) {
+	o.onCandidate = fn
+}
+
+// Seed возвращает исходный промпт спецификации: единственный системный промпт
+func Seed(spec *experiment.Spec) (string, error) {
+	if len(spec.SystemPrompts) != 1 || spec.SystemPrompts[0].Text == "" {
+		return "", errors.New("для оптимизации нужен ровно один непустой исходный промпт в system_prompts")
+	}
+	for i, p := range spec.Prompts {
+		if len(spec.Evaluators) == 0 && len(p.Evaluators) == 0 {
+			return "", fmt.Errorf("prompts[%d] (%s): нет проверок - оценить ответ нечем", i, p.Name)
+		}
+	}
+	return spec.SystemPrompts[0].Text, nil
+}
+
+// Optimize улучшает исходный промпт спецификации. Превышение бюджета - штатная
+// остановка: возвращается лучший промпт на момент остановки. При отмене ctx
+// итог возвращается вместе с ошибкой.
+func (o *Optimizer) Optimize(ctx context.Context, spec *experiment.Spec, defaults experiment.Defaults) (*Result, error) {
+	seed, err := Seed(spec)
+	if err != nil {
+		return nil, err
+	}
+	if o.opts.Candidates < 1 || o.opts.Iterations < 1 || o.opts.Patience < 1 {
+		return nil, fmt.Errorf("candidates, iterations и patience должны быть положительными")
+	}
+	if _, err := o.prompts.Get(o.opts.MetaPrompt); err != nil {
+		return nil, fmt.Errorf("мета-промпт: %w", err)
+	}
+
+	ctx, span := telemetry.Tracer().Start(ctx, "optimize.Optimize")
+	defer span.End()
+	span.SetAttributes(attribute.String("experiment.name", spec.Name))
+
+	result := &Result{Experiment: spec.Name}
+	seen := make(map[string]Candidate)
+
+	// Без оценки исходного промпта сравнивать не с чем: любая ошибка прерывает оптимизацию
+	initial, err := o.evaluate(ctx, spec, defaults, seed, 0, result)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return nil, err
+	}
+	result.Seed, result.Best = initial, initial
+	seen[seed] = initial
+
+	stale := 0
+	result.Stop = StopIterations
+	for number := 1; number <= o.opts.Iterations; number++ {
+		if result.Best.Score >= 1 {
+			result.Stop = StopPerfect
+			break
+		}
+
+		iteration := Iteration{Number: number}
+		err := o.iterate(ctx, spec, defaults, &iteration, result, seen)
+		if len(iteration.Candidates) > 0 {
+			for _, c := range iteration.Candidates {
+				if c.Failed == "" && c.Score > result.Best.Score+o.opts.MinImprovement {
+					result.Best = c
+					iteration.Improved = true
+				}
+			}
+			iteration.BestScore = result.Best.Score
+			result.History = append(result.History, iteration)
+		}
+		if err != nil {
+			result.Stop = stopReason(err)
+			if result.Stop == "" {
+				telemetry.RecordError(span, err)
+			}
+			return result, stopError(err)
+		}
+
+		if iteration.Improved {
+			stale = 0
+		} else if stale++; stale >= o.opts.Patience {
+			result.Stop = StopPlateau
+			break
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Float64("optimize.seed_score", result.Seed.Score),
+		attribute.Float64("optimize.best_score", result.Best.Score),
+		attribute.String("optimize.stop", result.Stop),
+	)
+	return result, nil
+}
+
+// iterate генерирует и оценивает варианты одной итерации. Вариант, который
+// не удалось получить или оценить, записывается с ошибкой, и итерация
+// продолжается; прерывают ее только бюджет и отмена.
+func (o *Optimizer) iterate(ctx context.Context, spec *experiment.Spec, defaults experiment.Defaults,
+	iteration *Iteration, result *Result, seen map[string]Candidate) error {
+	for variant := 1; variant <= o.opts.Candidates; variant++ {
+		if err := o.checkCost(result); err != nil {
+			return err
+		}
+
+		prompt, err := o.rewrite(ctx, result, seen, variant)
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return err
+			}
+			o.fail(iteration, Candidate{Iteration: iteration.Number}, err)
+			continue
+		}
+
+		// Модель может вернуть уже проверенный вариант: повторно не оцениваем
+		if c, ok := seen[prompt]; ok {
+			c.Iteration, c.Duplicate, c.Tokens, c.Cost = iteration.Number, true, 0, 0
+			iteration.Candidates = append(iteration.Candidates, c)
+			o.progress(c)
+			continue
+		}
+
+		c, err := o.evaluate(ctx, spec, defaults, prompt, iteration.Number, result)
+		if err != nil {
+			if usage.IsFatal(ctx, err) {
+				return err
+			}
+			o.fail(iteration, c, err)
+			continue
+		}
+		iteration.Candidates = append(iteration.Candidates, c)
+		seen[prompt] = c
+	}
+	return nil
+}
+
+// fail записывает вариант с ошибкой. В seen он не попадает: такой же
+// вариант на следующей итерации будет оценен заново.
+func (o *Optimizer) fail(iteration *Iteration, c Candidate, err error) {
+	c.Failed = err.Error()
+	iteration.Candidates = append(iteration.Candidates, c)
+	o.progress(c)
+}
+
+// evaluate выполняет примеры спецификации с промптом как системным сообщением
+func (o *Optimizer) evaluate(ctx context.Context, spec *experiment.Spec, defaults experiment.Defaults,
+	prompt string, iteration int, result *Result) (Candidate, error) {
+	// Копия спецификации с одним системным промптом; истории общие,
+	// поэтому сжатие каждой выполняется один раз за оптимизацию
+	variant := *spec
+	variant.SystemPrompts = []experiment.Text{{Name: fmt.Sprintf("candidate-%d", iteration), Text: prompt}}
+
+	results, err := o.runner.Run(ctx, &variant, variant.Expand(defaults))
+	c := score(prompt, iteration, results)
+	result.Tokens += c.Tokens
+	result.Cost += c.Cost
+	if err != nil {
+		return c, err
+	}
+	o.progress(c)
+	return c, nil
+}
+
+func (o *Optimizer) progress(c Candidate) {
+	if o.onCandidate != nil {
+		o.onCandidate(c)
+	}
+}
+
+// checkCost останавливает оптимизацию, когда расходы достигли MaxCost
+func (o *Optimizer) checkCost(result *Result) error {
+	if o.opts.MaxCost > 0 && result.Cost >= o.opts.MaxCost {
+		return fmt.Errorf("%w: потрачено $%.4f из $%.4f на оптимизацию", usage.ErrBudgetExceeded, result.Cost, o.opts.MaxCost)
+	}
+	return nil
+}
+
+// score считает оценку варианта: среднее значение проверок каждого ответа,
+// ответ с ошибкой запроса получает 0
+func score(prompt string, iteration int, results []experiment.Result) Candidate {
+	c := Candidate{Iteration: iteration, Prompt: prompt, Answers: len(results), results: results}
+	if len(results) == 0 {
+		return c
+	}
+
+	passed := 0
+	for _, r := range results {
+		c.Tokens += r.TotalTokens
+		c.Cost += r.Cost
+		if r.Error != "" {
+			c.Errors++
+			continue
+		}
+		if r.Passed() {
+			passed++
+		}
+		if len(r.Scores) > 0 {
+			sum := 0.0
+			for _, s := range r.Scores {
+				sum += s.Value
+			}
+			c.Score += sum / float64(len(r.Scores))
+		}
+	}
+	c.Score /= float64(len(results))
+	c.PassRate = float64(passed) / float64(len(results))
+	return c
+}
+
+// stopReason причина остановки для ошибок, которые не прерывают оптимизацию
+// с ошибкой (пусто - настоящая ошибка)
+func stopReason(err error) string {
+	switch {
+	case errors.Is(err, usage.ErrBudgetExceeded):
+		return StopBudget
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return StopCanceled
+	}
+	return ""
+}
+
+// stopError возвращает ошибку, которую должен увидеть вызывающий код:
+// бюджет - штатная остановка, отмена и прочие ошибки - нет
+func stopError(err error) error {
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		return nil
+	}
+	return err
+}
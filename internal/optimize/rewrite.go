@@ -0,0 +1,159 @@
+package optimize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+)
+
+// Сколько примеров ошибок и проверенных вариантов попадает в мета-промпт
+const (
+	maxFailures  = 3
+	maxTried     = 5
+	maxAnswerLen = 400 // Символов ответа в примере ошибки
+)
+
+// failure ответ, не прошедший проверки (переменная мета-промпта)
+type failure struct {
+	Input    string
+	Response string
+	Detail   string
+}
+
+// rewrite просит модель переписать лучший промпт с учетом ошибок и уже
+// проверенных вариантов
+func (o *Optimizer) rewrite(ctx context.Context, result *Result, seen map[string]Candidate, variant int) (string, error) {
+	best := result.Best
+	rendered, err := o.prompts.Render(o.opts.MetaPrompt, map[string]any{
+		"prompt":   best.Prompt,
+		"score":    best.Score,
+		"failures": failures(best),
+		"tried":    tried(seen, best.Prompt),
+		"variant":  variant,
+	})
+	if err != nil {
+		return "", fmt.Errorf("мета-промпт: %w", err)
+	}
+	result.MetaPrompt = rendered.String()
+
+	resp, err := o.client.CreateCompletionContext(ctx, client.CompletionRequest{
+		Model:       o.opts.Model,
+		Prompt:      rendered.Text,
+		Temperature: client.Temperature(o.opts.Temperature),
+		Templates:   []string{rendered.String()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("генерация варианта: %w", err)
+	}
+	result.Tokens += resp.TotalTokens
+	result.Cost += o.cost(resp)
+
+	prompt := cleanPrompt(resp.Content)
+	if prompt == "" {
+		return "", errors.New("генерация варианта: модель вернула пустой промпт")
+	}
+	return prompt, nil
+}
+
+// cost стоимость запроса мета-промпта (0 - модели нет в каталоге)
+func (o *Optimizer) cost(resp *client.CompletionResponse) float64 {
+	name := o.opts.Model
+	if name == "" {
+		name = resp.Model
+	}
+	model, err := models.Lookup(name)
+	if err != nil {
+		return 0
+	}
+	return model.Cost(resp.PromptTokens, resp.CachedTokens, resp.CompletionTokens)
+}
+
+// failures примеры ответов варианта, не прошедших проверки
+func failures(c Candidate) []failure {
+	var list []failure
+	for _, r := range c.results {
+		if r.Passed() || len(list) == maxFailures {
+			continue
+		}
+		f := failure{Input: oneLine(r.PromptText), Response: truncate(oneLine(r.Response), maxAnswerLen)}
+		if r.Error != "" {
+			f.Detail = "ошибка запроса: " + r.Error
+		} else {
+			var details []string
+			for _, s := range r.Scores {
+				if !s.Pass {
+					details = append(details, fmt.Sprintf("%s: %s", s.Evaluator, oneLine(s.Detail)))
+				}
+			}
+			f.Detail = strings.Join(details, "; ")
+		}
+		list = append(list, f)
+	}
+	return list
+}
+
+// tried лучшие из проверенных вариантов, кроме текущего
+func tried(seen map[string]Candidate, current string) []Candidate {
+	list := make([]Candidate, 0, len(seen))
+	for prompt, c := range seen {
+		if prompt != current {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Prompt < list[j].Prompt
+	})
+	if len(list) > maxTried {
+		list = list[:maxTried]
+	}
+	for i := range list {
+		list[i].Prompt = oneLine(list[i].Prompt)
+	}
+	return list
+}
+
+// cleanPrompt убирает обрамление, которое модели добавляют вопреки инструкции:
+// блок кода, кавычки, разделители <<< >>> и подпись "Промпт:"
+func cleanPrompt(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		if i := strings.Index(s, "\n"); i >= 0 && !strings.Contains(s[:i], " ") {
+			s = s[i+1:] // Язык блока кода
+		}
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "<<<")
+	s = strings.TrimSuffix(s, ">>>")
+	s = strings.TrimSpace(s)
+	for _, label := range []string{"Новый промпт:", "Промпт:"} {
+		s = strings.TrimSpace(strings.TrimPrefix(s, label))
+	}
+	for _, q := range [][2]string{{`"`, `"`}, {"«", "»"}} {
+		if len(s) > 1 && strings.HasPrefix(s, q[0]) && strings.HasSuffix(s, q[1]) {
+			s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, q[0]), q[1]))
+		}
+	}
+	return s
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,288 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed library
+var embedded embed.FS
+
+// Library набор шаблонов: у одного имени может быть несколько версий
+type Library struct {
+	templates map[string][]*Template // По имени, версии по возрастанию
+}
+
+// NewLibrary создает пустую библиотеку
+func NewLibrary() *Library {
+	return &Library{templates: make(map[string][]*Template)}
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultLibrary *Library
+)
+
+// Default возвращает встроенную библиотеку: промпты заданий и суммаризатора.
+// Ошибка во встроенных шаблонах - ошибка сборки, поэтому вызывает панику.
+func Default() *Library {
+	defaultOnce.Do(func() {
+		sub, err := fs.Sub(embedded, "library")
+		if err != nil {
+			panic(err)
+		}
+		defaultLibrary = NewLibrary()
+		if err := defaultLibrary.Load(sub); err != nil {
+			panic(fmt.Sprintf("встроенные шаблоны промптов: %v", err))
+		}
+	})
+	return defaultLibrary
+}
+
+// Clone возвращает копию библиотеки, в которую можно добавлять шаблоны
+func (l *Library) Clone() *Library {
+	clone := NewLibrary()
+	for name, versions := range l.templates {
+		clone.templates[name] = slices.Clone(versions)
+	}
+	return clone
+}
+
+// Load добавляет все файлы *.tmpl из fsys, включая подкаталоги
+func (l *Library) Load(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(file) != Ext {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		t, err := Parse(file, data)
+		if err != nil {
+			return err
+		}
+		return l.Add(t)
+	})
+}
+
+// LoadDir добавляет шаблоны из каталога
+func (l *Library) LoadDir(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("каталог шаблонов промптов: %w", err)
+	}
+	if err := l.Load(os.DirFS(dir)); err != nil {
+		return fmt.Errorf("каталог шаблонов промптов %s: %w", dir, err)
+	}
+	return nil
+}
+
+// Add добавляет шаблон. Версию с тем же номером, но другим текстом добавить
+// нельзя: изменение промпта требует новой версии.
+func (l *Library) Add(t *Template) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	versions := l.templates[t.Name]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].Version >= t.Version })
+	if i < len(versions) && versions[i].Version == t.Version {
+		if versions[i].Source == t.Source {
+			return nil
+		}
+		return fmt.Errorf("шаблон %s уже определен (%s) с другим текстом: увеличьте version", t.Ref(), orCode(versions[i].File))
+	}
+	l.templates[t.Name] = slices.Insert(versions, i, t)
+	return nil
+}
+
+// Get возвращает шаблон по ссылке name@version (без версии - последнюю)
+func (l *Library) Get(ref string) (*Template, error) {
+	name, version, err := ParseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	versions := l.templates[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("шаблон промпта %q не найден", name)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, t := range versions {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("нет версии %d шаблона %s (есть: %s)", version, name, versionList(versions))
+}
+
+// MustGet как Get, но вызывает панику при ошибке. Только для встроенных шаблонов.
+func (l *Library) MustGet(ref string) *Template {
+	t, err := l.Get(ref)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Templates возвращает все шаблоны по имени и версии
+func (l *Library) Templates() []*Template {
+	var all []*Template
+	for _, versions := range l.templates {
+		all = append(all, versions...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Name != all[j].Name {
+			return all[i].Name < all[j].Name
+		}
+		return all[i].Version < all[j].Version
+	})
+	return all
+}
+
+// Render строит промпт по шаблону: переменные vars дополняются значениями
+// по умолчанию из метаданных, неизвестная переменная - ошибка.
+// Фрагмент подключается в версии из ссылки {{template "name@version" .}},
+// без версии - в последней.
+func (l *Library) Render(ref string, vars map[string]any) (Rendered, error) {
+	t, err := l.Get(ref)
+	if err != nil {
+		return Rendered{}, err
+	}
+	if t.Partial {
+		return Rendered{}, fmt.Errorf("%s - фрагмент, его подключают из других шаблонов", t.Ref())
+	}
+
+	partials, err := l.partials(t)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	tmpl := template.New(t.Name).Option("missingkey=error")
+	if _, err := tmpl.Parse(t.Source); err != nil {
+		return Rendered{}, fmt.Errorf("шаблон %s: %w", t.Ref(), err)
+	}
+	for _, p := range partials {
+		if _, err := tmpl.New(p.include).Parse(p.Source); err != nil {
+			return Rendered{}, fmt.Errorf("фрагмент %s: %w", p.Ref(), err)
+		}
+	}
+
+	data := make(map[string]any, len(t.Vars)+len(vars))
+	for _, p := range partials {
+		for k, v := range p.Vars {
+			data[k] = v
+		}
+	}
+	for k, v := range t.Vars {
+		data[k] = v
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return Rendered{}, fmt.Errorf("шаблон %s: %w", t.Ref(), err)
+	}
+
+	return Rendered{
+		Name:    t.Name,
+		Version: t.Version,
+		Hash:    contentHash(t, partials),
+		Text:    sb.String(),
+	}, nil
+}
+
+// MustRender как Render, но вызывает панику при ошибке. Только для
+// встроенных шаблонов, которые вызывающий код передает с нужными переменными.
+func (l *Library) MustRender(ref string, vars map[string]any) Rendered {
+	r, err := l.Render(ref, vars)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// includeRe вызов фрагмента {{template "name@version" ...}} (версия необязательна)
+var includeRe = regexp.MustCompile(`\{\{-?\s*template\s+"([^"]+)"`)
+
+// partial фрагмент и ссылка, по которой его подключили
+type partial struct {
+	*Template
+	include string // name@version или name, как в {{template}}
+}
+
+// partials возвращает фрагменты, которые подключает шаблон, в том числе
+// через другие фрагменты, упорядоченные по ссылке подключения
+func (l *Library) partials(t *Template) ([]partial, error) {
+	seen := make(map[string]*Template)
+	queue := []*Template{t}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, m := range includeRe.FindAllStringSubmatch(current.Source, -1) {
+			include := m[1]
+			if _, ok := seen[include]; ok {
+				continue
+			}
+			p, err := l.Get(include)
+			if err != nil {
+				return nil, fmt.Errorf("шаблон %s: %w", current.Ref(), err)
+			}
+			if !p.Partial {
+				return nil, fmt.Errorf("шаблон %s подключает %s, который не отмечен partial: true", current.Ref(), p.Ref())
+			}
+			seen[include] = p
+			queue = append(queue, p)
+		}
+	}
+
+	partials := make([]partial, 0, len(seen))
+	for include, p := range seen {
+		partials = append(partials, partial{Template: p, include: include})
+	}
+	sort.Slice(partials, func(i, j int) bool { return partials[i].include < partials[j].include })
+	return partials, nil
+}
+
+// contentHash хэш текста шаблона и его фрагментов: меняется при любой
+// правке текста, даже если версию забыли увеличить
+func contentHash(t *Template, partials []partial) string {
+	h := sha256.New()
+	h.Write([]byte(t.Source))
+	for _, p := range partials {
+		fmt.Fprintf(h, "\x00%s\x00%s", p.Ref(), p.Source)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+func versionList(versions []*Template) string {
+	list := make([]string, 0, len(versions))
+	for _, t := range versions {
+		list = append(list, fmt.Sprintf("%d", t.Version))
+	}
+	return strings.Join(list, ", ")
+}
+
+func orCode(file string) string {
+	if file == "" {
+		return "в коде"
+	}
+	return file
+}
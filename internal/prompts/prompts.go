@@ -0,0 +1,137 @@
+// Package prompts - библиотека шаблонов промптов. Шаблон - файл .tmpl
+// с метаданными во front-matter (имя, версия, описание, значения переменных
+// по умолчанию) и текстом в синтаксисе text/template. Общие фрагменты
+// (partials) подключаются через {{template "имя" .}}. Промпт задается ссылкой
+// name@version, а в запрос записываются имя, версия и хэш содержимого.
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ext расширение файлов шаблонов
+const Ext = ".tmpl"
+
+// frontMatterDelimiter строка, которая открывает и закрывает метаданные
+const frontMatterDelimiter = "---"
+
+// Template шаблон промпта одной версии
+type Template struct {
+	Name        string            `yaml:"name"`
+	Version     int               `yaml:"version"`
+	Description string            `yaml:"description"`
+	Partial     bool              `yaml:"partial"` // Фрагмент для других шаблонов, не отдельный промпт
+	Vars        map[string]string `yaml:"vars"`    // Значения переменных по умолчанию
+
+	Source string `yaml:"-"` // Текст шаблона без метаданных
+	File   string `yaml:"-"` // Файл шаблона (пусто - задан в коде)
+}
+
+// Ref возвращает ссылку на шаблон: name@version
+func (t *Template) Ref() string {
+	return fmt.Sprintf("%s@%d", t.Name, t.Version)
+}
+
+// Parse читает шаблон из файла: метаданные YAML между строками "---",
+// затем текст. Имя по умолчанию - путь файла без расширения.
+func Parse(file string, data []byte) (*Template, error) {
+	meta, body, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	t := &Template{File: file}
+	decoder := yaml.NewDecoder(bytes.NewReader(meta))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(t); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(file, Ext)
+	}
+	// Перевод строки в конце файла не входит в промпт
+	t.Source = strings.TrimSuffix(string(body), "\n")
+
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return t, nil
+}
+
+// Validate проверяет метаданные шаблона
+func (t *Template) Validate() error {
+	if t.Name == "" {
+		return errors.New("не задано имя шаблона (name)")
+	}
+	if strings.ContainsAny(t.Name, "@# \t\n") {
+		return fmt.Errorf("имя шаблона %q не должно содержать @, # и пробелы", t.Name)
+	}
+	if t.Version < 1 {
+		return fmt.Errorf("%s: version должна быть не меньше 1, получено %d", t.Name, t.Version)
+	}
+	return nil
+}
+
+// splitFrontMatter отделяет метаданные от текста; без "---" в первой строке
+// метаданных нет
+func splitFrontMatter(data []byte) (meta, body []byte, err error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	first, rest, _ := bytes.Cut(data, []byte("\n"))
+	if string(bytes.TrimSpace(first)) != frontMatterDelimiter {
+		return nil, data, nil
+	}
+
+	for offset := 0; offset < len(rest); {
+		line, _, _ := bytes.Cut(rest[offset:], []byte("\n"))
+		if string(bytes.TrimSpace(line)) == frontMatterDelimiter {
+			end := min(offset+len(line)+1, len(rest))
+			return rest[:offset], rest[end:], nil
+		}
+		offset += len(line) + 1
+	}
+	return nil, nil, errors.New("метаданные не закрыты строкой ---")
+}
+
+// ParseRef разбирает ссылку name@version; без версии (или @latest) - 0, последняя версия
+func ParseRef(ref string) (name string, version int, err error) {
+	name, v, ok := strings.Cut(strings.TrimSpace(ref), "@")
+	if name == "" {
+		return "", 0, fmt.Errorf("пустое имя шаблона в ссылке %q", ref)
+	}
+	if !ok || v == "latest" {
+		return name, 0, nil
+	}
+	version, err = strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("неверная версия в ссылке %q: ожидается name@N, N >= 1", ref)
+	}
+	return name, version, nil
+}
+
+// Rendered промпт, построенный по шаблону
+type Rendered struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Hash    string `json:"hash"` // Хэш текста шаблона вместе с подключенными фрагментами
+	Text    string `json:"-"`
+}
+
+// Ref возвращает ссылку name@version
+func (r Rendered) Ref() string {
+	return fmt.Sprintf("%s@%d", r.Name, r.Version)
+}
+
+// String возвращает ссылку с хэшем для журналов: name@version#hash
+func (r Rendered) String() string {
+	if r.Name == "" {
+		return ""
+	}
+	return r.Ref() + "#" + r.Hash
+}
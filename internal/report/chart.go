@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Размеры горизонтальной столбчатой диаграммы, px
+const (
+	chartWidth  = 760
+	labelWidth  = 300
+	valueWidth  = 90
+	barHeight   = 18
+	barGap      = 6
+	titleHeight = 28
+	labelRunes  = 44
+)
+
+// Bar столбец диаграммы
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// Chart горизонтальная столбчатая диаграмма
+type Chart struct {
+	Title  string
+	Bars   []Bar
+	Format string // Формат подписи значения для fmt
+}
+
+// SVG возвращает диаграмму в виде встроенного SVG без внешних ресурсов
+// и скриптов; подписи экранируются
+func (c Chart) SVG() string {
+	var maxValue float64
+	for _, b := range c.Bars {
+		maxValue = max(maxValue, b.Value)
+	}
+
+	height := titleHeight + len(c.Bars)*(barHeight+barGap) + barGap
+	plot := float64(chartWidth - labelWidth - valueWidth)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s" font-family="sans-serif" font-size="12">`,
+		chartWidth, height, chartWidth, height, html.EscapeString(c.Title))
+	fmt.Fprintf(&sb, `<text x="0" y="16" font-size="14" font-weight="bold">%s</text>`, html.EscapeString(c.Title))
+
+	for i, b := range c.Bars {
+		y := titleHeight + i*(barHeight+barGap)
+		width := 0.0
+		if maxValue > 0 {
+			width = b.Value / maxValue * plot
+		}
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end"><title>%s</title>%s</text>`,
+			labelWidth-8, y+barHeight-5, html.EscapeString(b.Label), html.EscapeString(truncate(b.Label, labelRunes)))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="#4f81bd"/>`,
+			labelWidth, y, width, barHeight)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d">%s</text>`,
+			float64(labelWidth)+width+6, y+barHeight-5, html.EscapeString(fmt.Sprintf(c.Format, b.Value)))
+	}
+
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
+
+// truncate обрезает строку до n символов
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
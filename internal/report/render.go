@@ -0,0 +1,95 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/report.md.tmpl
+var markdownTemplate string
+
+//go:embed templates/report.html.tmpl
+var htmlTemplate string
+
+// Форматы отчета
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// Formats допустимые форматы отчета
+var Formats = []string{FormatMarkdown, FormatHTML}
+
+// funcs общие функции шаблонов
+var funcs = map[string]any{
+	"date":  formatDate,
+	"money": func(v float64) string { return fmt.Sprintf("$%.6f", v) },
+}
+
+var (
+	markdown = template.Must(template.New("report.md").Funcs(funcs).Funcs(template.FuncMap{
+		"cell":    markdownCell,
+		"fence":   fence,
+		"oneLine": oneLine,
+	}).Parse(markdownTemplate))
+
+	page = htmltemplate.Must(htmltemplate.New("report.html").Funcs(funcs).Funcs(htmltemplate.FuncMap{
+		// SVG диаграмм строится из экранированных подписей и чисел
+		"svg": func(c Chart) htmltemplate.HTML { return htmltemplate.HTML(c.SVG()) },
+	}).Parse(htmlTemplate))
+)
+
+// Write выводит отчет в формате format (md или html)
+func Write(w io.Writer, r *Report, format string) error {
+	var err error
+	switch format {
+	case FormatMarkdown:
+		err = markdown.Execute(w, r)
+	case FormatHTML:
+		err = page.Execute(w, r)
+	default:
+		return fmt.Errorf("неизвестный формат отчета %q (допустимо: %s)", format, strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка построения отчета %s: %w", format, err)
+	}
+	return nil
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// markdownCell готовит текст для ячейки таблицы markdown
+func markdownCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", `\|`)
+}
+
+// oneLine заменяет переводы строк пробелами
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// fence оборачивает текст в блок кода, ограничитель длиннее любой
+// последовательности обратных кавычек внутри текста
+func fence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	marker := strings.Repeat("`", max(3, longest+1))
+	return marker + "text\n" + strings.TrimRight(s, "\n") + "\n" + marker
+}
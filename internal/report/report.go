@@ -0,0 +1,196 @@
+// Package report - отчеты по сохраненным запускам экспериментов: markdown
+// и самостоятельная HTML-страница с таблицами сравнения, графиками стоимости
+// и времени во встроенном SVG и ответами моделей. Внешних ресурсов нет.
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/eval"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/experiment"
+)
+
+// Report данные отчета по одному запуску
+type Report struct {
+	Title     string
+	Run       experiment.Run
+	Generated time.Time
+
+	Sections []Section // По одному на промпт
+
+	CostChart    Chart // Стоимость каждой ячейки
+	LatencyChart Chart // Среднее время ответа каждой ячейки
+}
+
+// Section сравнение ячеек одного промпта: строки отличаются системным
+// промптом, историей, моделью, температурой или max_tokens
+type Section struct {
+	Prompt     string
+	PromptText string
+	Evaluators []string // Проверки в порядке первого появления (столбцы таблицы)
+	Rows       []Row
+}
+
+// Row строка таблицы сравнения: сводка по повторам ячейки и сами ответы
+type Row struct {
+	Label string // Параметры, которыми ячейка отличается от остальных
+	experiment.Summary
+
+	CostPer1K float64  // Стоимость 1000 токенов
+	Scores    []string // Средние значения проверок по столбцам Section.Evaluators ("" - нет)
+	Responses []Response
+}
+
+// Response ответ одного повтора
+type Response struct {
+	experiment.Result
+	Failed []eval.Score // Непройденные проверки
+}
+
+// Build собирает отчет по запуску и его результатам
+func Build(run experiment.Run, results []experiment.Result) *Report {
+	r := &Report{
+		Title:     "Эксперимент: " + run.Experiment,
+		Run:       run,
+		Generated: time.Now(),
+	}
+
+	responses := make(map[string][]Response)
+	for _, res := range results {
+		var failed []eval.Score
+		for _, s := range res.Scores {
+			if !s.Pass {
+				failed = append(failed, s)
+			}
+		}
+		responses[res.Key()] = append(responses[res.Key()], Response{Result: res, Failed: failed})
+	}
+
+	// Сводки по промптам в порядке первого появления
+	var prompts []string
+	byPrompt := make(map[string][]experiment.Summary)
+	for _, s := range experiment.Summarize(results) {
+		if _, ok := byPrompt[s.Prompt]; !ok {
+			prompts = append(prompts, s.Prompt)
+		}
+		byPrompt[s.Prompt] = append(byPrompt[s.Prompt], s)
+	}
+
+	var costBars, latencyBars []Bar
+	for _, prompt := range prompts {
+		summaries := byPrompt[prompt]
+		section := Section{
+			Prompt:     prompt,
+			PromptText: summaries[0].PromptText,
+			Evaluators: evaluators(summaries),
+		}
+		vary := varyingParams(summaries)
+
+		for _, s := range summaries {
+			row := Row{
+				Label:     rowLabel(s.Cell, vary),
+				Summary:   s,
+				Scores:    rowScores(s, section.Evaluators),
+				Responses: responses[s.Key()],
+			}
+			if tokens := s.AvgTotalTokens * float64(s.Runs-s.Errors); tokens > 0 {
+				row.CostPer1K = s.TotalCost / tokens * 1000
+			}
+			section.Rows = append(section.Rows, row)
+
+			label := prompt + " · " + row.Label
+			costBars = append(costBars, Bar{Label: label, Value: s.TotalCost})
+			latencyBars = append(latencyBars, Bar{Label: label, Value: s.AvgLatencyMs})
+		}
+		r.Sections = append(r.Sections, section)
+	}
+
+	r.CostChart = Chart{Title: "Стоимость ячейки, $", Bars: costBars, Format: "$%.6f"}
+	r.LatencyChart = Chart{Title: "Среднее время ответа, мс", Bars: latencyBars, Format: "%.0f"}
+	return r
+}
+
+// evaluators имена проверок в порядке первого появления
+func evaluators(summaries []experiment.Summary) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range summaries {
+		for _, score := range s.Scores {
+			if !seen[score.Evaluator] {
+				seen[score.Evaluator] = true
+				names = append(names, score.Evaluator)
+			}
+		}
+	}
+	return names
+}
+
+func rowScores(s experiment.Summary, names []string) []string {
+	values := make(map[string]float64, len(s.Scores))
+	for _, score := range s.Scores {
+		values[score.Evaluator] = score.Value
+	}
+	scores := make([]string, len(names))
+	for i, name := range names {
+		if v, ok := values[name]; ok {
+			scores[i] = fmt.Sprintf("%.2f", v)
+		}
+	}
+	return scores
+}
+
+// params параметры ячейки, которые могут различаться внутри промпта
+type params struct {
+	system, history, model, temperature, maxTokens bool
+}
+
+// varyingParams определяет, какие параметры различаются между ячейками
+// промпта: подписи строк содержат только их
+func varyingParams(summaries []experiment.Summary) params {
+	var p params
+	if len(summaries) == 0 {
+		return p
+	}
+	first := summaries[0].Cell
+	for _, s := range summaries[1:] {
+		p.system = p.system || s.System != first.System
+		p.history = p.history || s.History != first.History
+		p.model = p.model || s.Model != first.Model
+		p.temperature = p.temperature || s.Temperature != first.Temperature
+		p.maxTokens = p.maxTokens || s.MaxTokens != first.MaxTokens
+	}
+	return p
+}
+
+func rowLabel(c experiment.Cell, vary params) string {
+	var parts []string
+	if vary.system {
+		parts = append(parts, "system="+orNone(c.System))
+	}
+	if vary.history {
+		parts = append(parts, "history="+orNone(c.History))
+	}
+	if vary.model {
+		parts = append(parts, c.Model)
+	}
+	if vary.temperature {
+		parts = append(parts, fmt.Sprintf("t=%.1f", c.Temperature))
+	}
+	if vary.maxTokens {
+		parts = append(parts, fmt.Sprintf("max=%d", c.MaxTokens))
+	}
+	if len(parts) == 0 {
+		// Одна ячейка на промпт
+		parts = append(parts, c.Model, fmt.Sprintf("t=%.1f", c.Temperature))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "нет"
+	}
+	return s
+}
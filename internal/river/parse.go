@@ -0,0 +1,253 @@
+package river
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Префиксы слов для разбора ответа (русские и английские формы)
+var (
+	// moveVerbs глаголы переправы: ход фермера
+	moveVerbs = []string{
+		"перевоз", "перевез", "перевёз", "вез", "вёз", "отвоз", "отвез", "отвёз",
+		"переправ", "переплыв", "плыв", "поплыв", "возвращ", "вернул", "вернуть", "вернув",
+		"доставл", "достав", "перенос", "перенес", "перенёс",
+		"bring", "brought", "carr", "cross", "return", "row", "ferr", "transport",
+	}
+	// takeVerbs глаголы, после которых груз сажают в лодку: ход - следующий глагол
+	// переправы или, если его нет, сам этот глагол
+	takeVerbs = []string{"бер", "брать", "взя", "возьм", "забир", "забер", "забра", "сажа", "посад", "take", "took"}
+	// itemWords названия грузов
+	itemWords = map[Item][]string{
+		Wolf:    {"волк", "wolf"},
+		Goat:    {"коз", "goat"},
+		Cabbage: {"капуст", "cabbage"},
+	}
+	// aloneWords фермер плывет без груза
+	aloneWords = []string{"один", "одна", "пуст", "порожн", "налегке", "alone", "empty", "himself", "herself"}
+	// conditions условные предложения - рассуждение, а не ход
+	conditions = map[string]bool{"если": true, "if": true}
+	// skipWords отрицание или цель перед глаголом: "не может перевезти", "чтобы забрать"
+	skipWords = map[string]bool{"не": true, "нельзя": true, "чтобы": true, "чтоб": true, "not": true, "cannot": true, "can't": true, "don't": true, "to": true}
+	// targetPrepositions груз после предлога - цель, а не груз ("возвращается за волком")
+	targetPrepositions = map[string]bool{"за": true, "for": true, "к": true, "to": true}
+)
+
+// stepStart строка, с которой начинается нумерованный список ходов
+var stepStart = regexp.MustCompile(`(?i)^[\s*#>-]*(?:шаг|ход|step|move)?\s*1\s*[.):]`)
+
+// Слова заголовка, которым ответ отмечает итоговый список ("Итоговое решение:", "Ответ:").
+// "Ответ" - только целым словом: "пример ответа" итогом не считается.
+var (
+	finalPrefixes = []string{"итог", "окончательн", "финальн", "final"}
+	finalWords    = map[string]bool{"ответ": true, "answer": true}
+)
+
+// ParseMoves извлекает последовательность ходов из текста ответа.
+// Ответ часто содержит несколько списков (пример, рассуждение по шагам, итог),
+// поэтому каждый нумерованный список разбирается отдельно и оценивается тот,
+// на котором ответ остановился: последний список под итоговым заголовком,
+// иначе - последний список, в котором нашлись ходы, иначе - весь текст.
+func ParseMoves(text string) []Item {
+	type block struct {
+		lines []string
+		final bool // Перед списком стоит итоговый заголовок
+	}
+	var blocks []block
+	var all []string
+	var heading string // Последняя непустая строка перед текущей
+	for _, line := range strings.Split(text, "\n") {
+		all = append(all, line)
+		if stepStart.MatchString(line) || len(blocks) == 0 {
+			blocks = append(blocks, block{final: hasFinalWord(heading)})
+		}
+		blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, line)
+		if strings.TrimSpace(line) != "" {
+			heading = line
+		}
+	}
+
+	var last, final []Item
+	for _, b := range blocks {
+		moves := parseLines(b.lines)
+		if len(moves) == 0 {
+			continue
+		}
+		last = moves
+		if b.final {
+			final = moves
+		}
+	}
+	if final != nil {
+		return final
+	}
+	if last != nil {
+		return last
+	}
+	return parseLines(all)
+}
+
+// hasFinalWord возвращает true для итогового заголовка
+func hasFinalWord(line string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(line), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if finalWords[word] || hasPrefix(word, finalPrefixes) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLines разбирает строки на предложения и находит в них ходы
+func parseLines(lines []string) []Item {
+	var moves []Item
+	for _, line := range lines {
+		for _, clause := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == '.' || r == ';' || r == ':' || r == '!' || r == '?'
+		}) {
+			moves = append(moves, parseClause(clause)...)
+		}
+	}
+	return moves
+}
+
+// parseClause находит ходы в одном предложении
+func parseClause(clause string) []Item {
+	words := strings.FieldsFunc(strings.ToLower(clause), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	var moves []Item
+	var pending Item // Груз, посаженный глаголом "берет"
+	for i, word := range words {
+		if conditions[word] {
+			return nil
+		}
+
+		isMove := hasPrefix(word, moveVerbs)
+		isTake := !isMove && isTakeVerb(word)
+		if !isMove && !isTake || skipped(words, i) {
+			continue
+		}
+
+		item := itemAfter(words[i+1:])
+		if isTake {
+			if item != "" && item != Nobody {
+				pending = item
+			}
+			continue
+		}
+
+		if item == "" {
+			item = pending
+		}
+		if item == "" {
+			item = Nobody
+		}
+		moves = append(moves, item)
+		pending = ""
+	}
+
+	if pending != "" {
+		moves = append(moves, pending)
+	}
+	return moves
+}
+
+// itemAfter возвращает груз после глагола до следующего глагола (пусто - не указан)
+func itemAfter(words []string) Item {
+	for i, word := range words {
+		if hasPrefix(word, moveVerbs) || isTakeVerb(word) {
+			return ""
+		}
+		if hasPrefix(word, aloneWords) || word == "сам" {
+			return Nobody
+		}
+		for _, item := range []Item{Wolf, Goat, Cabbage} {
+			if hasPrefix(word, itemWords[item]) {
+				if i > 0 && targetPrepositions[words[i-1]] {
+					return Nobody
+				}
+				return item
+			}
+		}
+	}
+	return ""
+}
+
+// isTakeVerb "берет", но не "берег"
+func isTakeVerb(word string) bool {
+	return hasPrefix(word, takeVerbs) && !strings.HasPrefix(word, "берег")
+}
+
+// skipped возвращает true, если перед глаголом (через одно слово) стоит отрицание или цель
+func skipped(words []string, i int) bool {
+	for j := max(0, i-2); j < i; j++ {
+		if skipWords[words[j]] {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(word string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(word, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTemplate шаблон промпта, который просит модель выписать ходы из ответа без исправлений.
+// Версия не закреплена: новая версия в prompts_dir заменяет встроенную.
+const extractTemplate = "river/extract"
+
+// Extract извлекает ходы из ответа дополнительным запросом со структурированным
+// выводом (JSON). Надежнее ParseMoves, но стоит одного запроса к API.
+// lib - библиотека с шаблоном river/extract (nil - встроенная).
+func Extract(ctx context.Context, c *client.OpenAIClient, lib *prompts.Library, answer string) ([]Item, error) {
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	prompt, err := lib.Render(extractTemplate, map[string]any{"answer": answer})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      prompt.Text,
+		Templates:   []string{prompt.String()},
+		MaxTokens:   200,
+		Temperature: client.Temperature(0), // Извлечение ходов, а не творчество
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Moves []string `json:"moves"`
+	}
+	if err := json.Unmarshal([]byte(resp.Content), &parsed); err != nil {
+		return nil, fmt.Errorf("ответ извлечения ходов не JSON: %w", err)
+	}
+
+	moves := make([]Item, 0, len(parsed.Moves))
+	for _, m := range parsed.Moves {
+		item, err := ParseItem(m)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, item)
+	}
+	return moves, nil
+}
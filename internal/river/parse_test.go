@@ -0,0 +1,83 @@
+package river_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/river"
+)
+
+// Кратчайшее решение и его запись в ответе модели
+var (
+	solution = []river.Item{river.Goat, river.Nobody, river.Wolf, river.Goat, river.Cabbage, river.Nobody, river.Goat}
+
+	solutionText = `1. Фермер перевозит козу на правый берег.
+2. Возвращается один.
+3. Перевозит волка.
+4. Возвращается с козой.
+5. Перевозит капусту.
+6. Возвращается за козой.
+7. Перевозит козу.`
+
+	wrongText = `1. Фермер перевозит волка.
+2. Возвращается один.
+3. Перевозит козу.`
+)
+
+func TestParseMoves(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []river.Item
+	}{
+		{"один список", solutionText, solution},
+		{"шаги с заголовком", "Шаг 1: перевозит козу\nШаг 2: возвращается один", []river.Item{river.Goat, river.Nobody}},
+		{"английский", "1. The farmer takes the goat across.\n2. He returns alone.", []river.Item{river.Goat, river.Nobody}},
+		{"текст без списка", "Сначала фермер перевозит козу, затем возвращается за волком.", []river.Item{river.Goat, river.Nobody}},
+		{"берет, затем плывет", "1. Фермер берет капусту и переплывает реку.", []river.Item{river.Cabbage}},
+		{"берет без глагола переправы", "1. Фермер берет козу.", []river.Item{river.Goat}},
+		{"берег не глагол", "1. На левом берегу фермер сажает козу в лодку и плывет.", []river.Item{river.Goat}},
+		{"возвращается за грузом", "1. Фермер возвращается за волком.", []river.Item{river.Nobody}},
+		{"возвращается с грузом", "1. Фермер возвращается с волком.", []river.Item{river.Wolf}},
+		{"пустая лодка", "1. Фермер плывет обратно пустым.\n2. Переправляется налегке.", []river.Item{river.Nobody, river.Nobody}},
+		{"отрицание пропускается", "1. Фермер не может перевезти волка, поэтому перевозит козу.", []river.Item{river.Goat}},
+		{"цель пропускается", "1. Фермер плывет обратно, чтобы забрать волка.", []river.Item{river.Nobody}},
+		{"условие пропускается", "Если фермер перевезет волка, коза съест капусту.\n1. Фермер перевозит козу.", []river.Item{river.Goat}},
+		{"последний из нескольких списков", solutionText + "\n\nТеперь короче:\n" + wrongText, []river.Item{river.Wolf, river.Nobody, river.Goat}},
+		{"пример, затем неверный план", "Пример ответа:\n" + solutionText + "\n\nМой план:\n" + wrongText, []river.Item{river.Wolf, river.Nobody, river.Goat}},
+		{"итоговый список", wrongText + "\n\nИтоговое решение:\n" + solutionText, solution},
+		{"итог важнее последнего списка", "Ответ:\n" + solutionText + "\n\nНеудачная попытка:\n" + wrongText, solution},
+		{"список без ходов не выбирается", solutionText + "\n\nПроверка:\n1. Все на правом берегу.\n2. Никто никого не съел.", solution},
+		{"пустой ответ", "", nil},
+		{"нет ходов", "Задача не имеет решения.", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := river.ParseMoves(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseMoves = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMovesVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want river.Status
+	}{
+		{"верное решение", solutionText, river.StatusValid},
+		{"верный пример и неверный итог", "Например:\n" + solutionText + "\n\nОкончательный ответ:\n" + wrongText, river.StatusInvalid},
+		{"неверный черновик и верный итог", wrongText + "\n\nИтог:\n" + solutionText, river.StatusValid},
+		{"без ходов", "Не знаю.", river.StatusUnparseable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v := river.Verify(river.ParseMoves(tt.text)); v.Status != tt.want {
+				t.Errorf("Status = %s, ожидалось %s (%s)", v.Status, tt.want, v)
+			}
+		})
+	}
+}
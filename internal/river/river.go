@@ -0,0 +1,195 @@
+// Package river - задача о волке, козе и капусте: правила переправы,
+// поиск кратчайшего решения и проверка последовательности ходов из ответа модели
+package river
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Item кого фермер везет в лодке
+type Item string
+
+const (
+	Nobody  Item = "none" // Фермер плывет один
+	Wolf    Item = "wolf"
+	Goat    Item = "goat"
+	Cabbage Item = "cabbage"
+)
+
+// Items все возможные ходы
+var Items = []Item{Nobody, Wolf, Goat, Cabbage}
+
+// Name возвращает название груза по-русски
+func (i Item) Name() string {
+	switch i {
+	case Wolf:
+		return "волк"
+	case Goat:
+		return "коза"
+	case Cabbage:
+		return "капуста"
+	case Nobody:
+		return "никого"
+	}
+	return string(i)
+}
+
+// ParseItem разбирает имя хода (none, wolf, goat, cabbage)
+func ParseItem(s string) (Item, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, item := range Items {
+		if s == string(item) {
+			return item, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный ход %q (допустимо: none, wolf, goat, cabbage)", s)
+}
+
+// Bank берег реки
+type Bank int
+
+const (
+	Left  Bank = iota // Исходный берег
+	Right             // Целевой берег
+)
+
+func (b Bank) other() Bank {
+	return 1 - b
+}
+
+func (b Bank) String() string {
+	if b == Left {
+		return "левый"
+	}
+	return "правый"
+}
+
+// at возвращает "на левом берегу" или "на правом берегу"
+func (b Bank) at() string {
+	if b == Left {
+		return "на левом берегу"
+	}
+	return "на правом берегу"
+}
+
+// State положение фермера и грузов
+type State struct {
+	Farmer  Bank
+	Wolf    Bank
+	Goat    Bank
+	Cabbage Bank
+}
+
+var (
+	// Start все на левом берегу
+	Start = State{}
+	// Goal все на правом берегу
+	Goal = State{Farmer: Right, Wolf: Right, Goat: Right, Cabbage: Right}
+)
+
+// bank возвращает берег груза
+func (s State) bank(item Item) Bank {
+	switch item {
+	case Wolf:
+		return s.Wolf
+	case Goat:
+		return s.Goat
+	case Cabbage:
+		return s.Cabbage
+	}
+	return s.Farmer
+}
+
+// Conflict возвращает описание нарушения правил (пусто - состояние безопасно)
+func (s State) Conflict() string {
+	if s.Wolf == s.Goat && s.Farmer != s.Goat {
+		return "волк съест козу " + s.Goat.at()
+	}
+	if s.Goat == s.Cabbage && s.Farmer != s.Goat {
+		return "коза съест капусту " + s.Goat.at()
+	}
+	return ""
+}
+
+// Apply выполняет переправу фермера с грузом. Ошибка - груз на другом берегу
+// или после переправы кто-то кого-то съест.
+func (s State) Apply(item Item) (State, error) {
+	if item != Nobody && s.bank(item) != s.Farmer {
+		return s, fmt.Errorf("%s %s, а фермер %s", item.Name(), s.bank(item).at(), s.Farmer.at())
+	}
+
+	to := s.Farmer.other()
+	next := s
+	next.Farmer = to
+	switch item {
+	case Wolf:
+		next.Wolf = to
+	case Goat:
+		next.Goat = to
+	case Cabbage:
+		next.Cabbage = to
+	}
+
+	if conflict := next.Conflict(); conflict != "" {
+		return s, errors.New(conflict)
+	}
+	return next, nil
+}
+
+// Moves возвращает допустимые из состояния ходы
+func (s State) Moves() []Item {
+	var moves []Item
+	for _, item := range Items {
+		if _, err := s.Apply(item); err == nil {
+			moves = append(moves, item)
+		}
+	}
+	return moves
+}
+
+// Left возвращает грузы, оставшиеся на исходном берегу
+func (s State) Left() []string {
+	var names []string
+	for _, item := range []Item{Wolf, Goat, Cabbage} {
+		if s.bank(item) == Left {
+			names = append(names, item.Name())
+		}
+	}
+	return names
+}
+
+// Solve ищет кратчайшее решение поиском в ширину (nil - решения нет)
+func Solve(from State) []Item {
+	type step struct {
+		prev State
+		item Item
+	}
+
+	visited := map[State]step{from: {}}
+	queue := []State{from}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		if s == Goal {
+			var path []Item
+			for s != from {
+				st := visited[s]
+				path = append([]Item{st.item}, path...)
+				s = st.prev
+			}
+			return path
+		}
+
+		for _, item := range s.Moves() {
+			next, _ := s.Apply(item)
+			if _, ok := visited[next]; !ok {
+				visited[next] = step{prev: s, item: item}
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package river
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status итог проверки ответа
+type Status string
+
+const (
+	StatusValid       Status = "valid"       // Все ходы допустимы, все на правом берегу
+	StatusInvalid     Status = "invalid"     // Недопустимый ход или решение не доведено до конца
+	StatusUnparseable Status = "unparseable" // В ответе не найдена последовательность ходов
+)
+
+// Verdict результат проверки последовательности ходов
+type Verdict struct {
+	Status   Status `json:"status"`
+	Moves    []Item `json:"moves,omitempty"`
+	Step     int    `json:"step,omitempty"` // Номер ошибочного хода с 1 (invalid)
+	Reason   string `json:"reason,omitempty"`
+	Shortest int    `json:"shortest"` // Длина кратчайшего решения
+	Optimal  bool   `json:"optimal"`  // Решение не длиннее кратчайшего
+}
+
+// shortest длина кратчайшего решения из начального состояния
+var shortest = len(Solve(Start))
+
+// Verify проверяет ходы из начального состояния по правилам задачи
+func Verify(moves []Item) Verdict {
+	v := Verdict{Moves: moves, Shortest: shortest}
+	if len(moves) == 0 {
+		v.Status = StatusUnparseable
+		v.Reason = "в ответе не найдена последовательность переправ"
+		return v
+	}
+
+	state := Start
+	for i, item := range moves {
+		next, err := state.Apply(item)
+		if err != nil {
+			v.Status = StatusInvalid
+			v.Step = i + 1
+			v.Reason = err.Error()
+			return v
+		}
+		state = next
+	}
+
+	if state != Goal {
+		v.Status = StatusInvalid
+		v.Step = len(moves)
+		v.Reason = "ходы закончились, а на левом берегу остались: " + strings.Join(state.Left(), ", ")
+		if len(state.Left()) == 0 {
+			v.Reason = "ходы закончились, а фермер остался на левом берегу"
+		}
+		return v
+	}
+
+	v.Status = StatusValid
+	v.Optimal = len(moves) <= shortest
+	return v
+}
+
+// Correct возвращает true для верного решения
+func (v Verdict) Correct() bool {
+	return v.Status == StatusValid
+}
+
+// String краткое описание результата проверки
+func (v Verdict) String() string {
+	switch v.Status {
+	case StatusValid:
+		if v.Optimal {
+			return fmt.Sprintf("верно, %d ходов (кратчайшее)", len(v.Moves))
+		}
+		return fmt.Sprintf("верно, %d ходов (кратчайшее - %d)", len(v.Moves), v.Shortest)
+	case StatusInvalid:
+		return fmt.Sprintf("ошибка на ходу %d: %s", v.Step, v.Reason)
+	}
+	return "не разобрано: " + v.Reason
+}
@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+)
+
+// commandTimeout сколько ждать команду получения ключа (pass может запросить пароль GPG)
+const commandTimeout = time.Minute
+
+// runCommand выполняет api_key_cmd через оболочку и возвращает первую строку вывода
+func runCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Вывод команды может содержать ключ - маскируем его
+		message := redact.String(strings.TrimSpace(stderr.String()))
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("api_key_cmd завершилась с ошибкой: %s", message)
+	}
+
+	// pass и подобные хранят ключ в первой строке
+	key, _, _ := strings.Cut(stdout.String(), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("api_key_cmd не вывела ключ")
+	}
+	return key, nil
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// fileVersion версия формата зашифрованного файла
+const fileVersion = 1
+
+// Параметры scrypt (рекомендованные для интерактивного входа)
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// ErrWrongPassphrase неверный пароль или поврежденный файл
+var ErrWrongPassphrase = errors.New("неверный пароль или файл секретов поврежден")
+
+// encryptedFile формат файла: ключ шифрования выводится из пароля через scrypt,
+// секреты (JSON) шифруются AES-256-GCM
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// ReadFile расшифровывает файл секретов
+func ReadFile(path, passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла секретов: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: ошибка разбора файла секретов: %w", path, err)
+	}
+	if file.Version != fileVersion || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("%s: неподдерживаемый формат файла секретов (версия %d, kdf %q)", path, file.Version, file.KDF)
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrWrongPassphrase)
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("%s: ошибка разбора секретов: %w", path, err)
+	}
+	return values, nil
+}
+
+// WriteFile шифрует секреты паролем и сохраняет их с правами 0600
+func WriteFile(path, passphrase string, values map[string]string) error {
+	if passphrase == "" {
+		return fmt.Errorf("пароль не может быть пустым")
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации секретов: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(encryptedFile{
+		Version:    fileVersion,
+		KDF:        "scrypt",
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+	if err := os.WriteFile(path, jsonData, 0600); err != nil {
+		return fmt.Errorf("ошибка записи файла секретов: %w", err)
+	}
+	return nil
+}
+
+// newGCM выводит ключ из пароля и создает шифр AES-GCM
+func newGCM(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка вывода ключа: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
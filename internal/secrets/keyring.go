@@ -0,0 +1,48 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringService имя сервиса в связке ключей ОС
+const KeyringService = "ai-advent"
+
+// ErrKeyringUnavailable связка ключей ОС недоступна (нет Secret Service, сессии D-Bus и т.п.)
+var ErrKeyringUnavailable = errors.New("связка ключей недоступна")
+
+// KeyringGet читает секрет из связки ключей ОС
+// (Secret Service в Linux, Keychain в macOS, Credential Manager в Windows)
+func KeyringGet(name string) (string, error) {
+	value, err := keyring.Get(KeyringService, name)
+	if err != nil {
+		return "", keyringError(err)
+	}
+	return value, nil
+}
+
+// KeyringSet сохраняет секрет в связке ключей ОС
+func KeyringSet(name, value string) error {
+	return keyringError(keyring.Set(KeyringService, name, value))
+}
+
+// KeyringDelete удаляет секрет из связки ключей ОС
+func KeyringDelete(name string) error {
+	return keyringError(keyring.Delete(KeyringService, name))
+}
+
+// keyringError приводит ошибки go-keyring к ErrNotFound и ErrKeyringUnavailable
+func keyringError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, keyring.ErrNotFound):
+		return fmt.Errorf("%w в связке ключей", ErrNotFound)
+	case errors.Is(err, keyring.ErrUnsupportedPlatform):
+		return ErrKeyringUnavailable
+	}
+	// Прочие ошибки - как правило, отсутствие Secret Service или D-Bus
+	return fmt.Errorf("%w: %v", ErrKeyringUnavailable, err)
+}
@@ -0,0 +1,123 @@
+// Package secrets загружает ключ API из окружения, команды, связки ключей ОС
+// или зашифрованного файла и регистрирует его для маскирования (pkg/redact).
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+	"golang.org/x/term"
+)
+
+// APIKeyName имя ключа OpenAI в связке ключей и зашифрованном файле
+const APIKeyName = "openai_api_key"
+
+// ErrNotFound ключ не найден ни в одном источнике
+var ErrNotFound = errors.New("ключ API не найден")
+
+// Config источники ключа API
+type Config struct {
+	APIKeyCmd string `yaml:"api_key_cmd"` // Команда, печатающая ключ (например, "pass show openai")
+	File      string `yaml:"file"`        // Зашифрованный файл (пусто - DefaultFile)
+	Keyring   bool   `yaml:"keyring"`     // Искать ключ в связке ключей ОС
+}
+
+// DefaultFile возвращает путь к зашифрованному файлу по умолчанию
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ai-advent", "secrets.enc")
+}
+
+// file возвращает путь к зашифрованному файлу
+func (c Config) file() string {
+	if c.File != "" {
+		return c.File
+	}
+	return DefaultFile()
+}
+
+// APIKey ищет ключ по порядку: OPENAI_API_KEY, api_key_cmd, связка ключей ОС,
+// зашифрованный файл. Возвращает ключ и название источника.
+// Найденный ключ регистрируется в redact и дальше маскируется при выводе.
+func APIKey(config Config) (key, source string, err error) {
+	defer func() {
+		if key != "" {
+			redact.Register(key)
+		}
+		err = redact.Error(err)
+	}()
+
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		return key, "OPENAI_API_KEY", nil
+	}
+
+	if config.APIKeyCmd != "" {
+		key, err := runCommand(config.APIKeyCmd)
+		if err != nil {
+			return "", "", err
+		}
+		return key, "api_key_cmd", nil
+	}
+
+	if config.Keyring {
+		key, err := KeyringGet(APIKeyName)
+		switch {
+		case err == nil:
+			return key, "связка ключей", nil
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrKeyringUnavailable):
+			// Переходим к следующему источнику
+		default:
+			return "", "", err
+		}
+	}
+
+	path := config.file()
+	if path != "" {
+		if _, statErr := os.Stat(path); statErr == nil {
+			passphrase, err := Passphrase("Пароль к " + path + ": ")
+			if err != nil {
+				return "", "", err
+			}
+			values, err := ReadFile(path, passphrase)
+			if err != nil {
+				return "", "", err
+			}
+			if key := values[APIKeyName]; key != "" {
+				return key, path, nil
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: задайте OPENAI_API_KEY, api_key_cmd, ключ в связке ключей или зашифрованный файл %s", ErrNotFound, path)
+}
+
+// Passphrase возвращает пароль из SECRETS_PASSPHRASE или запрашивает его в терминале без эха
+func Passphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv("SECRETS_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	return ReadHidden(prompt)
+}
+
+// ReadHidden читает строку из терминала без эха
+func ReadHidden(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("нет терминала для ввода пароля: задайте SECRETS_PASSPHRASE")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения из терминала: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,204 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/tokenizer"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Meter учет запросов к API в одном месте: до отправки - проверка бюджета,
+// после ответа - списание с бюджета, запись в журнал использования и метрики.
+// Любая часть может отсутствовать; nil *Meter только считает стоимость.
+// Через Meter идут клиент, агент, суммаризатор, извлечение фактов и эмбеддинги.
+type Meter struct {
+	ledger  *usage.Ledger
+	budget  *usage.Budget
+	metrics *Metrics
+	session string
+	command string
+
+	mu     sync.Mutex
+	warned map[string]bool // Уже выведенные предупреждения
+
+	// OnWarning вызывается, если запрос выполнен, но учтен не полностью
+	// (по умолчанию utils.PrintWarning, одно сообщение - один раз)
+	OnWarning func(message string)
+}
+
+// NewMeter создает учет запросов сессии session для команды command;
+// ledger - журнал использования (nil - без журнала)
+func NewMeter(ledger *usage.Ledger, session, command string) *Meter {
+	return &Meter{
+		ledger:    ledger,
+		session:   session,
+		command:   command,
+		warned:    make(map[string]bool),
+		OnWarning: utils.PrintWarning,
+	}
+}
+
+// SetBudget подключает лимиты расходов: запрос, который может превысить лимит,
+// не отправляется и возвращает usage.ErrBudgetExceeded
+func (m *Meter) SetBudget(budget *usage.Budget) {
+	m.budget = budget
+}
+
+// SetMetrics подключает метрики Prometheus: каждый запрос и каждая ошибка
+// учитываются в них
+func (m *Meter) SetMetrics(metrics *Metrics) {
+	m.metrics = metrics
+}
+
+// Metrics возвращает подключенные метрики (nil - метрики отключены)
+func (m *Meter) Metrics() *Metrics {
+	if m == nil {
+		return nil
+	}
+	return m.metrics
+}
+
+// Check проверяет, укладывается ли запрос в бюджет при максимальном размере ответа
+// (maxTokens, 0 - максимум модели). Без бюджета всегда nil; для модели, которой нет
+// в каталоге, при заданном бюджете возвращает models.ErrUnknownModel.
+func (m *Meter) Check(model string, messages []openai.ChatCompletionMessage, maxTokens int) error {
+	if m == nil || m.budget == nil {
+		return nil
+	}
+	return m.check(model, tokenizer.CountMessages(model, messages), maxTokens)
+}
+
+func (m *Meter) check(model string, promptTokens, maxTokens int) error {
+	info, err := models.Lookup(model)
+	if err == nil {
+		err = m.budget.Check(usage.Project(info, promptTokens, maxTokens))
+	}
+	if err != nil {
+		m.Fail(model, err)
+	}
+	return err
+}
+
+// Record учитывает выполненный запрос к модели model: стоимость по каталогу,
+// списание с бюджета, метрики и журнал. prompt - шаблоны промпта (name@version#hash).
+// Запрос учитывается и без модели в каталоге - со стоимостью 0; ошибка каталога
+// или записи в журнал возвращается как предупреждение вместе с записью.
+func (m *Meter) Record(model, prompt string, resp openai.ChatCompletionResponse, latency time.Duration) (usage.Record, error) {
+	var session, command string
+	if m != nil {
+		session, command = m.session, m.command
+	}
+	record := usage.FromResponse(session, command, resp, latency, 0)
+	record.Prompt = prompt
+	return record, m.record(model, &record)
+}
+
+// record дополняет запись стоимостью и учитывает ее
+func (m *Meter) record(model string, record *usage.Record) error {
+	info, lookupErr := models.Lookup(model)
+	if lookupErr == nil {
+		record.Cost = info.Cost(record.PromptTokens, record.CachedTokens, record.CompletionTokens)
+	}
+	if m == nil {
+		return lookupErr
+	}
+
+	if m.metrics != nil {
+		m.metrics.ObserveRequest(model, *record)
+	}
+	if m.budget != nil {
+		m.budget.Spend(record.TotalTokens(), record.Cost)
+	}
+	if m.ledger != nil {
+		if err := m.ledger.Append(*record); err != nil {
+			return err
+		}
+	}
+	return lookupErr
+}
+
+// Fail учитывает неуспешный запрос в метриках
+func (m *Meter) Fail(model string, err error) {
+	if m != nil && m.metrics != nil {
+		m.metrics.ObserveError(model, err)
+	}
+}
+
+// ChatCompletion отправляет запрос через ChatCompletion с проверкой бюджета до
+// отправки и учетом ответа. Предупреждения учета выводятся через OnWarning.
+func (m *Meter) ChatCompletion(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest, prompt string) (openai.ChatCompletionResponse, usage.Record, error) {
+	if err := m.Check(req.Model, req.Messages, req.MaxTokens); err != nil {
+		return openai.ChatCompletionResponse{}, usage.Record{}, err
+	}
+
+	start := time.Now()
+	resp, err := ChatCompletion(ctx, client, req)
+	if err != nil {
+		m.Fail(req.Model, err)
+		return resp, usage.Record{}, err
+	}
+
+	record, err := m.Record(req.Model, prompt, resp, time.Since(start))
+	m.Warn(err)
+	return resp, record, nil
+}
+
+// CreateEmbeddings запрашивает эмбеддинги с проверкой бюджета и учетом токенов
+// (у эмбеддингов нет ответа: учитываются только токены запроса)
+func (m *Meter) CreateEmbeddings(ctx context.Context, client *openai.Client, req openai.EmbeddingRequest) (openai.EmbeddingResponse, error) {
+	model := string(req.Model)
+	if m != nil && m.budget != nil {
+		promptTokens := 0
+		if texts, ok := req.Input.([]string); ok {
+			for _, text := range texts {
+				promptTokens += tokenizer.Count(model, text)
+			}
+		}
+		if err := m.check(model, promptTokens, 1); err != nil {
+			return openai.EmbeddingResponse{}, err
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.CreateEmbeddings(ctx, req)
+	if err != nil {
+		m.Fail(model, err)
+		return resp, err
+	}
+
+	record := usage.Record{
+		Time:         time.Now(),
+		Model:        model,
+		PromptTokens: resp.Usage.PromptTokens,
+		LatencyMs:    time.Since(start).Milliseconds(),
+	}
+	if m != nil {
+		record.Session, record.Command = m.session, m.command
+	}
+	m.Warn(m.record(model, &record))
+	return resp, nil
+}
+
+// Warn выводит предупреждение учета один раз на сообщение
+func (m *Meter) Warn(err error) {
+	if m == nil || err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	message := "Учет использования API: " + err.Error()
+	if m.warned[message] {
+		return
+	}
+	m.warned[message] = true
+	if m.OnWarning != nil {
+		m.OnWarning(message)
+	}
+}
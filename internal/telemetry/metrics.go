@@ -0,0 +1,242 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/usage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// namespace префикс имен метрик
+const namespace = "agent"
+
+// Классы ошибок для метрики agent_errors_total
+const (
+	ErrorBudget        = "budget"         // Запрос отклонен бюджетом
+	ErrorUnknownModel  = "unknown_model"  // Модели нет в каталоге
+	ErrorRateLimit     = "rate_limit"     // 429
+	ErrorAuth          = "auth"           // 401, 403
+	ErrorContextLength = "context_length" // Превышен контекст модели
+	ErrorBadRequest    = "bad_request"    // Прочие 4xx
+	ErrorServer        = "server"         // 5xx
+	ErrorTimeout       = "timeout"        // Истек таймаут
+	ErrorNetwork       = "network"        // Сетевая ошибка
+	ErrorOther         = "other"
+)
+
+// Metrics метрики использования API в формате Prometheus.
+// Все метрики размечены моделью и сессией.
+type Metrics struct {
+	session  string
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	promptTokens     *prometheus.CounterVec
+	completionTokens *prometheus.CounterVec
+	cachedTokens     *prometheus.CounterVec
+	cost             *prometheus.CounterVec
+	latency          *prometheus.HistogramVec
+	errors           *prometheus.CounterVec
+	contextUsage     *prometheus.GaugeVec
+	contextTokens    *prometheus.GaugeVec
+	originalTokens   *prometheus.GaugeVec
+	compressedTokens *prometheus.GaugeVec
+	compressionRatio *prometheus.GaugeVec
+}
+
+// NewMetrics создает метрики сессии в отдельном реестре
+func NewMetrics(session string) *Metrics {
+	labels := []string{"model", "session"}
+
+	m := &Metrics{
+		session:  session,
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "requests_total",
+			Help: "Успешные запросы к API",
+		}, labels),
+		promptTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "prompt_tokens_total",
+			Help: "Токены в запросах",
+		}, labels),
+		completionTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "completion_tokens_total",
+			Help: "Токены в ответах",
+		}, labels),
+		cachedTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cached_tokens_total",
+			Help: "Токены запросов, взятые из кэша",
+		}, labels),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cost_usd_total",
+			Help: "Стоимость запросов в долларах",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "request_duration_seconds",
+			Help:    "Время ответа API",
+			Buckets: []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "errors_total",
+			Help: "Неуспешные запросы по классам ошибок",
+		}, append(labels, "class")),
+		contextUsage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "context_usage_ratio",
+			Help: "Доля окна контекста модели, занятая последним запросом",
+		}, labels),
+		contextTokens: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "context_tokens",
+			Help: "Токены в контексте последнего запроса",
+		}, labels),
+		originalTokens: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "history_original_tokens",
+			Help: "Токены полной истории диалога без сжатия",
+		}, labels),
+		compressedTokens: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "history_compressed_tokens",
+			Help: "Токены истории после сжатия (summary плюс несжатые сообщения)",
+		}, labels),
+		compressionRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "history_compression_savings_ratio",
+			Help: "Доля токенов истории, сэкономленная сжатием",
+		}, labels),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.promptTokens, m.completionTokens, m.cachedTokens, m.cost,
+		m.latency, m.errors, m.contextUsage, m.contextTokens,
+		m.originalTokens, m.compressedTokens, m.compressionRatio,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// ObserveRequest учитывает успешный запрос
+func (m *Metrics) ObserveRequest(model string, record usage.Record) {
+	m.requests.WithLabelValues(model, m.session).Inc()
+	m.promptTokens.WithLabelValues(model, m.session).Add(float64(record.PromptTokens))
+	m.completionTokens.WithLabelValues(model, m.session).Add(float64(record.CompletionTokens))
+	m.cachedTokens.WithLabelValues(model, m.session).Add(float64(record.CachedTokens))
+	m.cost.WithLabelValues(model, m.session).Add(record.Cost)
+	m.latency.WithLabelValues(model, m.session).Observe(float64(record.LatencyMs) / 1000)
+}
+
+// ObserveError учитывает неуспешный запрос
+func (m *Metrics) ObserveError(model string, err error) {
+	m.errors.WithLabelValues(model, m.session, ErrorClass(err)).Inc()
+}
+
+// SetContextUsage обновляет заполненность окна контекста модели
+func (m *Metrics) SetContextUsage(model string, tokens, contextWindow int) {
+	m.contextTokens.WithLabelValues(model, m.session).Set(float64(tokens))
+	if contextWindow > 0 {
+		m.contextUsage.WithLabelValues(model, m.session).Set(float64(tokens) / float64(contextWindow))
+	}
+}
+
+// SetCompression обновляет экономию от сжатия истории
+func (m *Metrics) SetCompression(model string, originalTokens, compressedTokens int) {
+	m.originalTokens.WithLabelValues(model, m.session).Set(float64(originalTokens))
+	m.compressedTokens.WithLabelValues(model, m.session).Set(float64(compressedTokens))
+
+	ratio := 0.0
+	if originalTokens > 0 {
+		ratio = float64(originalTokens-compressedTokens) / float64(originalTokens)
+	}
+	m.compressionRatio.WithLabelValues(model, m.session).Set(ratio)
+}
+
+// Handler возвращает HTTP обработчик для /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve запускает HTTP сервер с /metrics в фоне.
+// Возвращает сервер, чтобы его можно было остановить через Shutdown.
+func (m *Metrics) Serve(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть адрес метрик %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go server.Serve(listener)
+
+	return server, nil
+}
+
+// ErrorClass относит ошибку запроса к одному из классов Error*
+func ErrorClass(err error) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, usage.ErrBudgetExceeded):
+		return ErrorBudget
+	case errors.Is(err, models.ErrUnknownModel):
+		return ErrorUnknownModel
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.As(err, &apiErr):
+		if apiErr.Code == "context_length_exceeded" {
+			return ErrorContextLength
+		}
+		return statusClass(apiErr.HTTPStatusCode)
+	case errors.As(err, &reqErr):
+		return statusClass(reqErr.HTTPStatusCode)
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	return ErrorOther
+}
+
+// statusClass относит HTTP статус к классу ошибки
+func statusClass(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status >= 500:
+		return ErrorServer
+	case status >= 400:
+		return ErrorBadRequest
+	}
+	return ErrorOther
+}
+
+// StartMetrics создает метрики и запускает /metrics на addr.
+// Если адрес не задан, возвращает nil (метрики отключены).
+func StartMetrics(addr, session string) (*Metrics, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	metrics := NewMetrics(session)
+	if _, err := metrics.Serve(addr); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -0,0 +1,184 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName имя инструментирующей библиотеки
+const tracerName = "github.com/georgijter-grigoranc/ai-advent-challenge"
+
+// Атрибуты спанов (по соглашениям OpenTelemetry для генеративного AI)
+const (
+	AttrRequestModel     = attribute.Key("gen_ai.request.model")
+	AttrResponseModel    = attribute.Key("gen_ai.response.model")
+	AttrMaxTokens        = attribute.Key("gen_ai.request.max_tokens")
+	AttrTemperature      = attribute.Key("gen_ai.request.temperature")
+	AttrInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	AttrCachedTokens     = attribute.Key("gen_ai.usage.cached_tokens")
+	AttrFinishReason     = attribute.Key("gen_ai.response.finish_reasons")
+	AttrErrorClass       = attribute.Key("error.class")
+	AttrMessages         = attribute.Key("agent.messages")
+	AttrSummaryLevel     = attribute.Key("agent.summary.level")
+	AttrSummarizerModel  = attribute.Key("agent.summarizer.model")
+	AttrPendingTokens    = attribute.Key("agent.context.pending_tokens")
+	AttrFactsLearned     = attribute.Key("agent.memory.facts_learned")
+	AttrFallbackReason   = attribute.Key("agent.fallback.reason")
+	AttrHistoryMessages  = attribute.Key("agent.history.messages")
+	AttrSummaryRefreshed = attribute.Key("agent.summary.refreshed")
+	AttrPromptTemplates  = attribute.Key("gen_ai.prompt.templates")
+)
+
+// Экспортеры трассировки
+const (
+	ExporterNone   = ""       // Трассировка выключена
+	ExporterOTLP   = "otlp"   // OTLP/HTTP в коллектор (OTEL_EXPORTER_OTLP_ENDPOINT, по умолчанию localhost:4318)
+	ExporterStdout = "stdout" // JSON в стандартный вывод
+	ExporterFile   = "file"   // JSON в файл
+)
+
+// TracingConfig настройки экспорта трассировки
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"` // ExporterNone, ExporterOTLP, ExporterStdout или ExporterFile
+	File     string `yaml:"file"`     // Путь к файлу для ExporterFile
+}
+
+// Tracer возвращает трассировщик проекта (без SetupTracing спаны никуда не отправляются)
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// SetupTracing настраивает глобальный экспорт спанов. Возвращает функцию,
+// которая отправляет накопленные спаны и закрывает экспортер - ее нужно
+// вызвать перед выходом из программы.
+func SetupTracing(config TracingConfig, service string) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+
+	switch config.Exporter {
+	case ExporterNone:
+		return noop, nil
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			// Локальный коллектор без TLS
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		if config.File == "" {
+			return noop, fmt.Errorf("для экспорта трассировки в файл укажите путь к файлу")
+		}
+		file, openErr := os.OpenFile(config.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if openErr != nil {
+			return noop, fmt.Errorf("ошибка открытия файла трассировки: %w", openErr)
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return noop, fmt.Errorf("неизвестный экспортер трассировки %q (допустимо: otlp, stdout, file)", config.Exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("ошибка создания экспортера трассировки: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// ChatCompletion выполняет запрос к API внутри спана "chat <модель>"
+// с атрибутами модели, токенов и причины завершения
+func ChatCompletion(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	ctx, span := Tracer().Start(ctx, "chat "+req.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			AttrRequestModel.String(req.Model),
+			AttrMessages.Int(len(req.Messages)),
+			AttrTemperature.Float64(float32ToFloat64(req.Temperature)),
+		),
+	)
+	defer span.End()
+
+	if req.MaxTokens > 0 {
+		span.SetAttributes(AttrMaxTokens.Int(req.MaxTokens))
+	}
+
+	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		RecordError(span, err)
+		return resp, err
+	}
+
+	RecordResponse(span, resp)
+	return resp, nil
+}
+
+// RecordResponse добавляет к спану модель, токены и причину завершения ответа
+func RecordResponse(span trace.Span, resp openai.ChatCompletionResponse) {
+	span.SetAttributes(
+		AttrResponseModel.String(resp.Model),
+		AttrInputTokens.Int(resp.Usage.PromptTokens),
+		AttrOutputTokens.Int(resp.Usage.CompletionTokens),
+	)
+	if resp.Usage.PromptTokensDetails != nil {
+		span.SetAttributes(AttrCachedTokens.Int(resp.Usage.PromptTokensDetails.CachedTokens))
+	}
+
+	reasons := make([]string, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		reasons = append(reasons, string(choice.FinishReason))
+	}
+	if len(reasons) > 0 {
+		span.SetAttributes(AttrFinishReason.String(strings.Join(reasons, ",")))
+	}
+}
+
+// float32ToFloat64 переводит float32 без артефактов вроде 0.30000001
+func float32ToFloat64(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'f', -1, 32), 64)
+	return f
+}
+
+// RecordError помечает спан ошибкой и ее классом
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	err = redact.Error(err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(AttrErrorClass.String(ErrorClass(err)))
+}
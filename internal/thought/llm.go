@@ -0,0 +1,245 @@
+package thought
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/client"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/prompts"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Шаблоны промптов во встроенной библиотеке (можно переопределить в prompts_dir)
+const (
+	ExpandPrompt = "thought/expand@1" // Варианты следующего шага
+	ValuePrompt  = "thought/value@1"  // Оценка частичного решения
+)
+
+// finalMarker начало шага, который завершает решение
+const finalMarker = "ОТВЕТ:"
+
+// Thought шаг рассуждения в свободной форме
+type Thought struct {
+	Text  string
+	Final bool // Шаг завершает решение
+}
+
+// String возвращает текст шага
+func (t Thought) String() string {
+	return t.Text
+}
+
+// Done возвращает true для шага с ответом
+func (t Thought) Done() bool {
+	return t.Final
+}
+
+// Key пустой: шаги в свободной форме не сравниваются
+func (t Thought) Key() string {
+	return ""
+}
+
+// step шаг пути (переменная шаблонов)
+type step struct {
+	Number int
+	Text   string
+}
+
+// steps шаги пути без корня
+func steps(path []State) []step {
+	var list []step
+	for i, s := range path[1:] {
+		list = append(list, step{Number: i + 1, Text: s.String()})
+	}
+	return list
+}
+
+// LLMExpander предлагает варианты следующего шага запросом к модели
+type LLMExpander struct {
+	client   *client.OpenAIClient
+	prompts  *prompts.Library
+	problem  string
+	branches int
+	parse    func(path []State, step string) (State, error)
+
+	// Temperature температура запроса: выше - разнообразнее варианты
+	Temperature float32
+
+	tokens   int
+	rejected int
+	template string
+}
+
+// NewLLMExpander создает расширение шагов: problem - условие задачи, branches -
+// сколько вариантов просить, lib - библиотека с шаблоном ExpandPrompt (nil - встроенная).
+// По умолчанию шаг - Thought; свое состояние задачи строит SetParser.
+func NewLLMExpander(c *client.OpenAIClient, lib *prompts.Library, problem string, branches int) *LLMExpander {
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	return &LLMExpander{
+		client:      c,
+		prompts:     lib,
+		problem:     problem,
+		branches:    branches,
+		parse:       parseThought,
+		Temperature: 0.8,
+	}
+}
+
+// SetParser задает разбор варианта шага в состояние задачи. Вариант, который
+// не удалось разобрать (например, недопустимый ход), отбрасывается.
+func (e *LLMExpander) SetParser(fn func(path []State, step string) (State, error)) {
+	e.parse = fn
+}
+
+// Expand запрашивает у модели варианты следующего шага
+func (e *LLMExpander) Expand(ctx context.Context, path []State) ([]State, error) {
+	rendered, err := e.prompts.Render(ExpandPrompt, map[string]any{
+		"problem":  e.problem,
+		"steps":    steps(path),
+		"branches": e.branches,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.template = rendered.String()
+
+	resp, err := e.client.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      rendered.Text,
+		Templates:   []string{rendered.String()},
+		Temperature: client.Temperature(e.Temperature),
+		MaxTokens:   300,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.tokens += resp.TotalTokens
+
+	var states []State
+	for _, line := range parseVariants(resp.Content) {
+		if len(states) == e.branches {
+			break
+		}
+		state, err := e.parse(path, line)
+		if err != nil {
+			e.rejected++
+			continue
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// Tokens возвращает токены всех запросов расширения
+func (e *LLMExpander) Tokens() int {
+	return e.tokens
+}
+
+// Rejected возвращает число отброшенных вариантов
+func (e *LLMExpander) Rejected() int {
+	return e.rejected
+}
+
+// Template возвращает шаблон промпта последнего запроса: name@version#hash
+func (e *LLMExpander) Template() string {
+	return e.template
+}
+
+// variantMarker маркер варианта в начале строки: "- ", "* ", "1. ", "2) "
+var variantMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// parseVariants возвращает варианты из ответа модели: строки с маркером списка,
+// а если их нет - все непустые строки
+func parseVariants(content string) []string {
+	var marked, plain []string
+	for _, line := range strings.Split(content, "\n") {
+		text := strings.TrimSpace(variantMarker.ReplaceAllString(line, ""))
+		if text == "" {
+			continue
+		}
+		if variantMarker.MatchString(line) {
+			marked = append(marked, text)
+		}
+		plain = append(plain, text)
+	}
+	if len(marked) > 0 {
+		return marked
+	}
+	return plain
+}
+
+// parseThought разбор по умолчанию: шаг в свободной форме
+func parseThought(_ []State, text string) (State, error) {
+	if rest, ok := strings.CutPrefix(text, finalMarker); ok {
+		return Thought{Text: strings.TrimSpace(rest), Final: true}, nil
+	}
+	return Thought{Text: text}, nil
+}
+
+// LLMEvaluator оценивает частичное решение промптом оценки
+type LLMEvaluator struct {
+	client  *client.OpenAIClient
+	prompts *prompts.Library
+	problem string
+
+	tokens   int
+	template string
+}
+
+// NewLLMEvaluator создает оценку промптом: problem - условие задачи,
+// lib - библиотека с шаблоном ValuePrompt (nil - встроенная)
+func NewLLMEvaluator(c *client.OpenAIClient, lib *prompts.Library, problem string) *LLMEvaluator {
+	if lib == nil {
+		lib = prompts.Default()
+	}
+	return &LLMEvaluator{client: c, prompts: lib, problem: problem}
+}
+
+// Evaluate просит модель оценить путь от 0 до 1
+func (e *LLMEvaluator) Evaluate(ctx context.Context, path []State) (Score, error) {
+	rendered, err := e.prompts.Render(ValuePrompt, map[string]any{
+		"problem": e.problem,
+		"steps":   steps(path),
+	})
+	if err != nil {
+		return Score{}, err
+	}
+	e.template = rendered.String()
+
+	resp, err := e.client.CreateCompletionContext(ctx, client.CompletionRequest{
+		Prompt:      rendered.Text,
+		Templates:   []string{rendered.String()},
+		MaxTokens:   150,
+		Temperature: client.Temperature(0), // Оценки вариантов должны быть воспроизводимы
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	})
+	if err != nil {
+		return Score{}, err
+	}
+	e.tokens += resp.TotalTokens
+
+	var parsed struct {
+		Score  float64 `json:"score"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(resp.Content), &parsed); err != nil {
+		return Score{}, fmt.Errorf("ответ оценки не JSON: %w", err)
+	}
+	return Score{Value: min(max(parsed.Score, 0), 1), Reason: parsed.Reason}, nil
+}
+
+// Tokens возвращает токены всех запросов оценки
+func (e *LLMEvaluator) Tokens() int {
+	return e.tokens
+}
+
+// Template возвращает шаблон промпта последнего запроса: name@version#hash
+func (e *LLMEvaluator) Template() string {
+	return e.template
+}
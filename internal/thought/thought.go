@@ -0,0 +1,263 @@
+// Package thought - рассуждение деревом мыслей (tree-of-thought): на каждом шаге
+// предлагается несколько вариантов следующего шага, каждый вариант оценивается
+// промптом оценки или программной проверкой, и рассуждение продолжается только
+// от лучших вариантов (лучевой поиск с шириной луча и ограничением глубины).
+// Задача описывается тремя интерфейсами: State, Expander и Evaluator.
+package thought
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// State состояние рассуждения после очередного шага
+type State interface {
+	// String описание шага для промптов и стенограммы
+	String() string
+	// Done возвращает true, если состояние - законченное решение
+	Done() bool
+	// Key идентификатор состояния: повторно достигнутое состояние отсекается
+	// (пусто - не отсекать)
+	Key() string
+}
+
+// Expander предлагает варианты следующего шага
+type Expander interface {
+	// Expand получает путь от корня до текущего состояния и возвращает
+	// варианты следующего шага (пусто - продолжить нельзя)
+	Expand(ctx context.Context, path []State) ([]State, error)
+}
+
+// Evaluator оценивает, насколько перспективен путь
+type Evaluator interface {
+	// Evaluate оценивает путь от корня до нового состояния
+	Evaluate(ctx context.Context, path []State) (Score, error)
+}
+
+// Score оценка состояния: 0 - тупик (ветка отсекается), 1 - лучшее
+type Score struct {
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Причины отсечения ветки
+const (
+	PrunedRepeat  = "repeat"   // Состояние уже встречалось
+	PrunedDeadEnd = "dead_end" // Оценка 0
+)
+
+// Node узел дерева рассуждения
+type Node struct {
+	ID     int    `json:"id"`
+	Parent int    `json:"parent"` // -1 у корня
+	Depth  int    `json:"depth"`
+	Step   string `json:"step"`
+	Score  Score  `json:"score"`
+	Done   bool   `json:"done,omitempty"`
+	Kept   bool   `json:"kept,omitempty"`   // Попал в луч (или стал решением)
+	Pruned string `json:"pruned,omitempty"` // Причина отсечения
+}
+
+// Options параметры поиска
+type Options struct {
+	Beam  int // Сколько лучших вариантов продолжается на каждой глубине
+	Depth int // Максимум шагов от корня
+}
+
+// DefaultOptions параметры по умолчанию
+func DefaultOptions() Options {
+	return Options{Beam: 2, Depth: 10}
+}
+
+// Result итог поиска: лучший путь и все дерево
+type Result struct {
+	Path        []Node `json:"path"`   // Лучший путь без корня: решение или самый перспективный незаконченный
+	Solved      bool   `json:"solved"` // Путь заканчивается решением
+	Tree        []Node `json:"tree"`   // Все узлы, корень первым
+	Expansions  int    `json:"expansions"`
+	Evaluations int    `json:"evaluations"`
+
+	states []State // Состояния лучшего пути, корень первым
+}
+
+// States возвращает состояния лучшего пути, корень первым
+func (r *Result) States() []State {
+	return r.states
+}
+
+// Search лучевой поиск по дереву рассуждения
+type Search struct {
+	expander  Expander
+	evaluator Evaluator
+	opts      Options
+
+	onNode func(Node) // Вызывается после оценки каждого узла (опционально)
+}
+
+// New создает поиск
+func New(expander Expander, evaluator Evaluator, opts Options) *Search {
+	return &Search{expander: expander, evaluator: evaluator, opts: opts}
+}
+
+// SetProgress задает функцию, которая получает каждый узел сразу после оценки
+func (s *Search) SetProgress(fn func(Node)) {
+	s.onNode = fn
+}
+
+// Run ищет решение от состояния root. На каждой глубине раскрываются Beam
+// лучших вариантов; поиск останавливается на первой глубине, где найдено
+// решение (из нескольких выбирается лучшее по оценке), или на глубине Depth.
+// При ошибке расширения или оценки возвращается лучший путь на момент ошибки
+// вместе с ошибкой.
+func (s *Search) Run(ctx context.Context, root State) (*Result, error) {
+	if s.opts.Beam < 1 || s.opts.Depth < 1 {
+		return nil, errors.New("ширина луча и глубина должны быть положительными")
+	}
+
+	ctx, span := telemetry.Tracer().Start(ctx, "thought.Search")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("thought.beam", s.opts.Beam),
+		attribute.Int("thought.depth", s.opts.Depth),
+	)
+
+	t := &tree{
+		nodes:  []Node{{Parent: -1, Step: root.String(), Done: root.Done(), Kept: true}},
+		states: []State{root},
+		seen:   map[string]bool{},
+	}
+	if key := root.Key(); key != "" {
+		t.seen[key] = true
+	}
+
+	result := &Result{}
+	err := s.search(ctx, t, result)
+	span.SetAttributes(
+		attribute.Bool("thought.solved", result.Solved),
+		attribute.Int("thought.nodes", len(t.nodes)),
+	)
+	if err != nil {
+		telemetry.RecordError(span, err)
+		return result, err
+	}
+	return result, nil
+}
+
+// tree узлы дерева и их состояния (индекс - ID узла)
+type tree struct {
+	nodes  []Node
+	states []State
+	seen   map[string]bool
+}
+
+// path возвращает состояния от корня до узла
+func (t *tree) path(id int) []State {
+	var path []State
+	for ; id >= 0; id = t.nodes[id].Parent {
+		path = append([]State{t.states[id]}, path...)
+	}
+	return path
+}
+
+func (s *Search) search(ctx context.Context, t *tree, result *Result) error {
+	best := 0 // Лучший узел: решение или самый перспективный на последней глубине
+	defer func() { s.finish(t, best, result) }()
+	if t.nodes[0].Done {
+		return nil
+	}
+
+	beam := []int{0}
+	for depth := 1; depth <= s.opts.Depth && len(beam) > 0; depth++ {
+		var candidates []int
+		for _, id := range beam {
+			added, err := s.expand(ctx, t, id, depth, result)
+			candidates = append(candidates, added...)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Стабильная сортировка: при равной оценке раньше предложенный вариант
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return t.nodes[candidates[i]].Score.Value > t.nodes[candidates[j]].Score.Value
+		})
+
+		// Решение на этой глубине - лучшее из законченных
+		for _, id := range candidates {
+			if t.nodes[id].Done {
+				t.nodes[id].Kept = true
+				best = id
+				return nil
+			}
+		}
+
+		beam = candidates[:min(s.opts.Beam, len(candidates))]
+		for _, id := range beam {
+			t.nodes[id].Kept = true
+		}
+		if len(beam) > 0 {
+			best = beam[0]
+		}
+	}
+	return nil
+}
+
+// expand раскрывает узел и оценивает его варианты. Возвращает узлы,
+// которые не отсечены.
+func (s *Search) expand(ctx context.Context, t *tree, id, depth int, result *Result) ([]int, error) {
+	path := t.path(id)
+	children, err := s.expander.Expand(ctx, path)
+	result.Expansions++
+	if err != nil {
+		return nil, fmt.Errorf("расширение шага %d: %w", depth, err)
+	}
+
+	var added []int
+	for _, child := range children {
+		node := Node{ID: len(t.nodes), Parent: id, Depth: depth, Step: child.String(), Done: child.Done()}
+
+		key := child.Key()
+		if key != "" && t.seen[key] {
+			node.Pruned = PrunedRepeat
+		} else {
+			if key != "" {
+				t.seen[key] = true
+			}
+			node.Score, err = s.evaluator.Evaluate(ctx, append(path[:len(path):len(path)], child))
+			result.Evaluations++
+			if err != nil {
+				return added, fmt.Errorf("оценка шага %d: %w", depth, err)
+			}
+			if node.Score.Value <= 0 {
+				node.Pruned = PrunedDeadEnd
+			}
+		}
+
+		t.nodes = append(t.nodes, node)
+		t.states = append(t.states, child)
+		if node.Pruned == "" {
+			added = append(added, node.ID)
+		}
+		if s.onNode != nil {
+			s.onNode(node)
+		}
+	}
+	return added, nil
+}
+
+// finish заполняет итог: лучший путь от узла best и дерево
+func (s *Search) finish(t *tree, best int, result *Result) {
+	result.Tree = t.nodes
+	result.Solved = t.nodes[best].Done
+	result.states = t.path(best)
+
+	result.Path = nil
+	for id := best; id > 0; id = t.nodes[id].Parent {
+		result.Path = append([]Node{t.nodes[id]}, result.Path...)
+	}
+}
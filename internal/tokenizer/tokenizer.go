@@ -0,0 +1,106 @@
+package tokenizer
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultModel модель, словарь которой используется, если модель не указана или неизвестна
+const DefaultModel = "gpt-4o-mini"
+
+// Служебные токены формата чата (как их считает OpenAI при выставлении счета)
+const (
+	MessageOverhead = 3 // <|start|>роль ... <|end|> на каждое сообщение
+	NameOverhead    = 1 // Дополнительный токен за поле name
+	ReplyPriming    = 3 // <|start|>assistant<|message|> перед ответом модели
+)
+
+func init() {
+	// Словари cl100k_base и o200k_base встроены в бинарник - сеть не нужна
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+var (
+	mu       sync.Mutex
+	encoders = make(map[string]*tiktoken.Tiktoken)
+	failed   = make(map[string]bool)
+)
+
+// encoderFor возвращает (и кэширует) BPE-кодировщик для модели.
+// Для неизвестных моделей используется словарь DefaultModel.
+func encoderFor(model string) *tiktoken.Tiktoken {
+	if model == "" {
+		model = DefaultModel
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if enc, ok := encoders[model]; ok {
+		return enc
+	}
+	if failed[model] {
+		return nil
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil && model != DefaultModel {
+		enc, err = tiktoken.EncodingForModel(DefaultModel)
+	}
+	if err != nil {
+		// Словарь недоступен - запоминаем, чтобы не пытаться загрузить его на каждый вызов
+		failed[model] = true
+		return nil
+	}
+
+	encoders[model] = enc
+	return enc
+}
+
+// Count возвращает количество токенов в тексте для указанной модели.
+// Если словарь модели недоступен, используется приблизительная оценка по символам.
+func Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	if enc := encoderFor(model); enc != nil {
+		return len(enc.Encode(text, nil, nil))
+	}
+
+	return Estimate(text)
+}
+
+// CountMessage возвращает количество токенов одного сообщения чата
+// с учетом служебных токенов формата
+func CountMessage(model, role, content string) int {
+	return MessageOverhead + Count(model, role) + Count(model, content)
+}
+
+// CountMessages возвращает количество токенов запроса из списка сообщений:
+// служебные токены каждого сообщения плюс токены, открывающие ответ модели
+func CountMessages(model string, messages []openai.ChatCompletionMessage) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	total := ReplyPriming
+	for _, msg := range messages {
+		total += CountMessage(model, msg.Role, msg.Content)
+		if msg.Name != "" {
+			total += NameOverhead + Count(model, msg.Name)
+		}
+	}
+
+	return total
+}
+
+// Estimate приблизительно оценивает количество токенов (~3 символа на токен).
+// Считает символы, а не байты, поэтому не завышает оценку для кириллицы.
+func Estimate(text string) int {
+	return (utf8.RuneCountInString(text) + 2) / 3
+}
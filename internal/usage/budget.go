@@ -0,0 +1,260 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/internal/models"
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/utils"
+)
+
+// DefaultWarnRatio доля лимита, после которой выводится предупреждение
+const DefaultWarnRatio = 0.8
+
+// ErrBudgetExceeded запрос отклонен, потому что превысил бы лимит расходов.
+// Проверяется через errors.Is; подробности - в *BudgetExceededError.
+var ErrBudgetExceeded = errors.New("превышен бюджет")
+
+// IsFatal сообщает, что после ошибки err следующие запросы слать не нужно:
+// исчерпан бюджет или ctx отменен (Ctrl+C). Прочие ошибки касаются одного запроса.
+func IsFatal(ctx context.Context, err error) bool {
+	return errors.Is(err, ErrBudgetExceeded) || ctx.Err() != nil
+}
+
+// Limits лимиты расходов (нулевое значение - без ограничения)
+type Limits struct {
+	CostUSD float64 `yaml:"cost_usd"` // Лимит в долларах
+	Tokens  int     `yaml:"tokens"`   // Лимит в токенах
+}
+
+// IsZero проверяет, что лимиты не заданы
+func (l Limits) IsZero() bool {
+	return l.CostUSD <= 0 && l.Tokens <= 0
+}
+
+// BudgetConfig лимиты расходов по областям
+type BudgetConfig struct {
+	Session Limits `yaml:"session"` // На один запуск программы
+	Day     Limits `yaml:"day"`     // На календарный день (по журналу)
+	Total   Limits `yaml:"total"`   // За все время (по журналу)
+
+	// WarnRatio доля лимита, после которой выводится предупреждение (0 - DefaultWarnRatio)
+	WarnRatio float64 `yaml:"warn_ratio"`
+}
+
+// IsZero проверяет, что ни один лимит не задан
+func (c BudgetConfig) IsZero() bool {
+	return c.Session.IsZero() && c.Day.IsZero() && c.Total.IsZero()
+}
+
+// Projection ожидаемые расходы запроса
+type Projection struct {
+	Tokens  int     // Токены запроса плюс максимальный размер ответа
+	CostUSD float64 // Стоимость при максимальном размере ответа
+}
+
+// Project оценивает расходы запроса до отправки: токены промпта плюс максимальный
+// размер ответа (maxTokens, а если он не задан - максимум модели) по ценам модели
+func Project(model models.Model, promptTokens, maxTokens int) Projection {
+	if maxTokens <= 0 {
+		maxTokens = model.MaxOutput
+	}
+	return Projection{
+		Tokens:  promptTokens + maxTokens,
+		CostUSD: model.Cost(promptTokens, 0, maxTokens),
+	}
+}
+
+// BudgetExceededError подробности превышения лимита
+type BudgetExceededError struct {
+	Scope     string  // "сессия", "день" или "всего"
+	Unit      string  // "$" или "токенов"
+	Limit     float64 // Лимит
+	Spent     float64 // Уже потрачено
+	Projected float64 // Ожидаемый расход запроса
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Unit == "$" {
+		return fmt.Sprintf("%s (%s): потрачено $%.4f, запрос до $%.4f, лимит $%.4f",
+			ErrBudgetExceeded, e.Scope, e.Spent, e.Projected, e.Limit)
+	}
+	return fmt.Sprintf("%s (%s): потрачено %.0f токенов, запрос до %.0f, лимит %.0f",
+		ErrBudgetExceeded, e.Scope, e.Spent, e.Projected, e.Limit)
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrBudgetExceeded)
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// spent расходы в одной области
+type spent struct {
+	cost   float64
+	tokens int
+}
+
+// Budget проверяет запросы на соответствие лимитам до их отправки.
+// Жесткие лимиты отклоняют запрос с ErrBudgetExceeded, мягкие пороги
+// (WarnRatio от лимита) выводят предупреждение один раз на область.
+type Budget struct {
+	config BudgetConfig
+
+	mu      sync.Mutex
+	session spent
+	day     spent
+	total   spent
+	dayKey  string          // Дата, к которой относятся расходы day
+	warned  map[string]bool // Области, по которым уже было предупреждение
+	history []Record        // Записи журнала на момент создания (для пересчета дня)
+
+	// OnWarning вызывается при достижении мягкого порога (по умолчанию utils.PrintWarning)
+	OnWarning func(message string)
+}
+
+// NewBudget создает бюджет; расходы за день и за все время берутся из записей журнала
+func NewBudget(config BudgetConfig, history []Record) *Budget {
+	if config.WarnRatio <= 0 {
+		config.WarnRatio = DefaultWarnRatio
+	}
+
+	b := &Budget{
+		config:    config,
+		warned:    make(map[string]bool),
+		history:   history,
+		OnWarning: utils.PrintWarning,
+	}
+	for _, r := range history {
+		b.total.cost += r.Cost
+		b.total.tokens += r.TotalTokens()
+	}
+	b.rollDay(time.Now())
+
+	return b
+}
+
+// OpenBudget создает бюджет по журналу в пути по умолчанию.
+// Если ни один лимит не задан, возвращает nil (проверки отключены).
+func OpenBudget(config BudgetConfig) (*Budget, error) {
+	if config.IsZero() {
+		return nil, nil
+	}
+
+	ledger, err := Open()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := ledger.Records()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBudget(config, history), nil
+}
+
+// rollDay пересчитывает дневные расходы при смене даты
+func (b *Budget) rollDay(now time.Time) {
+	key := now.Local().Format("2006-01-02")
+	if key == b.dayKey {
+		return
+	}
+
+	b.dayKey = key
+	b.day = spent{}
+	for _, r := range b.history {
+		if ByDay.key(r) == key {
+			b.day.cost += r.Cost
+			b.day.tokens += r.TotalTokens()
+		}
+	}
+	delete(b.warned, "день$")
+	delete(b.warned, "деньтокенов")
+}
+
+// Check проверяет, укладывается ли запрос в лимиты.
+// Возвращает *BudgetExceededError, если хотя бы один жесткий лимит будет превышен.
+func (b *Budget) Check(projection Projection) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollDay(time.Now())
+
+	scopes := []struct {
+		name   string
+		limits Limits
+		spent  spent
+	}{
+		{"сессия", b.config.Session, b.session},
+		{"день", b.config.Day, b.day},
+		{"всего", b.config.Total, b.total},
+	}
+
+	for _, scope := range scopes {
+		if err := b.checkLimit(scope.name, "$", scope.limits.CostUSD, scope.spent.cost, projection.CostUSD); err != nil {
+			return err
+		}
+		if err := b.checkLimit(scope.name, "токенов", float64(scope.limits.Tokens), float64(scope.spent.tokens), float64(projection.Tokens)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkLimit проверяет один лимит и выводит предупреждение при достижении мягкого порога
+func (b *Budget) checkLimit(scope, unit string, limit, spentValue, projected float64) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	if spentValue+projected > limit {
+		return &BudgetExceededError{
+			Scope:     scope,
+			Unit:      unit,
+			Limit:     limit,
+			Spent:     spentValue,
+			Projected: projected,
+		}
+	}
+
+	key := scope + unit
+	if spentValue+projected >= limit*b.config.WarnRatio && !b.warned[key] {
+		b.warned[key] = true
+		if b.OnWarning != nil {
+			b.OnWarning(fmt.Sprintf("Бюджет (%s) использован на %.0f%%: после запроса будет до %s из %s",
+				scope, (spentValue+projected)/limit*100, formatAmount(unit, spentValue+projected), formatAmount(unit, limit)))
+		}
+	}
+
+	return nil
+}
+
+// Spend учитывает фактические расходы выполненного запроса
+func (b *Budget) Spend(tokens int, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollDay(time.Now())
+	for _, s := range []*spent{&b.session, &b.day, &b.total} {
+		s.tokens += tokens
+		s.cost += cost
+	}
+}
+
+// SessionSpent возвращает расходы текущей сессии
+func (b *Budget) SessionSpent() (tokens int, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.session.tokens, b.session.cost
+}
+
+func formatAmount(unit string, value float64) string {
+	if unit == "$" {
+		return fmt.Sprintf("$%.4f", value)
+	}
+	return fmt.Sprintf("%.0f %s", value, unit)
+}
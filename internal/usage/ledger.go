@@ -0,0 +1,162 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultLedgerFile имя файла журнала в домашней директории
+const DefaultLedgerFile = ".agent_usage.jsonl"
+
+// Record одна запись журнала - один запрос к API
+type Record struct {
+	Time             time.Time `json:"time"`
+	Session          string    `json:"session"`           // Идентификатор запуска программы
+	Command          string    `json:"command"`           // Команда или день ("day7", "day8")
+	Model            string    `json:"model"`             // Модель, которую вернул API
+	PromptTokens     int       `json:"prompt_tokens"`     // Токенов в запросе
+	CompletionTokens int       `json:"completion_tokens"` // Токенов в ответе
+	CachedTokens     int       `json:"cached_tokens"`     // Токенов запроса, взятых из кэша
+	Cost             float64   `json:"cost_usd"`          // Стоимость в долларах
+	LatencyMs        int64     `json:"latency_ms"`        // Время ответа API
+	FinishReason     string    `json:"finish_reason"`     // Причина завершения ответа
+	Prompt           string    `json:"prompt,omitempty"`  // Шаблон системного промпта: name@version#hash
+}
+
+// TotalTokens возвращает сумму токенов запроса и ответа
+func (r Record) TotalTokens() int {
+	return r.PromptTokens + r.CompletionTokens
+}
+
+// FromResponse формирует запись журнала из ответа API
+func FromResponse(session, command string, resp openai.ChatCompletionResponse, latency time.Duration, cost float64) Record {
+	record := Record{
+		Time:             time.Now(),
+		Session:          session,
+		Command:          command,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		Cost:             cost,
+		LatencyMs:        latency.Milliseconds(),
+	}
+	if resp.Usage.PromptTokensDetails != nil {
+		record.CachedTokens = resp.Usage.PromptTokensDetails.CachedTokens
+	}
+	if len(resp.Choices) > 0 {
+		record.FinishReason = string(resp.Choices[0].FinishReason)
+	}
+	return record
+}
+
+// Ledger журнал использования API в формате JSON Lines (одна запись на строку).
+// Записи только дописываются в конец файла, поэтому журнал переживает перезапуски
+// и может одновременно пополняться из нескольких программ.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLedger создает журнал в указанном файле (файл создается при первой записи)
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// DefaultPath возвращает путь к журналу: USAGE_LEDGER или ~/.agent_usage.jsonl
+func DefaultPath() (string, error) {
+	if path := os.Getenv("USAGE_LEDGER"); path != "" {
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("не удалось получить домашнюю директорию: %w", err)
+	}
+	return filepath.Join(homeDir, DefaultLedgerFile), nil
+}
+
+// NewSessionID возвращает идентификатор сессии на основе времени запуска
+func NewSessionID() string {
+	return time.Now().Format("20060102-150405")
+}
+
+// Path возвращает путь к файлу журнала
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append дописывает запись в конец журнала
+func (l *Ledger) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("ошибка записи в журнал: %w", err)
+	}
+
+	return nil
+}
+
+// Records читает все записи журнала (отсутствие файла не считается ошибкой)
+func (l *Ledger) Records() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения журнала: %w", err)
+	}
+	defer file.Close()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("некорректная запись в строке %d: %w", lineNum, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала: %w", err)
+	}
+
+	return records, nil
+}
+
+// Open открывает журнал по пути по умолчанию
+func Open() (*Ledger, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewLedger(path), nil
+}
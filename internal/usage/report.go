@@ -0,0 +1,174 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GroupBy поле, по которому группируются записи в отчете
+type GroupBy string
+
+const (
+	ByDay     GroupBy = "day"
+	ByModel   GroupBy = "model"
+	BySession GroupBy = "session"
+	ByCommand GroupBy = "command"
+)
+
+// ParseGroupBy проверяет название группировки
+func ParseGroupBy(value string) (GroupBy, error) {
+	switch by := GroupBy(value); by {
+	case ByDay, ByModel, BySession, ByCommand:
+		return by, nil
+	}
+	return "", fmt.Errorf("неизвестная группировка %q (допустимо: day, model, session, command)", value)
+}
+
+// key возвращает значение поля группировки для записи
+func (by GroupBy) key(r Record) string {
+	switch by {
+	case ByModel:
+		return r.Model
+	case BySession:
+		return r.Session
+	case ByCommand:
+		return r.Command
+	default:
+		return r.Time.Local().Format("2006-01-02")
+	}
+}
+
+// Totals итоги по группе записей
+type Totals struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	Cost             float64 `json:"cost_usd"`
+	LatencyMs        int64   `json:"latency_ms"` // Суммарное время ответов
+}
+
+// add учитывает запись в итогах
+func (t *Totals) add(r Record) {
+	t.Requests++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.CachedTokens += r.CachedTokens
+	t.Cost += r.Cost
+	t.LatencyMs += r.LatencyMs
+}
+
+// TotalTokens возвращает сумму токенов запросов и ответов
+func (t Totals) TotalTokens() int {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+// AverageLatency возвращает среднее время ответа
+func (t Totals) AverageLatency() time.Duration {
+	if t.Requests == 0 {
+		return 0
+	}
+	return time.Duration(t.LatencyMs/int64(t.Requests)) * time.Millisecond
+}
+
+// Filter оставляет записи в интервале [since, until); нулевая граница не ограничивает
+func Filter(records []Record, since, until time.Time) []Record {
+	filtered := make([]Record, 0, len(records))
+	for _, r := range records {
+		if !since.IsZero() && r.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !r.Time.Before(until) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// Group суммирует записи по группам, отсортированным по ключу
+func Group(records []Record, by GroupBy) []Totals {
+	byKey := make(map[string]*Totals)
+	for _, r := range records {
+		key := by.key(r)
+		totals, ok := byKey[key]
+		if !ok {
+			totals = &Totals{Key: key}
+			byKey[key] = totals
+		}
+		totals.add(r)
+	}
+
+	groups := make([]Totals, 0, len(byKey))
+	for _, totals := range byKey {
+		groups = append(groups, *totals)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups
+}
+
+// Sum возвращает общие итоги по всем записям
+func Sum(records []Record) Totals {
+	totals := Totals{Key: "total"}
+	for _, r := range records {
+		totals.add(r)
+	}
+	return totals
+}
+
+// WriteJSON выводит значение (итоги или записи) в формате JSON
+func WriteJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// WriteTotalsCSV выводит итоги по группам в формате CSV
+func WriteTotalsCSV(w io.Writer, by GroupBy, groups []Totals) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{string(by), "requests", "prompt_tokens", "completion_tokens", "cached_tokens", "cost_usd", "avg_latency_ms"})
+	for _, g := range groups {
+		writer.Write([]string{
+			g.Key,
+			strconv.Itoa(g.Requests),
+			strconv.Itoa(g.PromptTokens),
+			strconv.Itoa(g.CompletionTokens),
+			strconv.Itoa(g.CachedTokens),
+			strconv.FormatFloat(g.Cost, 'f', 6, 64),
+			strconv.FormatInt(g.AverageLatency().Milliseconds(), 10),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteRecordsCSV выводит записи журнала в формате CSV
+func WriteRecordsCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "session", "command", "model", "prompt_tokens", "completion_tokens", "cached_tokens", "cost_usd", "latency_ms", "finish_reason"})
+	for _, r := range records {
+		writer.Write([]string{
+			r.Time.Format(time.RFC3339),
+			r.Session,
+			r.Command,
+			r.Model,
+			strconv.Itoa(r.PromptTokens),
+			strconv.Itoa(r.CompletionTokens),
+			strconv.Itoa(r.CachedTokens),
+			strconv.FormatFloat(r.Cost, 'f', 6, 64),
+			strconv.FormatInt(r.LatencyMs, 10),
+			r.FinishReason,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
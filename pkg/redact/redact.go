@@ -0,0 +1,120 @@
+// Package redact скрывает секреты (ключи API, токены) в тексте перед выводом,
+// записью в журнал или сохранением в файл.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minSecretLength более короткие значения не регистрируются, чтобы не портить обычный текст
+const minSecretLength = 8
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// keyPattern ключи, похожие на ключи OpenAI и Bearer-токены, даже если они не зарегистрированы
+var keyPattern = regexp.MustCompile(`\b(sk-[A-Za-z0-9_\-]{16,}|Bearer\s+[A-Za-z0-9_\-.=]{16,})`)
+
+// Register добавляет значение в список скрываемых секретов
+func Register(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+	// Длинные первыми, чтобы секрет, содержащий другой, скрывался целиком
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// String возвращает текст, в котором секреты заменены маской
+func String(text string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		if strings.Contains(text, secret) {
+			text = strings.ReplaceAll(text, secret, Mask(secret))
+		}
+	}
+	mu.RUnlock()
+
+	return keyPattern.ReplaceAllStringFunc(text, Mask)
+}
+
+// Bytes как String для содержимого файлов
+func Bytes(data []byte) []byte {
+	return []byte(String(string(data)))
+}
+
+// Mask возвращает маску секрета: префикс ключа и последние 4 символа
+// (sk-...abcd), по которым ключ можно узнать, но нельзя использовать
+func Mask(secret string) string {
+	if strings.HasPrefix(secret, "Bearer") {
+		return "Bearer ***"
+	}
+	if len(secret) <= minSecretLength {
+		return "***"
+	}
+
+	prefix := ""
+	if strings.HasPrefix(secret, "sk-") {
+		prefix = "sk-"
+	}
+	return prefix + "..." + secret[len(secret)-4:]
+}
+
+// Error возвращает ошибку с замаскированным текстом; errors.Is и errors.As
+// по-прежнему работают с исходной ошибкой
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := String(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, message: message}
+}
+
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string { return e.message }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// Writer маскирует секреты во всем, что в него пишется
+// (например, log.SetOutput(redact.NewWriter(os.Stderr)))
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter оборачивает w; повторная обертка не создается
+func NewWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*Writer); ok {
+		return w
+	}
+	return &Writer{w: w}
+}
+
+// Write записывает данные с замаскированными секретами.
+// Возвращает длину исходных данных, как того ожидают вызывающие.
+func (rw *Writer) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write(Bytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"strings"
+
+	"github.com/georgijter-grigoranc/ai-advent-challenge/pkg/redact"
 )
 
 const (
@@ -16,6 +18,22 @@ const (
 	ColorWhite  = "\033[37m"
 )
 
+// colorEnabled выводить ли ANSI-коды цвета
+var colorEnabled = true
+
+// SetColor включает или выключает цветной вывод (NO_COLOR, флаг -no-color, вывод не в терминал)
+func SetColor(enabled bool) {
+	colorEnabled = enabled
+}
+
+// Colorize окрашивает текст, если цветной вывод включен
+func Colorize(color, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	return color + text + ColorReset
+}
+
 // PrintHeader выводит заголовок
 func PrintHeader(title string) {
 	line := strings.Repeat("=", 80)
@@ -58,22 +76,27 @@ func PrintDivider() {
 
 // PrintColored выводит цветной текст
 func PrintColored(color, text string) {
-	fmt.Printf("%s%s%s\n", color, text, ColorReset)
+	fmt.Println(Colorize(color, text))
 }
 
 // PrintSuccess выводит успешное сообщение
 func PrintSuccess(text string) {
-	fmt.Printf("%s✓ %s%s\n", ColorGreen, text, ColorReset)
+	fmt.Println(Colorize(ColorGreen, "✓ "+text))
 }
 
 // PrintError выводит сообщение об ошибке
 func PrintError(text string) {
-	fmt.Printf("%s✗ %s%s\n", ColorRed, text, ColorReset)
+	fmt.Println(Colorize(ColorRed, "✗ "+redact.String(text)))
+}
+
+// PrintWarning выводит предупреждение
+func PrintWarning(text string) {
+	fmt.Println(Colorize(ColorYellow, "⚠ "+redact.String(text)))
 }
 
 // PrintInfo выводит информационное сообщение
 func PrintInfo(text string) {
-	fmt.Printf("%sℹ %s%s\n", ColorBlue, text, ColorReset)
+	fmt.Println(Colorize(ColorBlue, "ℹ "+redact.String(text)))
 }
 
 // Repeat повторяет строку n раз